@@ -0,0 +1,398 @@
+package main
+
+import (
+	"SafeTransfer/internal/config"
+	"SafeTransfer/internal/db"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// command is a subcommand of the server binary. All commands load their configuration like the
+// server does, and accept the same configuration flags.
+type command struct {
+	run     func(args []string) error
+	summary string
+}
+
+var commands = map[string]command{
+	"serve":          {serve, "start the API server (the default)"},
+	"migrate":        {migrate, "apply or revert schema migrations, or list them"},
+	"rotate-kek":     {rotateKEK, "rewrap file encryption keys with the current KEK"},
+	"verify-all":     {verifyAll, "download and check the integrity of every stored file version"},
+	"gc":             {collectGarbage, "unpin IPFS content that no stored file refers to"},
+	"export-user":    {exportUser, "write everything stored about a user as JSON"},
+	"create-api-key": {createAPIKey, "issue an API key for a user"},
+}
+
+// errFailed reports that a command has already described its failure.
+var errFailed = errors.New("failed")
+
+func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		printUsage()
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+		printUsage()
+		os.Exit(2)
+	}
+
+	err := cmd.run(args)
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	case errors.Is(err, errFailed):
+		os.Exit(1)
+	default:
+		log.Fatalf("%s: %v", name, err)
+	}
+}
+
+func printUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: SafeTransfer [command] [flags]\n\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].summary)
+	}
+}
+
+// errUsage reports an invalid command line, which the flag set has already described.
+var errUsage = errors.New("usage")
+
+// newFlagSet creates the flag set of a command, whose usage line lists arguments, with the
+// configuration flags.
+func newFlagSet(name, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	config.RegisterFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: SafeTransfer %s [flags] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses the arguments of a command, which takes nargs positional arguments.
+func parseFlags(flags *flag.FlagSet, args []string, nargs int) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if flags.NArg() != nargs {
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// migrate applies or reverts schema migrations, or lists them. Up migrates to the latest schema
+// and down reverts the last migration, unless -to names another version.
+func migrate(args []string) error {
+	flags := newFlagSet("migrate", "[up | down | status]")
+	to := flags.Int("to", 0, "schema `version` to migrate to")
+	action := "up"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	if err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+	if action != "up" && action != "down" && action != "status" {
+		flags.Usage()
+		return errUsage
+	}
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return err
+	}
+
+	database := connectDatabase(cfg.Database)
+	defer database.Close()
+	if action == "status" {
+		return printMigrationStatus(database)
+	}
+
+	current, err := database.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	target := *to
+	toSet := false
+	flags.Visit(func(f *flag.Flag) { toSet = toSet || f.Name == "to" })
+	switch {
+	case action == "up" && !toSet:
+		if target, err = db.LatestVersion(); err != nil {
+			return err
+		}
+	case action == "down" && !toSet:
+		target = max(current-1, 0)
+	}
+	if action == "up" && target < current {
+		return fmt.Errorf("the schema is at version %d, use migrate down to revert to version %d", current, target)
+	}
+	if action == "down" && target > current {
+		return fmt.Errorf("the schema is at version %d, use migrate up to reach version %d", current, target)
+	}
+
+	migrations, err := database.MigrateTo(target)
+	if action == "up" {
+		logMigrations(migrations, "Applied")
+	} else {
+		logMigrations(migrations, "Reverted")
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Database schema is at version %d", target)
+	return nil
+}
+
+// printMigrationStatus lists the migrations with when they were applied.
+func printMigrationStatus(database *db.Database) error {
+	statuses, err := database.MigrationStatus()
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		state := "pending"
+		if status.AppliedAt != nil {
+			state = "applied " + status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Unknown {
+			state += " by a newer version"
+		}
+		fmt.Printf("%4d  %-32s %s\n", status.Version, status.Name, state)
+	}
+	return nil
+}
+
+// newMaintenanceService builds the maintenance service from the server's configuration.
+func newMaintenanceService(cfg *config.Config) (*service.MaintenanceService, func()) {
+	database := openDatabase(cfg.Database)
+	ipfsStorage := setupIPFSStorage(cfg.IPFS)
+	keyRing := setupKeyRing(cfg.Encryption)
+	fileRepo := repository.NewFileRepository(database)
+	downloadService := service.NewDownloadService(ipfsStorage, fileRepo, nil, nil, service.ChainVerifyOff, keyRing)
+	return service.NewMaintenanceService(ipfsStorage, fileRepo, downloadService, keyRing), func() { database.Close() }
+}
+
+// rotateKEK rewraps every file encryption key with ENCRYPTION_KEK. To rotate, configure the new
+// KEK there and the old one in ENCRYPTION_PREVIOUS_KEK_FILES, restart the server, run this
+// command, and then remove the old KEK.
+func rotateKEK(args []string) error {
+	flags := newFlagSet("rotate-kek", "")
+	if err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return err
+	}
+
+	maintenanceService, closeDatabase := newMaintenanceService(cfg)
+	defer closeDatabase()
+
+	rewrapped, err := maintenanceService.RotateKeys(context.Background())
+	if errors.Is(err, service.ErrNoKEK) {
+		return errors.New("ENCRYPTION_KEK is not set")
+	}
+	log.Printf("Rewrapped %d encryption keys with KEK %s", rewrapped, maintenanceService.KeyRing.CurrentKEKID())
+	return err
+}
+
+// verifyAll checks every stored file version, printing the versions that fail and exiting with
+// status 1 if there are any.
+func verifyAll(args []string) error {
+	flags := newFlagSet("verify-all", "")
+	verbose := flags.Bool("v", false, "also print the versions that pass")
+	if err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return err
+	}
+
+	maintenanceService, closeDatabase := newMaintenanceService(cfg)
+	defer closeDatabase()
+
+	checked, failed, err := maintenanceService.VerifyAll(context.Background(), func(result service.VerifyResult) {
+		switch {
+		case result.Err != nil:
+			fmt.Printf("FAIL %s (file %s, version %d): %v\n", result.File.CID, logicalFileID(result.File), result.File.Version, result.Err)
+		case *verbose:
+			fmt.Printf("ok   %s %s\n", result.File.CID, result.Hash)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Checked %d file versions, %d failed", checked, failed)
+	if failed > 0 {
+		return errFailed
+	}
+	return nil
+}
+
+func logicalFileID(file *model.File) string {
+	if file.LogicalFileID == nil {
+		return "-"
+	}
+	return fmt.Sprint(*file.LogicalFileID)
+}
+
+// collectGarbage unpins the content on the IPFS node that no stored file version refers to. The
+// node must be dedicated to SafeTransfer: anything else pinned on it is unpinned too. Orphans
+// are only unpinned if they are still orphaned after the grace period, so that uploads in
+// progress while the command runs keep their content.
+func collectGarbage(args []string) error {
+	flags := newFlagSet("gc", "")
+	dryRun := flags.Bool("dry-run", false, "only print the orphaned CIDs")
+	grace := flags.Duration("grace", 10*time.Minute, "how long CIDs must stay orphaned before they are unpinned")
+	if err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return err
+	}
+
+	maintenanceService, closeDatabase := newMaintenanceService(cfg)
+	defer closeDatabase()
+
+	orphans, err := maintenanceService.FindOrphanedPins(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, cid := range orphans {
+		fmt.Println(cid)
+	}
+	log.Printf("Found %d orphaned CIDs", len(orphans))
+	if *dryRun || len(orphans) == 0 {
+		return nil
+	}
+
+	log.Printf("Unpinning them in %s unless they are stored by then", *grace)
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-time.After(*grace):
+	case <-interrupted:
+		return errors.New("interrupted before unpinning")
+	}
+
+	unpinned, err := maintenanceService.UnpinOrphans(context.Background(), orphans)
+	log.Printf("Unpinned %d CIDs", len(unpinned))
+	return err
+}
+
+// exportUser writes everything stored about a user as JSON.
+func exportUser(args []string) error {
+	flags := newFlagSet("export-user", "<ethereum-address>")
+	output := flags.String("o", "", "`file` to write the export to instead of stdout")
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return err
+	}
+
+	database := openDatabase(cfg.Database)
+	defer database.Close()
+	exportService := service.NewExportService(
+		repository.NewUserRepository(database),
+		repository.NewFileRepository(database),
+		repository.NewFolderRepository(database),
+		repository.NewShareRepository(database),
+		repository.NewAPIKeyRepository(database),
+	)
+
+	export, err := exportService.ExportUser(context.Background(), flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+// createAPIKey issues an API key for a user and prints it. The key cannot be shown again.
+func createAPIKey(args []string) error {
+	flags := newFlagSet("create-api-key", "<ethereum-address>")
+	name := flags.String("name", "", "`name` of the key, shown in listings")
+	scopes := flags.String("scopes", "", "comma-separated `scopes`: upload, download and read-metadata")
+	expiresIn := flags.Duration("expires-in", 0, "`duration` after which the key expires; 0 for a key that does not expire")
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	database := openDatabase(cfg.Database)
+	defer database.Close()
+	userRepo := repository.NewUserRepository(database)
+	if _, err := userRepo.FindByEthereumAddress(ctx, flags.Arg(0)); errors.Is(err, repository.ErrNotFound) {
+		return service.ErrUserNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var expiresAt *time.Time
+	if *expiresIn > 0 {
+		t := time.Now().Add(*expiresIn)
+		expiresAt = &t
+	}
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(database))
+	apiKey, key, err := apiKeyService.CreateAPIKey(ctx, flags.Arg(0), *name, strings.Split(*scopes, ","), expiresAt)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Created API key %s for %s with scopes %s", apiKey.Prefix, apiKey.EthereumAddress, strings.Join(apiKey.Scopes, ","))
+	fmt.Println(key)
+	return nil
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/chain"
+	"SafeTransfer/internal/config"
+	"SafeTransfer/internal/crypto"
 	"SafeTransfer/internal/db"
-	"SafeTransfer/internal/model"
 	"SafeTransfer/internal/repository"
 	"SafeTransfer/internal/service"
 	"SafeTransfer/internal/storage"
-	"SafeTransfer/utils"
+	"SafeTransfer/internal/tracing"
 	"context"
 	"errors"
 	"fmt"
@@ -18,65 +20,286 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 )
 
-const defaultPort = "8083"
+// serve starts the API server and runs it until it is interrupted.
+func serve(args []string) error {
+	flags := newFlagSet("serve", "")
+	if err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return err
+	}
+	if cfg.Auth.JWTSecret == "" {
+		return errors.New("JWT_SECRET is not set")
+	}
+
+	shutdownTracing := setupTracing(cfg.Tracing)
+	defer shutdownTracing()
 
-func main() {
-	database := setupDatabase()
+	database := setupDatabase(cfg.Database)
 	defer database.Close()
 
-	ipfsStorage := setupIPFSStorage()
+	ipfsStorage := setupIPFSStorage(cfg.IPFS)
+	keyRing := setupKeyRing(cfg.Encryption)
 
 	fileRepo := repository.NewFileRepository(database)
-	fileService := service.NewFileService(ipfsStorage, fileRepo)
-	downloadService := service.NewDownloadService(ipfsStorage, fileRepo)
-
+	folderRepo := repository.NewFolderRepository(database)
 	userRepo := repository.NewUserRepository(database)
-	JWTSecretKey, err := os.ReadFile("/run/secrets/jwt_secret")
-	if err != nil {
-		log.Fatalf("Failed to read JWT secret key: %v", err)
+	quotaService := service.NewQuotaService(userRepo, cfg.Storage.DefaultQuotaBytes, cfg.Storage.DefaultQuotaFiles)
+	registry := setupRegistry(cfg.Chain)
+	var registrationService *service.RegistrationService
+	if registry != nil {
+		registrationService = service.NewRegistrationService(registry, fileRepo)
+	}
+	fileService := service.NewFileService(ipfsStorage, fileRepo, folderRepo, quotaService, registrationService, keyRing)
+	shareRepo := repository.NewShareRepository(database)
+	downloadService := service.NewDownloadService(ipfsStorage, fileRepo, shareRepo, registry, cfg.Chain.VerifyMode, keyRing)
+	folderService := service.NewFolderService(folderRepo, fileRepo)
+	shareService := service.NewShareService(shareRepo, fileRepo)
+	versionService := service.NewVersionService(fileService, downloadService, fileRepo, shareRepo, ipfsStorage, cfg.Storage.VersionRetention)
+
+	userService := service.NewUserService(userRepo, cfg.Auth.JWTSecret, authChainID(cfg.Auth, registry), walletBackend(registry))
+
+	chainIndexer := startChainIndexer(cfg.Chain, registry, repository.NewChainEventRepository(database))
+
+	adminService := service.NewAdminService(userRepo, fileRepo, fileService)
+	if err := adminService.BootstrapAdmins(context.Background(), cfg.Auth.AdminAddresses); err != nil {
+		log.Fatalf("Failed to set up admins: %v", err)
 	}
-	userService := service.NewUserService(userRepo, string(JWTSecretKey))
 
-	apiHandler := api.NewAPIHandler(fileService, downloadService, userService)
+	apiHandler := &api.Handler{
+		FileService:     fileService,
+		DownloadService: downloadService,
+		UserService:     userService,
+		FolderService:   folderService,
+		VersionService:  versionService,
+		QuotaService:    quotaService,
+		ChainIndexer:    chainIndexer,
+		VerifyService:   service.NewVerificationService(fileRepo, shareRepo, registry),
+		ReceiptService:  setupReceiptService(cfg.Receipts, registry),
+		ShareService:    shareService,
+		AdminService:    adminService,
+		APIKeyService:   service.NewAPIKeyService(repository.NewAPIKeyRepository(database)),
+		HealthService:   setupHealthService(cfg.Chain, database, ipfsStorage, registry),
+		MetricsToken:    cfg.Server.MetricsToken,
+	}
 	router := setupRouter(apiHandler)
 
-	startServer(router)
+	startServer(router, cfg.Server.Port)
+	return nil
+}
+
+// setupTracing installs the trace context propagators and, when an OTLP endpoint is configured,
+// exports spans to it. The returned function flushes the spans that have not been exported yet.
+func setupTracing(cfg config.TracingConfig) func() {
+	shutdown, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	if cfg.Enabled() {
+		log.Printf("Exporting traces to %s as %s", cfg.Endpoint, cfg.ServiceName)
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			log.Printf("Failed to flush traces: %v", err)
+		}
+	}
 }
 
-func setupDatabase() *db.Database {
-	host := utils.GetEnvOrDefault("DB_HOST", "localhost")
-	port := utils.GetEnvOrDefault("DB_PORT", "5432")
-	dbname := utils.GetEnvOrDefault("DB_NAME", "postgres")
-	user := utils.GetEnvOrDefault("DB_USER", "postgres")
-	sslmode := utils.GetEnvOrDefault("SSL_MODE", "disable")
+// setupDatabase connects to the database and applies pending migrations, or only checks that the
+// schema is up to date when DB_AUTO_MIGRATE is off. It refuses to run against a schema newer than
+// this version understands.
+func setupDatabase(cfg config.DatabaseConfig) *db.Database {
+	if !cfg.AutoMigrate {
+		return openDatabase(cfg)
+	}
 
-	// Read the database password from the mounted secret file
-	password, err := os.ReadFile("/run/secrets/db_password")
+	database := connectDatabase(cfg)
+	migrations, err := database.Migrate()
+	logMigrations(migrations, "Applied")
 	if err != nil {
-		log.Fatalf("Failed to read database password: %v", err)
+		log.Fatalf("Failed to migrate schema: %v", err)
+	}
+	return database
+}
+
+// openDatabase connects to the database and checks that its schema is the version this build
+// was made for.
+func openDatabase(cfg config.DatabaseConfig) *db.Database {
+	database := connectDatabase(cfg)
+	if err := database.CheckSchema(); err != nil {
+		log.Fatalf("Failed to check schema: %v", err)
 	}
+	return database
+}
 
-	dataSourceName := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s sslmode=%s", host, port, dbname, user, string(password), sslmode)
+// connectDatabase connects to the configured database.
+func connectDatabase(cfg config.DatabaseConfig) *db.Database {
+	dataSourceName := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=%s", cfg.Host, cfg.Port, cfg.Name, cfg.User, cfg.Password, cfg.SSLMode)
 
 	database, err := db.NewDatabase(dataSourceName)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	return database
+}
 
-	err = database.AutoMigrate(&model.File{}, &model.User{})
+func logMigrations(migrations []db.Migration, verb string) {
+	for _, migration := range migrations {
+		log.Printf("%s migration %d_%s", verb, migration.Version, migration.Name)
+	}
+}
+
+// setupRegistry connects to the FileRegistry contract used to register uploads on chain.
+// Registration is disabled, and nil returned, when ETH_RPC_URL is not set.
+func setupRegistry(cfg config.ChainConfig) *chain.Registry {
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	signerKey, err := chain.ParseSignerKey(cfg.SignerKey)
 	if err != nil {
-		log.Fatalf("Failed to migrate schema: %v", err)
+		log.Fatalf("Failed to set up on-chain registration: %v", err)
 	}
 
-	return database
+	registryConfig := chain.Config{
+		ContractAddress: common.HexToAddress(cfg.RegistryAddress),
+		SignerKey:       signerKey,
+		GasLimit:        cfg.GasLimit,
+		GasFeeCap:       cfg.GasFeeCap,
+		GasTipCap:       cfg.GasTipCap,
+		PollInterval:    cfg.ReceiptPollInterval,
+		ReceiptTimeout:  cfg.ReceiptTimeout,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := chain.Dial(ctx, cfg.RPCURL)
+	if err != nil {
+		log.Fatalf("Failed to set up on-chain registration: %v", err)
+	}
+	registry, err := chain.NewRegistry(ctx, client, registryConfig)
+	if err != nil {
+		log.Fatalf("Failed to set up on-chain registration: %v", err)
+	}
+
+	log.Printf("Registering uploads in FileRegistry %s as %s", registryConfig.ContractAddress.Hex(), registry.Signer().Hex())
+	return registry
+}
+
+// authChainID returns the chain ID of the EIP-712 domain that sensitive actions are signed in:
+// the registry's chain when on-chain registration is enabled, and AUTH_CHAIN_ID otherwise.
+func authChainID(cfg config.AuthConfig, registry *chain.Registry) int64 {
+	if registry != nil {
+		return registry.ChainID().Int64()
+	}
+	return cfg.ChainID
+}
+
+// walletBackend returns the client used to check EIP-1271 contract wallet signatures, which
+// requires ETH_RPC_URL. Without it only externally owned accounts can sign in.
+func walletBackend(registry *chain.Registry) chain.WalletBackend {
+	if registry == nil {
+		return nil
+	}
+	return registry.Backend()
+}
+
+// setupReceiptService sets up signing upload receipts with RECEIPT_SIGNING_KEY. Receipts are
+// disabled, and nil returned, when it is not set.
+func setupReceiptService(cfg config.ReceiptsConfig, registry *chain.Registry) *service.ReceiptService {
+	if cfg.SigningKey == "" {
+		return nil
+	}
+
+	signingKey, err := chain.ParseSignerKey(cfg.SigningKey)
+	if err != nil {
+		log.Fatalf("Failed to set up upload receipts: %v", err)
+	}
+
+	receiptService := service.NewReceiptService(signingKey, registry)
+	log.Printf("Signing upload receipts as %s", receiptService.Signer().Hex())
+	return receiptService
 }
-func setupIPFSStorage() *storage.IPFSStorage {
-	ipfsAddress := utils.GetEnvOrDefault("IPFS_ADDRESS", "/ip4/127.0.0.1/tcp/5001")
-	return storage.NewIPFSStorage(ipfsAddress)
+
+// startChainIndexer starts indexing FileRegistered events in the background when on-chain
+// registration is enabled, returning nil otherwise.
+func startChainIndexer(cfg config.ChainConfig, registry *chain.Registry, eventRepo repository.ChainEventRepository) *service.ChainIndexer {
+	if registry == nil {
+		return nil
+	}
+
+	indexer := service.NewChainIndexer(
+		registry,
+		eventRepo,
+		cfg.IndexerStartBlock,
+		cfg.Confirmations,
+		cfg.IndexerPollInterval,
+	)
+	go indexer.Run(context.Background())
+	return indexer
+}
+
+// setupKeyRing sets up wrapping file encryption keys with ENCRYPTION_KEK, also unwrapping keys
+// wrapped with the KEKs in ENCRYPTION_PREVIOUS_KEK_FILES. Keys are stored unwrapped, and nil
+// returned, when ENCRYPTION_KEK is not set.
+func setupKeyRing(cfg config.EncryptionConfig) *crypto.KeyRing {
+	if cfg.KEK == "" {
+		return nil
+	}
+
+	current, err := crypto.ParseKEK(cfg.KEK)
+	if err != nil {
+		log.Fatalf("Failed to set up key encryption: %v", err)
+	}
+	var previous [][]byte
+	for _, path := range cfg.PreviousKEKFiles {
+		kek, err := crypto.LoadKEK(path)
+		if err != nil {
+			log.Fatalf("Failed to set up key encryption: %v", err)
+		}
+		previous = append(previous, kek)
+	}
+
+	keyRing, err := crypto.NewKeyRing(current, previous...)
+	if err != nil {
+		log.Fatalf("Failed to set up key encryption: %v", err)
+	}
+	return keyRing
+}
+
+// setupHealthService sets up the readiness checks of the database, the IPFS API and, when
+// on-chain registration is enabled, the Ethereum RPC. The server stays ready without the RPC
+// unless downloads must be verified against the registry.
+func setupHealthService(cfg config.ChainConfig, database *db.Database, ipfsStorage *storage.IPFSStorage, registry *chain.Registry) *service.HealthService {
+	checks := []service.HealthCheck{
+		{Name: "postgres", Check: database.Ping, Critical: true},
+		{Name: "ipfs", Check: ipfsStorage.Ping, Critical: true},
+	}
+	if registry != nil {
+		checks = append(checks, service.HealthCheck{
+			Name: "ethereum",
+			Check: func(ctx context.Context) error {
+				_, err := registry.BlockNumber(ctx)
+				return err
+			},
+			Critical: cfg.VerifyMode == service.ChainVerifyStrict,
+		})
+	}
+	return service.NewHealthService(checks...)
+}
+
+func setupIPFSStorage(cfg config.IPFSConfig) *storage.IPFSStorage {
+	return storage.NewIPFSStorage(cfg.Address)
 }
 
 func setupRouter(apiHandler *api.Handler) *chi.Mux {
@@ -89,17 +312,16 @@ func setupRouter(apiHandler *api.Handler) *chi.Mux {
 func corsHandler() func(http.Handler) http.Handler {
 	return cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "EthereumAddress"},
-		ExposedHeaders:   []string{"Link", "X-File-Hash"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "EthereumAddress", "traceparent", "tracestate"},
+		ExposedHeaders:   []string{"Link", "Deprecation", "Sunset", "X-File-Hash", "X-Chain-Verification", "X-Chain-Owner", "X-Chain-Registered-At"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}).Handler
 }
 
-func startServer(router *chi.Mux) {
-	port := utils.GetEnvOrDefault("PORT", defaultPort)
-	addr := fmt.Sprintf(":%s", port)
+func startServer(router *chi.Mux, port int) {
+	addr := fmt.Sprintf(":%d", port)
 	fmt.Printf("Starting SafeTransfer server on %s...\n", addr)
 
 	server := &http.Server{
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"SafeTransfer/pkg/client"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// credentials locate the wallet the CLI signs with.
+type credentials struct {
+	keystoreFile string
+	passwordFile string
+	keyFile      string
+}
+
+func (c *credentials) register(flags *flag.FlagSet) {
+	flags.StringVar(&c.keystoreFile, "keystore", os.Getenv("SAFETRANSFER_KEYSTORE"), "encrypted keystore `file` to sign with ($SAFETRANSFER_KEYSTORE)")
+	flags.StringVar(&c.passwordFile, "password-file", os.Getenv("SAFETRANSFER_PASSWORD_FILE"), "`file` holding the keystore password; $SAFETRANSFER_PASSWORD is used otherwise")
+	flags.StringVar(&c.keyFile, "key-file", os.Getenv("SAFETRANSFER_KEY_FILE"), "`file` holding a hex-encoded private key to sign with ($SAFETRANSFER_KEY_FILE)")
+}
+
+// signer loads the wallet from the keystore or private-key file.
+func (c *credentials) signer() (*client.KeySigner, error) {
+	switch {
+	case c.keystoreFile != "" && c.keyFile != "":
+		return nil, usagef("use either --keystore or --key-file, not both")
+	case c.keyFile != "":
+		key, err := crypto.LoadECDSA(c.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load private key: %w", err)
+		}
+		return client.NewKeySigner(key), nil
+	case c.keystoreFile != "":
+		keyJSON, err := os.ReadFile(c.keystoreFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keystore: %w", err)
+		}
+		password, err := c.password()
+		if err != nil {
+			return nil, err
+		}
+		key, err := keystore.DecryptKey(keyJSON, password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt keystore: %w", err)
+		}
+		return client.NewKeySigner(key.PrivateKey), nil
+	}
+	return nil, errors.New("not signed in: set --token, --api-key, --keystore or --key-file")
+}
+
+// password returns the keystore password from --password-file or $SAFETRANSFER_PASSWORD.
+func (c *credentials) password() (string, error) {
+	if c.passwordFile == "" {
+		return os.Getenv("SAFETRANSFER_PASSWORD"), nil
+	}
+	password, err := os.ReadFile(c.passwordFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+	return strings.TrimRight(string(password), "\r\n"), nil
+}
+
+// login signs in with the wallet and prints the session token, so that later commands can
+// reuse it through $SAFETRANSFER_TOKEN.
+func (a *app) login(ctx context.Context, args []string) error {
+	flags := a.newFlagSet("login", "")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return usagef("login takes no arguments")
+	}
+
+	signer, err := a.creds.signer()
+	if err != nil {
+		return err
+	}
+	token, err := a.client.Login(ctx, signer)
+	if err != nil {
+		return err
+	}
+
+	if a.json {
+		return a.writeJSON(map[string]string{"address": signer.Address().Hex(), "token": token})
+	}
+	fmt.Fprintln(a.stdout, token)
+	return nil
+}
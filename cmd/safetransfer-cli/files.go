@@ -0,0 +1,392 @@
+package main
+
+import (
+	"SafeTransfer/pkg/client"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// stringList is a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// uploadReport is the outcome of uploading one file.
+type uploadReport struct {
+	Path string `json:"path"`
+	*client.UploadResult
+	Error string `json:"error,omitempty"`
+}
+
+// upload uploads files, and directories with their subdirectories as folders.
+func (a *app) upload(ctx context.Context, args []string) error {
+	flags := a.newFlagSet("upload", "<path>...")
+	folder := flags.Uint("folder", 0, "`id` of the folder to upload to; 0 is the root folder")
+	description := flags.String("description", "", "description of the uploaded files")
+	var tags stringList
+	flags.Var(&tags, "tag", "`tag` of the uploaded files; may be repeated")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return usagef("upload needs at least one path")
+	}
+	if err := a.ensureAuthenticated(ctx); err != nil {
+		return err
+	}
+
+	opts := client.UploadOptions{Description: *description, Tags: tags}
+	var reports []uploadReport
+	for _, path := range flags.Args() {
+		info, err := os.Stat(path)
+		if err != nil {
+			reports = append(reports, a.reportUpload(path, nil, err))
+			continue
+		}
+		if info.IsDir() {
+			dirReports, err := a.uploadDir(ctx, path, uint(*folder), opts)
+			reports = append(reports, dirReports...)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		opts.FolderID = uint(*folder)
+		result, err := a.uploadFile(ctx, path, opts)
+		reports = append(reports, a.reportUpload(path, result, err))
+	}
+
+	if a.json {
+		if err := a.writeJSON(reports); err != nil {
+			return err
+		}
+	}
+	return failures("upload", len(reports), countFailed(reports))
+}
+
+// uploadDir uploads the files under dir into a folder of the same name in parentID, creating
+// a folder for each subdirectory. Errors uploading a file are reported and skipped; errors
+// creating a folder stop the upload.
+func (a *app) uploadDir(ctx context.Context, dir string, parentID uint, opts client.UploadOptions) ([]uploadReport, error) {
+	folders := make(map[string]uint)
+	var reports []uploadReport
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			reports = append(reports, a.reportUpload(path, nil, err))
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() {
+			parent := parentID
+			if path != dir {
+				parent = folders[filepath.Dir(path)]
+			}
+			id, err := a.ensureFolder(ctx, filepath.Base(path), parent)
+			if err != nil {
+				return fmt.Errorf("failed to create folder for %s: %w", path, err)
+			}
+			folders[path] = id
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		opts.FolderID = folders[filepath.Dir(path)]
+		result, err := a.uploadFile(ctx, path, opts)
+		reports = append(reports, a.reportUpload(path, result, err))
+		return nil
+	})
+	return reports, err
+}
+
+// ensureFolder returns the ID of the folder called name in parentID, creating it if needed.
+func (a *app) ensureFolder(ctx context.Context, name string, parentID uint) (uint, error) {
+	folder, err := a.client.CreateFolder(ctx, name, parentID)
+	if err == nil {
+		return folder.ID, nil
+	}
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "folder_exists" {
+		return 0, err
+	}
+
+	// Subfolders come before files, on as many pages as they take
+	for page := 1; ; page++ {
+		contents, err := a.client.ListFolder(ctx, parentID, page, 0)
+		if errors.As(err, &apiErr) && apiErr.Code == "insufficient_scope" {
+			return 0, fmt.Errorf("folder %q exists, and finding it needs an API key with the read-metadata scope: %w", name, err)
+		} else if err != nil {
+			return 0, err
+		}
+		for _, folder := range contents.Folders {
+			if folder.Name == name {
+				return folder.ID, nil
+			}
+		}
+		if len(contents.Folders) == 0 || len(contents.Files) > 0 {
+			return 0, fmt.Errorf("folder %q exists but was not found", name)
+		}
+	}
+}
+
+func (a *app) uploadFile(ctx context.Context, path string, opts client.UploadOptions) (*client.UploadResult, error) {
+	bar := a.newProgressBar(filepath.Base(path))
+	if bar != nil {
+		opts.Progress = bar.update
+	}
+	result, err := a.client.UploadFile(ctx, path, opts)
+	bar.finish(err)
+	return result, err
+}
+
+// reportUpload records the outcome of an upload, printing it unless JSON output is enabled.
+func (a *app) reportUpload(path string, result *client.UploadResult, err error) uploadReport {
+	report := uploadReport{Path: path, UploadResult: result}
+	if err != nil {
+		report.Error = err.Error()
+		report.UploadResult = nil
+	}
+	if a.json {
+		return report
+	}
+	if err != nil {
+		fmt.Fprintf(a.stderr, "safetransfer-cli: %s: %v\n", path, err)
+	} else {
+		fmt.Fprintf(a.stdout, "%s\t%s\tv%d\n", result.CID, path, result.Version)
+	}
+	return report
+}
+
+func countFailed(reports []uploadReport) int {
+	failed := 0
+	for _, report := range reports {
+		if report.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// failures returns an error if any of total operations failed.
+func failures(operation string, total, failed int) error {
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d %ss failed", failed, total, operation)
+}
+
+// downloadReport is the outcome of downloading one file.
+type downloadReport struct {
+	CID               string `json:"cid"`
+	Path              string `json:"path,omitempty"`
+	Hash              string `json:"hash,omitempty"`
+	Size              int64  `json:"size,omitempty"`
+	ChainVerification string `json:"chainVerification,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+// download downloads files by CID, keeping them only if their content matches X-File-Hash.
+func (a *app) download(ctx context.Context, args []string) error {
+	flags := a.newFlagSet("download", "<cid>...")
+	output := flags.String("o", "", "output `path`: a file, \"-\" for stdout, or with several CIDs a directory; defaults to the current directory")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return usagef("download needs at least one CID")
+	}
+	if *output == "-" && flags.NArg() > 1 {
+		return usagef("only one CID can be downloaded to stdout")
+	}
+	if err := a.ensureAuthenticated(ctx); err != nil {
+		return err
+	}
+
+	several := flags.NArg() > 1
+	var reports []downloadReport
+	var lastErr error
+	failed := 0
+	for _, cid := range flags.Args() {
+		report, err := a.downloadFile(ctx, cid, *output, several)
+		if err != nil {
+			failed++
+			lastErr = err
+			report.Error = err.Error()
+			if several && !a.json {
+				fmt.Fprintf(a.stderr, "safetransfer-cli: %s: %v\n", cid, err)
+			}
+		} else if !a.json && report.Path != "-" {
+			fmt.Fprintf(a.stdout, "%s\t%s\t%s\n", report.CID, report.Path, report.Hash)
+		}
+		reports = append(reports, report)
+	}
+
+	if a.json && *output != "-" {
+		if err := a.writeJSON(reports); err != nil {
+			return err
+		}
+	}
+	if !several {
+		return lastErr
+	}
+	return failures("download", len(reports), failed)
+}
+
+// downloadFile downloads cid to output. The content is written to a temporary file that is
+// renamed into place only once its hash has been verified.
+func (a *app) downloadFile(ctx context.Context, cid, output string, several bool) (downloadReport, error) {
+	report := downloadReport{CID: cid}
+	bar := a.newProgressBar(cid)
+	var progress client.ProgressFunc
+	if bar != nil {
+		progress = bar.update
+	}
+	download, err := a.client.Download(ctx, cid, progress)
+	if err != nil {
+		return report, err
+	}
+	defer download.Close()
+
+	if output == "-" {
+		report.Path = "-"
+		report.Size, err = io.Copy(a.stdout, download)
+		bar.finish(err)
+		// Sent as trailers for older files, so only known once the content has been read
+		report.Hash = download.Hash
+		report.ChainVerification = download.ChainVerification
+		return report, err
+	}
+
+	path := output
+	if info, statErr := os.Stat(output); output == "" || several || (statErr == nil && info.IsDir()) {
+		path = filepath.Join(output, download.Filename)
+	}
+	report.Path = path
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return report, err
+	}
+	defer os.Remove(temp.Name())
+
+	bar.rename(download.Filename)
+	report.Size, err = io.Copy(temp, download)
+	bar.finish(err)
+	report.Hash = download.Hash
+	report.ChainVerification = download.ChainVerification
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return report, err
+	}
+	return report, os.Rename(temp.Name(), path)
+}
+
+// list lists the contents of a folder.
+func (a *app) list(ctx context.Context, args []string) error {
+	flags := a.newFlagSet("ls", "[folder-id|root]")
+	page := flags.Int("page", 1, "`page` to list")
+	pageSize := flags.Int("page-size", 0, "number of entries per page; 0 is the server's default")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	var folderID uint
+	switch {
+	case flags.NArg() > 1:
+		return usagef("ls takes at most one folder")
+	case flags.NArg() == 1 && flags.Arg(0) != "root":
+		id, err := parseUint("folder ID", flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		folderID = id
+	}
+	if err := a.ensureAuthenticated(ctx); err != nil {
+		return err
+	}
+
+	contents, err := a.client.ListFolder(ctx, folderID, *page, *pageSize)
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.writeJSON(contents)
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	for _, folder := range contents.Folders {
+		fmt.Fprintf(w, "%d\t-\t-\t%s/\n", folder.ID, folder.Name)
+	}
+	a.printFiles(w, contents.Files)
+	return w.Flush()
+}
+
+// search searches the caller's files.
+func (a *app) search(ctx context.Context, args []string) error {
+	flags := a.newFlagSet("search", "[text]")
+	var tags stringList
+	flags.Var(&tags, "tag", "`tag` the files must have; may be repeated")
+	mimeType := flags.String("mime-type", "", "MIME `type` of the files, or a type/* wildcard")
+	page := flags.Int("page", 1, "`page` to list")
+	pageSize := flags.Int("page-size", 0, "number of files per page; 0 is the server's default")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := a.ensureAuthenticated(ctx); err != nil {
+		return err
+	}
+
+	list, err := a.client.SearchFiles(ctx, client.SearchOptions{
+		Text:     strings.Join(flags.Args(), " "),
+		Tags:     tags,
+		MimeType: *mimeType,
+		Page:     *page,
+		PageSize: *pageSize,
+	})
+	if err != nil {
+		return err
+	}
+	return a.printFileList(list)
+}
+
+// printFileList writes a page of files.
+func (a *app) printFileList(list *client.FileList) error {
+	if a.json {
+		return a.writeJSON(list)
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	a.printFiles(w, list.Files)
+	return w.Flush()
+}
+
+// printFiles writes one line per file: its file ID, CID, size and name.
+func (a *app) printFiles(w io.Writer, files []client.File) {
+	for _, file := range files {
+		id := "-"
+		if file.FileID != nil {
+			id = strconv.FormatUint(uint64(*file.FileID), 10)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s (v%d)\n", id, file.CID, formatBytes(file.Size), file.Name, file.Version)
+	}
+}
@@ -0,0 +1,244 @@
+// Command safetransfer-cli is a command-line client for a SafeTransfer server.
+//
+// It signs in by signing a nonce with a local keystore or private-key file, or uses a token or
+// API key from the environment, and uploads, downloads, lists and shares files:
+//
+//	export SAFETRANSFER_URL=https://safetransfer.example.com
+//	export SAFETRANSFER_TOKEN=$(safetransfer-cli login --key-file key.hex)
+//	safetransfer-cli upload --folder 7 report.pdf photos/
+//	safetransfer-cli download -o report.pdf QmXyz...
+//	safetransfer-cli --json ls root
+//
+// With --json every command writes a single JSON document to stdout, and errors are written to
+// stderr as JSON objects, so that the output can be used in scripts.
+package main
+
+import (
+	"SafeTransfer/pkg/client"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+)
+
+const usage = `Usage: safetransfer-cli [global flags] <command> [flags] [arguments]
+
+Commands:
+  login                          sign in and print a session token
+  upload <path>...               upload files, and directories recursively
+  download <cid>...              download files, verifying their hashes
+  ls [folder-id|root]            list the contents of a folder
+  search [text]                  search files
+  shares grant <file-id> <address>
+  shares list <file-id>
+  shares revoke <file-id> <address>
+  shares received                list the files shared with you
+
+Run "safetransfer-cli <command> -h" for the flags of a command.
+
+Global flags:
+`
+
+// Exit codes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// app holds the global options and the client built from them.
+type app struct {
+	client   *client.Client
+	creds    credentials
+	json     bool
+	quiet    bool
+	stdout   io.Writer
+	stderr   io.Writer
+	progress bool // whether progress bars are drawn on stderr
+}
+
+// usageError is an error in the command line, reported with exit code 2.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string) int {
+	a := &app{stdout: os.Stdout, stderr: os.Stderr}
+
+	flags := flag.NewFlagSet("safetransfer-cli", flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	flags.Usage = func() {
+		fmt.Fprint(a.stderr, usage)
+		flags.PrintDefaults()
+	}
+	server := flags.String("server", envOr("SAFETRANSFER_URL", "http://localhost:8083"), "`URL` of the server ($SAFETRANSFER_URL)")
+	chainID := flags.Int64("chain-id", envInt64("SAFETRANSFER_CHAIN_ID", 1), "chain `id` that actions such as shares are signed for ($SAFETRANSFER_CHAIN_ID)")
+	token := flags.String("token", os.Getenv("SAFETRANSFER_TOKEN"), "session `token` from login ($SAFETRANSFER_TOKEN)")
+	apiKey := flags.String("api-key", os.Getenv("SAFETRANSFER_API_KEY"), "API `key` ($SAFETRANSFER_API_KEY)")
+	a.creds.register(flags)
+	flags.BoolVar(&a.json, "json", false, "write JSON output")
+	flags.BoolVar(&a.quiet, "quiet", false, "do not draw progress bars")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	a.client = client.New(*server)
+	a.client.ChainID = *chainID
+	a.client.Token = *token
+	a.client.APIKey = *apiKey
+	a.progress = !a.json && !a.quiet && isTerminal(os.Stderr)
+
+	commands := map[string]func(context.Context, []string) error{
+		"login":    a.login,
+		"upload":   a.upload,
+		"download": a.download,
+		"ls":       a.list,
+		"search":   a.search,
+		"shares":   a.shares,
+	}
+	command, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(a.stderr, "safetransfer-cli: unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return exitUsage
+	}
+
+	err := command(ctx, flags.Args()[1:])
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(a.stderr, "safetransfer-cli: %s\n", usageErr.message)
+		return exitUsage
+	default:
+		a.reportError(err)
+		return exitError
+	}
+}
+
+// newFlagSet creates the flag set of a command.
+func (a *app) newFlagSet(name, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(a.stderr, "Usage: safetransfer-cli %s [flags] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses the arguments of a command, turning flag errors into usage errors.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usagef("%v", err)
+	}
+	return nil
+}
+
+// ensureAuthenticated signs in with the configured wallet unless the client already has a token
+// or API key.
+func (a *app) ensureAuthenticated(ctx context.Context) error {
+	if a.client.Token != "" || a.client.APIKey != "" {
+		return nil
+	}
+	signer, err := a.creds.signer()
+	if err != nil {
+		return err
+	}
+	_, err = a.client.Login(ctx, signer)
+	return err
+}
+
+// writeJSON writes v to stdout as indented JSON.
+func (a *app) writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(a.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// reportError writes err to stderr, as a JSON object when JSON output is enabled.
+func (a *app) reportError(err error) {
+	if !a.json {
+		fmt.Fprintf(a.stderr, "safetransfer-cli: %v\n", err)
+		return
+	}
+
+	report := struct {
+		Error  string `json:"error"`
+		Code   string `json:"code,omitempty"`
+		Status int    `json:"status,omitempty"`
+	}{Error: err.Error()}
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		report.Code = apiErr.Code
+		report.Status = apiErr.StatusCode
+	}
+	if errors.Is(err, client.ErrHashMismatch) {
+		report.Code = "hash_mismatch"
+	}
+	json.NewEncoder(a.stderr).Encode(report)
+}
+
+// parseUint parses the ID argument name.
+func parseUint(name, value string) (uint, error) {
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || id == 0 {
+		return 0, usagef("invalid %s %q", name, value)
+	}
+	return uint(id), nil
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func envInt64(name string, fallback int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(name), 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// isTerminal reports whether f is a character device, such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	progressBarWidth    = 30
+	progressLabelWidth  = 24
+	progressRedrawEvery = 100 * time.Millisecond
+)
+
+// progressBar draws the progress of a transfer on one line of a terminal. A nil *progressBar
+// draws nothing, so callers need not check whether progress bars are enabled.
+type progressBar struct {
+	w           io.Writer
+	label       string
+	transferred int64
+	total       int64
+	drawn       time.Time
+}
+
+// newProgressBar returns a progress bar for a transfer called label, or nil if progress bars
+// are disabled.
+func (a *app) newProgressBar(label string) *progressBar {
+	if !a.progress {
+		return nil
+	}
+	return &progressBar{w: a.stderr, label: label, total: -1}
+}
+
+// update is a client.ProgressFunc that redraws the bar at most every progressRedrawEvery.
+func (b *progressBar) update(transferred, total int64) {
+	b.transferred, b.total = transferred, total
+	if time.Since(b.drawn) >= progressRedrawEvery {
+		b.draw()
+	}
+}
+
+// rename changes the label of the bar.
+func (b *progressBar) rename(label string) {
+	if b != nil {
+		b.label = label
+	}
+}
+
+// finish draws the final state of the bar and ends its line, if it was drawn at all.
+func (b *progressBar) finish(err error) {
+	if b == nil || b.drawn.IsZero() {
+		return
+	}
+	b.draw()
+	if err != nil {
+		fmt.Fprintln(b.w, " failed")
+		return
+	}
+	fmt.Fprintln(b.w)
+}
+
+func (b *progressBar) draw() {
+	b.drawn = time.Now()
+
+	label := b.label
+	if len(label) > progressLabelWidth {
+		label = label[:progressLabelWidth-3] + "..."
+	}
+	if b.total <= 0 {
+		fmt.Fprintf(b.w, "\r%-*s %s", progressLabelWidth, label, formatBytes(b.transferred))
+		return
+	}
+
+	fraction := float64(b.transferred) / float64(b.total)
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	fmt.Fprintf(b.w, "\r%-*s [%s] %3.0f%% %s/%s", progressLabelWidth, label, bar, fraction*100, formatBytes(b.transferred), formatBytes(b.total))
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. 1.5 MiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
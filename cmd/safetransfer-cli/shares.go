@@ -0,0 +1,142 @@
+package main
+
+import (
+	"SafeTransfer/pkg/client"
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+)
+
+// shares manages the users files are shared with.
+func (a *app) shares(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usagef("shares needs a subcommand: grant, list, revoke or received")
+	}
+	switch args[0] {
+	case "grant":
+		return a.grantShare(ctx, args[1:])
+	case "list":
+		return a.listShares(ctx, args[1:])
+	case "revoke":
+		return a.revokeShare(ctx, args[1:])
+	case "received":
+		return a.listReceivedShares(ctx, args[1:])
+	}
+	return usagef("unknown shares subcommand %q", args[0])
+}
+
+// grantShare shares a file. Grants are signed by the owner's wallet, so it needs --keystore or
+// --key-file even when a token is set.
+func (a *app) grantShare(ctx context.Context, args []string) error {
+	flags := a.newFlagSet("shares grant", "<file-id> <address>")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return usagef("shares grant needs a file ID and an address")
+	}
+	fileID, err := parseUint("file ID", flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	signer, err := a.creds.signer()
+	if err != nil {
+		return err
+	}
+	if a.client.Token == "" {
+		// API keys cannot grant shares, so sign in with the wallet
+		a.client.APIKey = ""
+		if _, err := a.client.Login(ctx, signer); err != nil {
+			return err
+		}
+	}
+
+	share, err := a.client.GrantShare(ctx, signer, fileID, flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.writeJSON(share)
+	}
+	fmt.Fprintf(a.stdout, "shared file %d with %s\n", share.FileID, share.Grantee)
+	return nil
+}
+
+func (a *app) listShares(ctx context.Context, args []string) error {
+	flags := a.newFlagSet("shares list", "<file-id>")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usagef("shares list needs a file ID")
+	}
+	fileID, err := parseUint("file ID", flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := a.ensureAuthenticated(ctx); err != nil {
+		return err
+	}
+
+	shares, err := a.client.ListShares(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if a.json {
+		if shares == nil {
+			shares = []client.Share{}
+		}
+		return a.writeJSON(shares)
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	for _, share := range shares {
+		fmt.Fprintf(w, "%s\t%s\n", share.Grantee, share.CreatedAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+func (a *app) revokeShare(ctx context.Context, args []string) error {
+	flags := a.newFlagSet("shares revoke", "<file-id> <address>")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return usagef("shares revoke needs a file ID and an address")
+	}
+	fileID, err := parseUint("file ID", flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := a.ensureAuthenticated(ctx); err != nil {
+		return err
+	}
+
+	if err := a.client.RevokeShare(ctx, fileID, flags.Arg(1)); err != nil {
+		return err
+	}
+	if a.json {
+		return a.writeJSON(map[string]interface{}{"fileId": fileID, "grantee": flags.Arg(1), "revoked": true})
+	}
+	fmt.Fprintf(a.stdout, "revoked access of %s to file %d\n", flags.Arg(1), fileID)
+	return nil
+}
+
+func (a *app) listReceivedShares(ctx context.Context, args []string) error {
+	flags := a.newFlagSet("shares received", "")
+	page := flags.Int("page", 1, "`page` to list")
+	pageSize := flags.Int("page-size", 0, "number of files per page; 0 is the server's default")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := a.ensureAuthenticated(ctx); err != nil {
+		return err
+	}
+
+	list, err := a.client.ListSharedWithMe(ctx, *page, *pageSize)
+	if err != nil {
+		return err
+	}
+	return a.printFileList(list)
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"SafeTransfer/internal/model"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type userResponse struct {
+	EthereumAddress string     `json:"ethereumAddress"`
+	Role            string     `json:"role"`
+	Disabled        bool       `json:"disabled"`
+	DisabledAt      *time.Time `json:"disabledAt"`
+	BytesStored     int64      `json:"bytesStored"`
+	FileCount       int64      `json:"fileCount"`
+	QuotaBytes      *int64     `json:"quotaBytes"`
+	QuotaFiles      *int64     `json:"quotaFiles"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+type userListResponse struct {
+	Users    []userResponse `json:"users"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"pageSize"`
+	Total    int64          `json:"total"`
+}
+
+type statsResponse struct {
+	Users struct {
+		Total    int64            `json:"total"`
+		Disabled int64            `json:"disabled"`
+		ByRole   map[string]int64 `json:"byRole"`
+	} `json:"users"`
+	Storage struct {
+		Files       int64 `json:"files"`
+		Versions    int64 `json:"versions"`
+		BytesStored int64 `json:"bytesStored"`
+	} `json:"storage"`
+}
+
+func newUserResponse(user *model.User) userResponse {
+	role := user.Role
+	if role == "" {
+		role = model.RoleUser
+	}
+	return userResponse{
+		EthereumAddress: user.EthereumAddress,
+		Role:            role,
+		Disabled:        user.DisabledAt != nil,
+		DisabledAt:      user.DisabledAt,
+		BytesStored:     user.BytesStored,
+		FileCount:       user.FileCount,
+		QuotaBytes:      user.QuotaBytes,
+		QuotaFiles:      user.QuotaFiles,
+		CreatedAt:       user.CreatedAt,
+	}
+}
+
+func (h *Handler) handleAdminListUsers(w http.ResponseWriter, r *http.Request) {
+	page, pageSize, err := parsePagination(r)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	users, total, err := h.AdminService.ListUsers(r.Context(), page, pageSize)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	response := userListResponse{
+		Users:    make([]userResponse, 0, len(users)),
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	}
+	for i := range users {
+		response.Users = append(response.Users, newUserResponse(&users[i]))
+	}
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) handleAdminGetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.AdminService.GetUser(r.Context(), chi.URLParam(r, "address"))
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, newUserResponse(user))
+}
+
+// handleAdminListUserFiles lists and searches any user's files with the parameters of /files/search.
+func (h *Handler) handleAdminListUserFiles(w http.ResponseWriter, r *http.Request) {
+	h.searchFiles(w, r, model.CanonicalAddress(chi.URLParam(r, "address")))
+}
+
+func (h *Handler) handleAdminSetRole(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	user, err := h.AdminService.SetRole(r.Context(), r.Header.Get("EthereumAddress"), chi.URLParam(r, "address"), req.Role)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, newUserResponse(user))
+}
+
+func (h *Handler) handleAdminDisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, true)
+}
+
+func (h *Handler) handleAdminEnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, false)
+}
+
+func (h *Handler) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	user, err := h.AdminService.SetDisabled(r.Context(), r.Header.Get("EthereumAddress"), chi.URLParam(r, "address"), disabled)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, newUserResponse(user))
+}
+
+// handleAdminDeleteFile deletes any user's file with all of its versions.
+func (h *Handler) handleAdminDeleteFile(w http.ResponseWriter, r *http.Request) {
+	fileID, err := parseID(chi.URLParam(r, "fileId"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	if err := h.AdminService.ForceDeleteFile(r.Context(), r.Header.Get("EthereumAddress"), fileID); err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleAdminStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.AdminService.Stats(r.Context())
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	var response statsResponse
+	response.Users.Total = stats.Users.Total
+	response.Users.Disabled = stats.Users.Disabled
+	response.Users.ByRole = stats.Users.ByRole
+	response.Storage.Files = stats.Storage.LogicalFiles
+	response.Storage.Versions = stats.Storage.Versions
+	response.Storage.BytesStored = stats.Storage.BytesStored
+	RespondWithJSON(w, http.StatusOK, response)
+}
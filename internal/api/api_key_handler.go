@@ -0,0 +1,84 @@
+package api
+
+import (
+	"SafeTransfer/internal/model"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type apiKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func newAPIKeyResponse(apiKey *model.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
+
+// handleCreateAPIKey issues an API key. The key is only ever returned in this response.
+func (h *Handler) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	apiKey, key, err := h.APIKeyService.CreateAPIKey(r.Context(), r.Header.Get("EthereumAddress"), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, struct {
+		apiKeyResponse
+		Key string `json:"key"`
+	}{newAPIKeyResponse(apiKey), key})
+}
+
+func (h *Handler) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	apiKeys, err := h.APIKeyService.ListAPIKeys(r.Context(), r.Header.Get("EthereumAddress"))
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	response := make([]apiKeyResponse, 0, len(apiKeys))
+	for i := range apiKeys {
+		response = append(response, newAPIKeyResponse(&apiKeys[i]))
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{"apiKeys": response})
+}
+
+func (h *Handler) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	if err := h.APIKeyService.RevokeAPIKey(r.Context(), r.Header.Get("EthereumAddress"), id); err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"SafeTransfer/internal/service"
+	"SafeTransfer/pkg/receipt"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+type batchUploadResult struct {
+	FileName         string `json:"fileName"`
+	Status           int    `json:"status"`
+	CID              string `json:"cid,omitempty"`
+	OriginalFileHash string `json:"originalFileHash,omitempty"`
+	FileID           *uint  `json:"fileId,omitempty"`
+	Version          int    `json:"version,omitempty"`
+	Error            string `json:"error,omitempty"`
+	Code             string `json:"code,omitempty"`
+
+	Receipt *receipt.SignedReceipt `json:"receipt,omitempty"`
+}
+
+// handleBatchUpload stores every part of the "files" form field and reports a result per file.
+// The optional folderId, description and tags fields apply to all of them.
+func (h *Handler) handleBatchUpload(w http.ResponseWriter, r *http.Request) {
+	if err := parseMultipartForm(r); err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	folderID, err := parseOptionalID(r.FormValue("folderId"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid folder ID")
+		return
+	}
+
+	opts := service.UploadOptions{
+		FolderID:    folderID,
+		Description: r.FormValue("description"),
+		Tags:        parseTags(r.Form["tags"]),
+	}
+	results, err := h.FileService.UploadFiles(r.Context(), r.MultipartForm.File["files"], r.Header.Get("EthereumAddress"), opts)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	response := struct {
+		Succeeded int                 `json:"succeeded"`
+		Failed    int                 `json:"failed"`
+		Results   []batchUploadResult `json:"results"`
+	}{Results: make([]batchUploadResult, 0, len(results))}
+
+	for _, result := range results {
+		item := batchUploadResult{FileName: result.FileName, Status: http.StatusOK}
+		if result.Err != nil {
+			item.Status, item.Code, item.Error = describeError(result.Err)
+			if item.Status >= http.StatusInternalServerError {
+				log.Printf("Failed to upload %s: %v", result.FileName, result.Err)
+			}
+			response.Failed++
+		} else {
+			item.CID = result.File.CID
+			item.OriginalFileHash = result.OriginalFileHash
+			item.FileID = result.File.LogicalFileID
+			item.Version = result.File.Version
+			item.Receipt = h.issueReceipt(result.File, result.OriginalFileHash)
+			response.Succeeded++
+		}
+		response.Results = append(response.Results, item)
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// handleDownloadArchive streams a ZIP archive of the requested CIDs. Every file is decrypted and
+// verified on the fly; if one fails after streaming has started, the connection is aborted so
+// that the client never receives a complete archive containing unverified content.
+func (h *Handler) handleDownloadArchive(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CIDs []string `json:"cids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	files, err := h.DownloadService.ResolveArchive(r.Context(), r.Header.Get("EthereumAddress"), req.CIDs)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	filename := fmt.Sprintf("safetransfer-%s.zip", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Header().Set("Content-Type", "application/zip")
+	w.WriteHeader(http.StatusOK)
+
+	if err := h.DownloadService.WriteArchive(r.Context(), files, w); err != nil {
+		log.Printf("Aborting archive download: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
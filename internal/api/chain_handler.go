@@ -0,0 +1,89 @@
+package api
+
+import (
+	"SafeTransfer/internal/model"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type chainEventResponse struct {
+	BlockNumber uint64 `json:"blockNumber"`
+	LogIndex    uint   `json:"logIndex"`
+	TxHash      string `json:"txHash"`
+	Owner       string `json:"owner"`
+	CID         string `json:"cid"`
+	FileHash    string `json:"fileHash"`
+}
+
+type unregisteredFileResponse struct {
+	CID             string    `json:"cid"`
+	EthereumAddress string    `json:"ethereumAddress"`
+	ChainStatus     string    `json:"chainStatus"`
+	ChainTxHash     string    `json:"chainTxHash"`
+	ChainError      string    `json:"chainError"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+type reconciliationResponse struct {
+	LastIndexedBlock uint64                     `json:"lastIndexedBlock"`
+	MissingLocally   []chainEventResponse       `json:"missingLocally"`
+	MissingOnChain   []unregisteredFileResponse `json:"missingOnChain"`
+}
+
+func newChainEventResponse(event *model.ChainEvent) chainEventResponse {
+	return chainEventResponse{
+		BlockNumber: event.BlockNumber,
+		LogIndex:    event.LogIndex,
+		TxHash:      event.TxHash,
+		Owner:       event.Owner,
+		CID:         event.CID,
+		FileHash:    event.FileHash,
+	}
+}
+
+// handleChainReconciliation reports confirmed registrations without a stored file and stored
+// files without a confirmed registration, at most `limit` of each.
+func (h *Handler) handleChainReconciliation(w http.ResponseWriter, r *http.Request) {
+	if h.ChainIndexer == nil {
+		RespondWithError(w, http.StatusServiceUnavailable, "On-chain registration is not enabled")
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			RespondWithError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+
+	reconciliation, err := h.ChainIndexer.Reconcile(r.Context(), limit)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	response := reconciliationResponse{
+		LastIndexedBlock: reconciliation.LastBlock,
+		MissingLocally:   make([]chainEventResponse, 0, len(reconciliation.MissingLocally)),
+		MissingOnChain:   make([]unregisteredFileResponse, 0, len(reconciliation.MissingOnChain)),
+	}
+	for i := range reconciliation.MissingLocally {
+		response.MissingLocally = append(response.MissingLocally, newChainEventResponse(&reconciliation.MissingLocally[i]))
+	}
+	for _, file := range reconciliation.MissingOnChain {
+		response.MissingOnChain = append(response.MissingOnChain, unregisteredFileResponse{
+			CID:             file.CID,
+			EthereumAddress: file.EthereumAddress,
+			ChainStatus:     file.ChainStatus,
+			ChainTxHash:     file.ChainTxHash,
+			ChainError:      file.ChainError,
+			CreatedAt:       file.CreatedAt,
+		})
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
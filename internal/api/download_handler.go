@@ -0,0 +1,47 @@
+package api
+
+import (
+	"SafeTransfer/internal/service"
+	"log"
+	"net/http"
+	"time"
+)
+
+// sendDownload streams a file together with its hash and the result of its on-chain
+// verification. For files uploaded before their hash was recorded, these are only known once
+// the content has been streamed, so they are sent as trailers. If the content fails
+// verification after streaming has started, the connection is aborted so that the client never
+// receives a complete file that could not be verified.
+func sendDownload(w http.ResponseWriter, r *http.Request, download *service.Download) {
+	w.Header().Set("Content-Disposition", "attachment; filename="+download.File.CID)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	trailers := download.Hash == ""
+	if trailers {
+		w.Header().Set("Trailer", "X-File-Hash, X-Chain-Verification, X-Chain-Owner, X-Chain-Registered-At")
+	} else {
+		w.Header().Set("X-File-Hash", download.Hash)
+		setChainHeaders(w, download.Chain)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	if err := download.Stream(r.Context(), w); err != nil {
+		log.Printf("Aborting download of %s: %v", download.File.CID, err)
+		panic(http.ErrAbortHandler)
+	}
+	if trailers {
+		w.Header().Set("X-File-Hash", download.Hash)
+		setChainHeaders(w, download.Chain)
+	}
+}
+
+// setChainHeaders reports an on-chain verification result in the X-Chain-* headers.
+func setChainHeaders(w http.ResponseWriter, verification *service.ChainVerification) {
+	if verification == nil {
+		return
+	}
+	w.Header().Set("X-Chain-Verification", verification.Status)
+	if verification.Owner != "" {
+		w.Header().Set("X-Chain-Owner", verification.Owner)
+		w.Header().Set("X-Chain-Registered-At", verification.RegisteredAt.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fileResponse struct {
+	CID         string    `json:"cid"`
+	FileID      *uint     `json:"fileId"`
+	Version     int       `json:"version"`
+	Name        string    `json:"name"`
+	FolderID    *uint     `json:"folderId"`
+	Description string    `json:"description"`
+	Tags        []string  `json:"tags"`
+	Size        int64     `json:"size"`
+	MimeType    string    `json:"mimeType"`
+	ChainStatus string    `json:"chainStatus,omitempty"`
+	ChainTxHash string    `json:"chainTxHash,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type fileSearchResponse struct {
+	Files    []fileResponse `json:"files"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"pageSize"`
+	Total    int64          `json:"total"`
+}
+
+func newFileResponse(file *model.File) fileResponse {
+	tags := []string(file.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	return fileResponse{
+		CID:         file.CID,
+		FileID:      file.LogicalFileID,
+		Version:     file.Version,
+		Name:        file.Name,
+		FolderID:    file.FolderID,
+		Description: file.Description,
+		Tags:        tags,
+		Size:        file.Size,
+		MimeType:    file.MimeType,
+		ChainStatus: file.ChainStatus,
+		ChainTxHash: file.ChainTxHash,
+		CreatedAt:   file.CreatedAt,
+	}
+}
+
+func (h *Handler) handleUpdateFileDetails(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Description string   `json:"description"`
+		Tags        []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	file, err := h.FileService.UpdateFileDetails(r.Context(), r.Header.Get("EthereumAddress"), chi.URLParam(r, "cid"), req.Description, req.Tags)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, newFileResponse(file))
+}
+
+// handleSearchFiles serves GET /files/search. Supported query parameters are q (free text),
+// tag (repeatable, all must match), from and to (RFC 3339 timestamps or YYYY-MM-DD dates),
+// minSize and maxSize (bytes), mimeType (exact, or a "type/*" wildcard), page and pageSize.
+func (h *Handler) handleSearchFiles(w http.ResponseWriter, r *http.Request) {
+	h.searchFiles(w, r, r.Header.Get("EthereumAddress"))
+}
+
+// searchFiles answers a search over the files of ethereumAddress described by the query string.
+func (h *Handler) searchFiles(w http.ResponseWriter, r *http.Request, ethereumAddress string) {
+	query := r.URL.Query()
+	opts := service.FileSearchOptions{
+		Text:     query.Get("q"),
+		Tags:     parseTags(query["tag"]),
+		MimeType: query.Get("mimeType"),
+	}
+
+	var err error
+	if opts.Page, opts.PageSize, err = parsePagination(r); err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+	if opts.CreatedAfter, err = parseDateParam(query.Get("from"), false); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid from date")
+		return
+	}
+	if opts.CreatedBefore, err = parseDateParam(query.Get("to"), true); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid to date")
+		return
+	}
+	if opts.MinSize, err = parseSizeParam(query.Get("minSize")); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid minSize")
+		return
+	}
+	if opts.MaxSize, err = parseSizeParam(query.Get("maxSize")); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid maxSize")
+		return
+	}
+
+	files, total, err := h.FileService.SearchFiles(r.Context(), ethereumAddress, opts)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	response := fileSearchResponse{
+		Files:    make([]fileResponse, 0, len(files)),
+		Page:     opts.Page,
+		PageSize: opts.PageSize,
+		Total:    total,
+	}
+	for i := range files {
+		response.Files = append(response.Files, newFileResponse(&files[i]))
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// parseTags splits each value on commas, so tags can be sent as repeated fields or as a list.
+func parseTags(values []string) []string {
+	var tags []string
+	for _, value := range values {
+		tags = append(tags, strings.Split(value, ",")...)
+	}
+	return tags
+}
+
+// parseDateParam parses an RFC 3339 timestamp or a YYYY-MM-DD date. When endOfDay is set,
+// a plain date is moved to the following midnight so that the whole day is included.
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// parseSizeParam parses a non-negative byte count.
+func parseSizeParam(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return size, nil
+}
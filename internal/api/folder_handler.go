@@ -0,0 +1,231 @@
+package api
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// rootFolderID is the path parameter that addresses the owner's root folder in listings.
+const rootFolderID = "root"
+
+type folderResponse struct {
+	ID        uint      `json:"id"`
+	ParentID  *uint     `json:"parentId"`
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type folderContentsResponse struct {
+	Folder   *folderResponse  `json:"folder"`
+	Folders  []folderResponse `json:"folders"`
+	Files    []fileResponse   `json:"files"`
+	Page     int              `json:"page"`
+	PageSize int              `json:"pageSize"`
+	Total    int64            `json:"total"`
+}
+
+func newFolderResponse(folder *model.Folder) folderResponse {
+	return folderResponse{
+		ID:        folder.ID,
+		ParentID:  folder.ParentID,
+		Name:      folder.Name,
+		Path:      folder.Path,
+		CreatedAt: folder.CreatedAt,
+		UpdatedAt: folder.UpdatedAt,
+	}
+}
+
+func (h *Handler) handleCreateFolder(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name     string `json:"name"`
+		ParentID *uint  `json:"parentId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	folder, err := h.FolderService.CreateFolder(r.Context(), r.Header.Get("EthereumAddress"), req.Name, req.ParentID)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, newFolderResponse(folder))
+}
+
+func (h *Handler) handleListFolder(w http.ResponseWriter, r *http.Request) {
+	var folderID *uint
+	if idParam := chi.URLParam(r, "id"); idParam != rootFolderID {
+		id, err := parseID(idParam)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, "Invalid folder ID")
+			return
+		}
+		folderID = &id
+	}
+
+	page, pageSize, err := parsePagination(r)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	contents, err := h.FolderService.ListFolder(r.Context(), r.Header.Get("EthereumAddress"), folderID, page, pageSize)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	response := folderContentsResponse{
+		Folders:  make([]folderResponse, 0, len(contents.Folders)),
+		Files:    make([]fileResponse, 0, len(contents.Files)),
+		Page:     contents.Page,
+		PageSize: contents.PageSize,
+		Total:    contents.Total,
+	}
+	if contents.Folder != nil {
+		folder := newFolderResponse(contents.Folder)
+		response.Folder = &folder
+	}
+	for i := range contents.Folders {
+		response.Folders = append(response.Folders, newFolderResponse(&contents.Folders[i]))
+	}
+	for i := range contents.Files {
+		response.Files = append(response.Files, newFileResponse(&contents.Files[i]))
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) handleRenameFolder(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid folder ID")
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	folder, err := h.FolderService.RenameFolder(r.Context(), r.Header.Get("EthereumAddress"), id, req.Name)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, newFolderResponse(folder))
+}
+
+func (h *Handler) handleMoveFolder(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid folder ID")
+		return
+	}
+
+	var req struct {
+		ParentID *uint `json:"parentId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	folder, err := h.FolderService.MoveFolder(r.Context(), r.Header.Get("EthereumAddress"), id, req.ParentID)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, newFolderResponse(folder))
+}
+
+func (h *Handler) handleDeleteFolder(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid folder ID")
+		return
+	}
+
+	if err := h.FolderService.DeleteFolder(r.Context(), r.Header.Get("EthereumAddress"), id); err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleMoveFile(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		FolderID *uint `json:"folderId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	cid := chi.URLParam(r, "cid")
+	if err := h.FolderService.MoveFile(r.Context(), r.Header.Get("EthereumAddress"), cid, req.FolderID); err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{"cid": cid, "folderId": req.FolderID})
+}
+
+// parseID parses a numeric path or form parameter.
+func parseID(value string) (uint, error) {
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("invalid ID")
+	}
+	return uint(id), nil
+}
+
+// parseOptionalID parses an ID that may be omitted, returning nil when it is.
+func parseOptionalID(value string) (*uint, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := parseID(value)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// parsePagination reads the page and pageSize query parameters, applying defaults and limits.
+func parsePagination(r *http.Request) (int, int, error) {
+	page, pageSize := 1, service.DefaultPageSize
+	if value := r.URL.Query().Get("page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return 0, 0, fmt.Errorf("%w: page must be a positive integer", service.ErrInvalidPagination)
+		}
+		page = n
+	}
+	if value := r.URL.Query().Get("pageSize"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > service.MaxPageSize {
+			return 0, 0, fmt.Errorf("%w: pageSize must be between 1 and %d", service.ErrInvalidPagination, service.MaxPageSize)
+		}
+		pageSize = n
+	}
+	return page, pageSize, nil
+}
@@ -1,69 +1,201 @@
 package api
 
 import (
+	"SafeTransfer/internal/metrics"
+	"SafeTransfer/internal/model"
 	"SafeTransfer/internal/service"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
-	"log"
+	"mime/multipart"
 	"net/http"
-	"strings"
+	"time"
 )
 
+// Handler serves the API with the services its routes use.
 type Handler struct {
 	FileService     *service.FileService
 	DownloadService *service.DownloadService
 	UserService     *service.UserService
+	FolderService   *service.FolderService
+	VersionService  *service.VersionService
+	QuotaService    *service.QuotaService
+	ChainIndexer    *service.ChainIndexer // nil when on-chain registration is disabled
+	VerifyService   *service.VerificationService
+	ReceiptService  *service.ReceiptService // nil when receipts are disabled
+	ShareService    *service.ShareService
+	AdminService    *service.AdminService
+	APIKeyService   *service.APIKeyService
+	HealthService   *service.HealthService
+	MetricsToken    string // bearer token required to read /metrics, which are public when empty
 }
 
-func NewAPIHandler(fileService *service.FileService, downloadService *service.DownloadService, userService *service.UserService) *Handler {
-	return &Handler{
-		FileService:     fileService,
-		DownloadService: downloadService,
-		UserService:     userService,
-	}
-}
+// Deprecation of the unversioned paths, which predate /v1.
+var (
+	unversionedDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	unversionedSunset       = time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)
+)
 
+// RegisterRoutes mounts each version of the API under its own prefix, and the unversioned
+// paths as deprecated aliases of /v1. Every version has its own register function, so that a
+// later version can change request and response shapes while earlier ones are still served.
+// The health probes and metrics are not part of the API and are not versioned.
 func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Use(TracingMiddleware)
+	r.Use(MetricsMiddleware)
+	r.Get("/healthz", h.handleHealthz)
+	r.Get("/readyz", h.handleReadyz)
+	r.Get("/metrics", h.handleMetrics)
+
+	r.Route("/v1", h.registerV1)
+
+	r.Group(func(r chi.Router) {
+		r.Use(DeprecationMiddleware(unversionedDeprecatedAt, unversionedSunset, "/v1"))
+		h.registerV1(r)
+	})
+}
+
+// registerV1 registers the routes of version 1 of the API.
+func (h *Handler) registerV1(r chi.Router) {
 	r.Group(func(r chi.Router) {
-		r.Use(JWTMiddleware)
+		r.Use(APIKeyMiddleware(h.APIKeyService, h.UserService))
+		r.Use(AccountMiddleware(h.UserService))
 
-		r.Post("/upload", h.handleFileUpload)
-		r.Get("/download/{cid}", h.handleFileDownload)
 		r.Get("/checkToken", h.handleCheckToken)
+
+		r.Group(func(r chi.Router) {
+			r.Use(ScopeMiddleware(service.ScopeUpload))
+			r.Post("/upload", h.handleFileUpload)
+			r.Post("/upload/batch", h.handleBatchUpload)
+			r.Post("/files/{fileId}/versions", h.handleUploadVersion)
+			// Creating folders is part of uploading a directory
+			r.Post("/folders", h.handleCreateFolder)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(ScopeMiddleware(service.ScopeDownload))
+			r.Get("/download/{cid}", h.handleFileDownload)
+			r.Post("/files/archive", h.handleDownloadArchive)
+			r.Get("/files/{fileId}/versions/{version}/download", h.handleDownloadVersion)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(ScopeMiddleware(service.ScopeReadMetadata))
+			r.Get("/folders/{id}/contents", h.handleListFolder)
+			r.Get("/files/search", h.handleSearchFiles)
+			r.Get("/files/{fileId}/versions", h.handleListVersions)
+			r.Get("/files/{fileId}/shares", h.handleListShares)
+			r.Get("/shared", h.handleListSharedWithMe)
+			r.Get("/me/usage", h.handleGetUsage)
+		})
+
+		// Everything else requires signing in with the wallet
+		r.Group(func(r chi.Router) {
+			r.Use(SessionMiddleware)
+			r.Patch("/folders/{id}", h.handleRenameFolder)
+			r.Post("/folders/{id}/move", h.handleMoveFolder)
+			r.Delete("/folders/{id}", h.handleDeleteFolder)
+			r.Post("/files/{cid}/move", h.handleMoveFile)
+			r.Patch("/files/{cid}", h.handleUpdateFileDetails)
+
+			r.Post("/files/{fileId}/versions/{version}/restore", h.handleRestoreVersion)
+			r.Put("/files/{fileId}/retention", h.handleSetRetention)
+			r.Delete("/files/{fileId}", h.handleDeleteFile)
+			r.Post("/files/{fileId}/transfer", h.handleTransferOwnership)
+			r.Post("/files/{fileId}/shares", h.handleGrantShare)
+			r.Delete("/files/{fileId}/shares/{grantee}", h.handleRevokeShare)
+
+			r.Post("/api-keys", h.handleCreateAPIKey)
+			r.Get("/api-keys", h.handleListAPIKeys)
+			r.Delete("/api-keys/{id}", h.handleRevokeAPIKey)
+
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(RoleMiddleware(model.RoleAuditor, model.RoleAdmin))
+				r.Get("/users", h.handleAdminListUsers)
+				r.Get("/users/{address}", h.handleAdminGetUser)
+				r.Get("/users/{address}/files", h.handleAdminListUserFiles)
+				r.Get("/stats", h.handleAdminStats)
+				r.Get("/chain/reconciliation", h.handleChainReconciliation)
+
+				r.Group(func(r chi.Router) {
+					r.Use(RoleMiddleware(model.RoleAdmin))
+					r.Put("/users/{address}/quota", h.handleSetQuota)
+					r.Put("/users/{address}/role", h.handleAdminSetRole)
+					r.Post("/users/{address}/disable", h.handleAdminDisableUser)
+					r.Post("/users/{address}/enable", h.handleAdminEnableUser)
+					r.Delete("/files/{fileId}", h.handleAdminDeleteFile)
+				})
+			})
+		})
 	})
+	r.Get("/openapi.json", h.handleOpenAPI)
 	r.Post("/verifySignature", h.handleVerifySignature)
 	r.Post("/generateNonce", h.handleGenerateNonce)
+	r.Group(func(r chi.Router) {
+		r.Use(OptionalAuthMiddleware(h.APIKeyService, h.UserService))
+		r.Use(ScopeMiddleware(service.ScopeReadMetadata))
+		r.Post("/verify", h.handleVerifyFile)
+	})
+	r.Get("/receipts/signer", h.handleReceiptSigner)
+	r.Post("/receipts/verify", h.handleVerifyReceipt)
 }
 func (h *Handler) handleCheckToken(w http.ResponseWriter, r *http.Request) {
 	RespondWithJSON(w, http.StatusOK, map[string]string{"message": "This is a test message for authenticated users."})
 }
 
-func (h *Handler) handleFileUpload(w http.ResponseWriter, r *http.Request) {
+// parseMultipartForm parses an upload form, keeping up to MaxMultipartFormSize in memory.
+func parseMultipartForm(r *http.Request) error {
 	if err := r.ParseMultipartForm(service.MaxMultipartFormSize); err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Failed to parse form data")
+		return formError(err)
+	}
+	return nil
+}
+
+// formError reports a failure to read a multipart form as ErrFormTooLarge when the form exceeds
+// its size limit, and as ErrInvalidForm otherwise.
+func formError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.Is(err, multipart.ErrMessageTooLarge) || errors.As(err, &maxBytesErr) {
+		return service.ErrFormTooLarge
+	}
+	return fmt.Errorf("%w: %v", service.ErrInvalidForm, err)
+}
+
+func (h *Handler) handleFileUpload(w http.ResponseWriter, r *http.Request) {
+	if err := parseMultipartForm(r); err != nil {
+		RespondWithProblem(w, r, err)
 		return
 	}
 
-	file, _, err := r.FormFile("file")
+	file, fileHeader, err := r.FormFile("file")
 	if err != nil {
 		RespondWithError(w, http.StatusBadRequest, "Failed to get file from form data")
 		return
 	}
 	defer file.Close()
 
-	ethereumAddress := r.Header.Get("EthereumAddress")
-	cid, originalFileHash, err := h.FileService.UploadFile(file, ethereumAddress)
+	folderID, err := parseOptionalID(r.FormValue("folderId"))
 	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		RespondWithError(w, http.StatusBadRequest, "Invalid folder ID")
 		return
 	}
 
-	response := map[string]string{
-		"cid":              cid,
-		"originalFileHash": originalFileHash,
+	ethereumAddress := r.Header.Get("EthereumAddress")
+	opts := service.UploadOptions{
+		FileName:    fileHeader.Filename,
+		FolderID:    folderID,
+		Description: r.FormValue("description"),
+		Tags:        parseTags(r.Form["tags"]),
+		MimeType:    fileHeader.Header.Get("Content-Type"),
+	}
+	fileMetadata, originalFileHash, err := h.FileService.UploadFile(r.Context(), file, ethereumAddress, opts)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
 	}
 
-	RespondWithJSON(w, http.StatusOK, response)
+	RespondWithJSON(w, http.StatusOK, h.uploadResponse(fileMetadata, originalFileHash))
 }
 
 func (h *Handler) handleFileDownload(w http.ResponseWriter, r *http.Request) {
@@ -73,13 +205,13 @@ func (h *Handler) handleFileDownload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reader, hash, err := h.DownloadService.DownloadFile(cid) // Capture the hash here
+	download, err := h.DownloadService.DownloadFile(r.Context(), r.Header.Get("EthereumAddress"), cid)
 	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, err.Error())
+		RespondWithProblem(w, r, err)
 		return
 	}
 
-	SendFile(w, reader, cid, hash)
+	sendDownload(w, r, download)
 }
 
 func (h *Handler) handleVerifySignature(w http.ResponseWriter, r *http.Request) {
@@ -94,20 +226,21 @@ func (h *Handler) handleVerifySignature(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	log.Printf("Received address: %s, signature: %s, message: %s\n", req.EthereumAddress, req.Signature, req.Message)
-
 	// Verify the signature against the message instead of the nonce
-	recoveredAddress, err := h.UserService.VerifySignature(req.Message, req.Signature)
-	if err != nil || !strings.EqualFold(recoveredAddress, req.EthereumAddress) {
-		RespondWithError(w, http.StatusUnauthorized, "Invalid signature")
+	if err := h.UserService.VerifyWalletSignature(r.Context(), req.EthereumAddress, req.Message, req.Signature); err != nil {
+		respondAuthProblem(w, r, metrics.AuthSignature, err)
 		return
 	}
 
-	token, err := h.UserService.GenerateJWT(req.EthereumAddress)
-	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Failed to generate JWT token")
+	token, err := h.UserService.GenerateJWT(r.Context(), req.EthereumAddress)
+	if errors.Is(err, service.ErrUserNotFound) {
+		writeAuthProblem(w, r, metrics.AuthSignature, "nonce_required", "Request a nonce before signing in")
+		return
+	} else if err != nil {
+		respondAuthProblem(w, r, metrics.AuthSignature, err)
 		return
 	}
+	metrics.AuthSucceeded(metrics.AuthSignature)
 
 	RespondWithJSON(w, http.StatusOK, map[string]string{"token": token})
 }
@@ -121,7 +254,7 @@ func (h *Handler) handleGenerateNonce(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	nonce, err := h.UserService.GenerateNonceForUser(req.EthereumAddress)
+	nonce, err := h.UserService.GenerateNonceForUser(r.Context(), req.EthereumAddress)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Failed to generate nonce")
 		return
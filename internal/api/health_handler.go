@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+)
+
+// handleHealthz reports that the process is alive. It does not check any dependency, so that
+// an outage of one does not get the server restarted.
+func (h *Handler) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	RespondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReadyz reports whether the server can serve requests, with the status of each
+// dependency. It responds 503 while a critical dependency is failing.
+func (h *Handler) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if h.HealthService == nil {
+		RespondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		return
+	}
+
+	report := h.HealthService.Readiness()
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	RespondWithJSON(w, status, report)
+}
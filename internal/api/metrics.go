@@ -0,0 +1,65 @@
+package api
+
+import (
+	"SafeTransfer/internal/metrics"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsMiddleware counts and times requests by route pattern, such as /v1/download/{cid},
+// rather than by path, so that the number of series stays bounded. Requests that match no
+// route are counted as "unmatched", and methods outside the standard set as "other".
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if routeContext := chi.RouteContext(r.Context()); routeContext != nil && routeContext.RoutePattern() != "" {
+			route = routeContext.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := []string{route, methodLabel(r.Method), strconv.Itoa(status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.Since(metrics.HTTPRequestDuration.WithLabelValues(labels...), start)
+	})
+}
+
+// methodLabel returns the label for a request method. Clients can send any token as the method,
+// so only the standard methods get their own series.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
+
+// metricsHandler serves the metrics in the Prometheus exposition format.
+var metricsHandler = promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
+
+// handleMetrics serves the metrics to anyone presenting MetricsToken as a bearer token, or to
+// anyone at all when no token is configured.
+func (h *Handler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.MetricsToken != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.MetricsToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			writeProblem(w, r, http.StatusUnauthorized, "invalid_token", "A valid metrics token is required")
+			return
+		}
+	}
+	metricsHandler.ServeHTTP(w, r)
+}
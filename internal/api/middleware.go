@@ -1,52 +1,214 @@
 package api
 
 import (
+	"SafeTransfer/internal/metrics"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/service"
+	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 )
 
-// JWTMiddleware is a middleware that checks for a valid JWT token in the request.
-func JWTMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header is missing", http.StatusUnauthorized)
-			return
-		}
+// JWTMiddleware is a middleware that checks for a valid JWT token in the request, signed with the
+// secret key of userService.
+func JWTMiddleware(userService *service.UserService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				writeAuthProblem(w, r, metrics.AuthJWT, "missing_token", "Authorization header is missing")
+				return
+			}
 
-		// Extract the token from the Authorization header
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+			// Extract the token from the Authorization header
+			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Read the JWT secret key from the mounted secret file
-		jwtSecretKey, err := os.ReadFile("/run/secrets/jwt_secret")
-		if err != nil {
-			http.Error(w, "Failed to read JWT secret key", http.StatusInternalServerError)
-			return
-		}
+			// Parse and verify the token
+			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+				// Don't forget to validate the alg is what you expect
+				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+				}
+				return []byte(userService.JWTSecretKey), nil
+			})
 
-		// Parse and verify the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Don't forget to validate the alg is what you expect
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			if err != nil {
+				writeAuthProblem(w, r, metrics.AuthJWT, "invalid_token", "Invalid token")
+				return
+			}
+
+			if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+				// Token is valid, you can now use the claims. Tokens issued before addresses were
+				// canonicalized carry the address as the client sent it.
+				ethereumAddress, _ := claims["ethereumAddress"].(string)
+				r.Header.Set("EthereumAddress", model.CanonicalAddress(ethereumAddress))
+				// Tokens issued before roles were introduced belong to ordinary users
+				role, _ := claims["role"].(string)
+				if !model.ValidRole(role) {
+					role = model.RoleUser
+				}
+				r.Header.Set("Role", role)
+				r.Header.Del("ApiKeyScopes")
+				next.ServeHTTP(w, r)
+			} else {
+				writeAuthProblem(w, r, metrics.AuthJWT, "invalid_token", "Invalid token")
 			}
-			return []byte(jwtSecretKey), nil
 		})
+	}
+}
 
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
+// APIKeyMiddleware authenticates requests sent with an `Authorization: ApiKey <key>` header and
+// passes all others on to JWTMiddleware. Requests made with an API key carry the key's scopes
+// in the ApiKeyScopes header and always have the user role, whatever the owner's role.
+func APIKeyMiddleware(apiKeyService *service.APIKeyService, userService *service.UserService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		jwtHandler := JWTMiddleware(userService)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "ApiKey ")
+			if !ok {
+				jwtHandler.ServeHTTP(w, r)
+				return
+			}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Token is valid, you can now use the claims
-			r.Header.Set("EthereumAddress", claims["ethereumAddress"].(string))
+			apiKey, err := apiKeyService.Authenticate(r.Context(), strings.TrimSpace(key))
+			if err != nil {
+				respondAuthProblem(w, r, metrics.AuthAPIKey, err)
+				return
+			}
+
+			r.Header.Set("EthereumAddress", apiKey.EthereumAddress)
+			r.Header.Set("Role", model.RoleUser)
+			r.Header.Set("ApiKeyScopes", strings.Join(apiKey.Scopes, ","))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// OptionalAuthMiddleware authenticates requests that carry an Authorization header like
+// APIKeyMiddleware and AccountMiddleware, and passes the others on anonymously, without any
+// EthereumAddress, Role or ApiKeyScopes headers the client sent.
+func OptionalAuthMiddleware(apiKeyService *service.APIKeyService, userService *service.UserService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := APIKeyMiddleware(apiKeyService, userService)(AccountMiddleware(userService)(next))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" {
+				authenticated.ServeHTTP(w, r)
+				return
+			}
+			r.Header.Del("EthereumAddress")
+			r.Header.Del("Role")
+			r.Header.Del("ApiKeyScopes")
 			next.ServeHTTP(w, r)
-		} else {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		})
+	}
+}
+
+// writeAuthProblem rejects a request that failed to authenticate with method, counting the
+// failure by the problem code.
+func writeAuthProblem(w http.ResponseWriter, r *http.Request, method, code, detail string) {
+	metrics.AuthFailed(method, code)
+	writeProblem(w, r, http.StatusUnauthorized, code, detail)
+}
+
+// respondAuthProblem reports an error returned by a service while authenticating with method,
+// counting the failure by the problem code.
+func respondAuthProblem(w http.ResponseWriter, r *http.Request, method string, err error) {
+	_, code, _ := describeError(err)
+	metrics.AuthFailed(method, code)
+	RespondWithProblem(w, r, err)
+}
+
+// ScopeMiddleware only lets through requests made with an API key if the key has scope.
+// Requests authenticated with a JWT have every scope.
+func ScopeMiddleware(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes := r.Header.Get("ApiKeyScopes")
+			if scopes == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, granted := range strings.Split(scopes, ",") {
+				if granted == scope {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			writeProblem(w, r, http.StatusForbidden, "insufficient_scope", fmt.Sprintf("API key lacks the %s scope", scope))
+		})
+	}
+}
+
+// SessionMiddleware rejects requests made with an API key, for endpoints that require the user
+// to have signed in with their wallet.
+func SessionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("ApiKeyScopes") != "" {
+			writeProblem(w, r, http.StatusForbidden, "session_required", "API keys cannot be used for this endpoint")
+			return
 		}
+		next.ServeHTTP(w, r)
 	})
 }
+
+// AccountMiddleware rejects authenticated requests for accounts that have been disabled, and
+// tokens issued before the user's role changed. Requests it lets through are counted as
+// successful authentications.
+func AccountMiddleware(userService *service.UserService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method := metrics.AuthJWT
+			if r.Header.Get("ApiKeyScopes") != "" {
+				method = metrics.AuthAPIKey
+			}
+
+			user, err := userService.GetActiveUser(r.Context(), r.Header.Get("EthereumAddress"))
+			switch {
+			case errors.Is(err, service.ErrUserNotFound):
+				writeAuthProblem(w, r, method, "invalid_token", "Invalid token")
+			case err != nil:
+				respondAuthProblem(w, r, method, err)
+			case method == metrics.AuthJWT && user.Role != r.Header.Get("Role"):
+				writeAuthProblem(w, r, method, "role_changed", "Role has changed, sign in again")
+			default:
+				metrics.AuthSucceeded(method)
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// RoleMiddleware only lets through requests authenticated by JWTMiddleware with one of the given roles.
+func RoleMiddleware(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := r.Header.Get("Role")
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			writeProblem(w, r, http.StatusForbidden, "insufficient_role", "Insufficient role")
+		})
+	}
+}
+
+// DeprecationMiddleware marks responses as deprecated since deprecatedAt and to be removed at
+// sunset, with the Deprecation (RFC 9745) and Sunset (RFC 8594) headers. The successor of a
+// path is the same path under successorPrefix.
+func DeprecationMiddleware(deprecatedAt, sunset time.Time, successorPrefix string) func(http.Handler) http.Handler {
+	deprecation := fmt.Sprintf("@%d", deprecatedAt.Unix())
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Set("Sunset", sunsetDate)
+			w.Header().Add("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", successorPrefix, r.URL.Path))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPISpec is the OpenAPI 3.1 description of the core endpoints of version 1, served at /v1/openapi.json.
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+func (h *Handler) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPISpec)
+}
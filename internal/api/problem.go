@@ -0,0 +1,115 @@
+package api
+
+import (
+	"SafeTransfer/internal/service"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// problemTypePrefix is prepended to a problem's code to form its type URI.
+const problemTypePrefix = "urn:safetransfer:problem:"
+
+// Problem is an RFC 7807 problem details object. Code is a stable, machine-readable identifier
+// of the problem, such as "file_not_found", and Type is the URI form of it.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+}
+
+// RespondWithProblem reports an error returned by a service. Errors of a known kind are mapped
+// to their status code; anything else is logged and reported as an internal error, without
+// exposing its message.
+func RespondWithProblem(w http.ResponseWriter, r *http.Request, err error) {
+	var chainErr *service.ChainVerificationError
+	if errors.As(err, &chainErr) {
+		setChainHeaders(w, chainErr.Verification)
+	}
+
+	status, code, detail := describeError(err)
+	if status >= http.StatusInternalServerError {
+		// Failures of dependencies are logged in full, but only described in general terms
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+	writeProblem(w, r, status, code, detail)
+}
+
+// describeError returns the status code, problem code and detail that an error is reported with.
+func describeError(err error) (status int, code, detail string) {
+	if status, ok := quotaErrorStatus(err); ok {
+		return status, service.ErrQuotaExceeded.Code, err.Error()
+	}
+
+	var chainErr *service.ChainVerificationError
+	if errors.As(err, &chainErr) {
+		if chainErr.Verification.Status == service.ChainUnavailable {
+			return http.StatusBadGateway, service.ErrChainVerificationFailed.Code, err.Error()
+		}
+		return http.StatusConflict, service.ErrChainVerificationFailed.Code, err.Error()
+	}
+
+	var serviceErr *service.Error
+	if !errors.As(err, &serviceErr) {
+		return http.StatusInternalServerError, statusCode(http.StatusInternalServerError), "an unexpected error occurred"
+	}
+
+	status = errorStatus(serviceErr.Kind)
+	if status >= http.StatusInternalServerError {
+		return status, serviceErr.Code, serviceErr.Message
+	}
+	return status, serviceErr.Code, err.Error()
+}
+
+// errorStatus maps a kind of service error to an HTTP status code.
+func errorStatus(kind error) int {
+	switch kind {
+	case service.ErrNotFound:
+		return http.StatusNotFound
+	case service.ErrInvalidInput:
+		return http.StatusBadRequest
+	case service.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case service.ErrForbidden:
+		return http.StatusForbidden
+	case service.ErrConflict, service.ErrIntegrity:
+		return http.StatusConflict
+	case service.ErrTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case service.ErrUnavailable:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// statusCode derives a generic problem code from a status code, such as "not_found".
+func statusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// writeProblem sends a problem details response. r may be nil, in which case the problem has
+// no instance.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	problem := Problem{
+		Type:   problemTypePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+	if r != nil {
+		problem.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		log.Printf("Failed to encode problem response: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/pkg/receipt"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// issueReceipt signs a receipt for an upload, returning nil when receipts are disabled. The
+// upload has already succeeded, so a failure is logged rather than reported.
+func (h *Handler) issueReceipt(fileMetadata *model.File, originalFileHash string) *receipt.SignedReceipt {
+	if h.ReceiptService == nil {
+		return nil
+	}
+	signed, err := h.ReceiptService.Issue(fileMetadata, originalFileHash)
+	if err != nil {
+		log.Printf("Failed to issue receipt for %s: %v", fileMetadata.CID, err)
+		return nil
+	}
+	return signed
+}
+
+// handleReceiptSigner returns the address receipts are signed by, for verifying them offline.
+func (h *Handler) handleReceiptSigner(w http.ResponseWriter, r *http.Request) {
+	if h.ReceiptService == nil {
+		RespondWithError(w, http.StatusServiceUnavailable, "Receipts are not enabled")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]string{"signer": h.ReceiptService.Signer().Hex()})
+}
+
+// handleVerifyReceipt checks that a signed receipt was issued by this server.
+func (h *Handler) handleVerifyReceipt(w http.ResponseWriter, r *http.Request) {
+	if h.ReceiptService == nil {
+		RespondWithError(w, http.StatusServiceUnavailable, "Receipts are not enabled")
+		return
+	}
+
+	var signed receipt.SignedReceipt
+	if err := json.NewDecoder(r.Body).Decode(&signed); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	response := map[string]interface{}{
+		"valid":  true,
+		"signer": h.ReceiptService.Signer().Hex(),
+	}
+	if err := h.ReceiptService.Verify(&signed); err != nil {
+		response["valid"] = false
+		response["error"] = err.Error()
+	}
+	RespondWithJSON(w, http.StatusOK, response)
+}
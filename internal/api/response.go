@@ -4,17 +4,19 @@ package api
 
 import (
 	"encoding/json"
-	"io"
 	"net/http"
 )
 
-// RespondWithError sends an error response in JSON format.
+// RespondWithError sends a problem details response with a generic code for the status, for
+// errors detected by the handlers themselves. Errors returned by services go through
+// RespondWithProblem instead.
 func RespondWithError(w http.ResponseWriter, code int, message string) {
-	RespondWithJSON(w, code, map[string]string{"error": message})
+	writeProblem(w, nil, code, statusCode(code), message)
 }
 
 // RespondWithJSON sends a JSON response with the provided data.
 func RespondWithJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	err := json.NewEncoder(w).Encode(data)
 	if err != nil {
@@ -22,15 +24,3 @@ func RespondWithJSON(w http.ResponseWriter, status int, data interface{}) {
 		return
 	}
 }
-
-// SendFile sends the file data in the response, including the SHA-256 hash of the file content in the headers.
-func SendFile(w http.ResponseWriter, reader io.Reader, filename string, hash string) {
-	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("X-File-Hash", hash) // Include the SHA-256 hash in the response headers
-	_, err := io.Copy(w, reader)
-	if err != nil {
-		http.Error(w, "Failed to send file", http.StatusInternalServerError)
-		return
-	}
-}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/service"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type shareResponse struct {
+	FileID    uint      `json:"fileId"`
+	Grantee   string    `json:"grantee"`
+	GrantedBy string    `json:"grantedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func newShareResponse(share *model.Share) shareResponse {
+	return shareResponse{
+		FileID:    share.LogicalFileID,
+		Grantee:   share.GranteeAddress,
+		GrantedBy: share.GrantedBy,
+		CreatedAt: share.CreatedAt,
+	}
+}
+
+// handleGrantShare shares a file with another user. It requires an EIP-712 ShareGrant signature.
+func (h *Handler) handleGrantShare(w http.ResponseWriter, r *http.Request) {
+	fileID, err := parseID(chi.URLParam(r, "fileId"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	var req struct {
+		Grantee string `json:"grantee"`
+		service.ActionAuthorization
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	ethereumAddress := r.Header.Get("EthereumAddress")
+	params := map[string]interface{}{"fileId": new(big.Int).SetUint64(uint64(fileID)), "grantee": req.Grantee}
+	if err := h.UserService.AuthorizeAction(r.Context(), ethereumAddress, service.ActionShareGrant, params, req.ActionAuthorization); err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	share, err := h.ShareService.GrantShare(r.Context(), ethereumAddress, fileID, req.Grantee)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, newShareResponse(share))
+}
+
+func (h *Handler) handleListShares(w http.ResponseWriter, r *http.Request) {
+	fileID, err := parseID(chi.URLParam(r, "fileId"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	shares, err := h.ShareService.ListShares(r.Context(), r.Header.Get("EthereumAddress"), fileID)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	response := make([]shareResponse, 0, len(shares))
+	for i := range shares {
+		response = append(response, newShareResponse(&shares[i]))
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{"fileId": fileID, "shares": response})
+}
+
+func (h *Handler) handleRevokeShare(w http.ResponseWriter, r *http.Request) {
+	fileID, err := parseID(chi.URLParam(r, "fileId"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	if err := h.ShareService.RevokeShare(r.Context(), r.Header.Get("EthereumAddress"), fileID, chi.URLParam(r, "grantee")); err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListSharedWithMe lists the latest versions of the files other users have shared with the caller.
+func (h *Handler) handleListSharedWithMe(w http.ResponseWriter, r *http.Request) {
+	page, pageSize, err := parsePagination(r)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	files, total, err := h.ShareService.ListSharedWithMe(r.Context(), r.Header.Get("EthereumAddress"), page, pageSize)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	response := fileSearchResponse{
+		Files:    make([]fileResponse, 0, len(files)),
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	}
+	for i := range files {
+		response.Files = append(response.Files, newFileResponse(&files[i]))
+	}
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// handleDeleteFile deletes a file with all of its versions. It requires an EIP-712 DeleteFile signature.
+func (h *Handler) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
+	fileID, err := parseID(chi.URLParam(r, "fileId"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	var req service.ActionAuthorization
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	ethereumAddress := r.Header.Get("EthereumAddress")
+	params := map[string]interface{}{"fileId": new(big.Int).SetUint64(uint64(fileID))}
+	if err := h.UserService.AuthorizeAction(r.Context(), ethereumAddress, service.ActionDeleteFile, params, req); err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	if err := h.FileService.DeleteFile(r.Context(), ethereumAddress, fileID); err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTransferOwnership hands a file over to another user. It requires an EIP-712
+// TransferOwnership signature.
+func (h *Handler) handleTransferOwnership(w http.ResponseWriter, r *http.Request) {
+	fileID, err := parseID(chi.URLParam(r, "fileId"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	var req struct {
+		NewOwner string `json:"newOwner"`
+		service.ActionAuthorization
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	ethereumAddress := r.Header.Get("EthereumAddress")
+	params := map[string]interface{}{"fileId": new(big.Int).SetUint64(uint64(fileID)), "newOwner": req.NewOwner}
+	if err := h.UserService.AuthorizeAction(r.Context(), ethereumAddress, service.ActionTransferOwnership, params, req.ActionAuthorization); err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	logicalFile, err := h.FileService.TransferOwnership(r.Context(), ethereumAddress, fileID, req.NewOwner)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{"fileId": logicalFile.ID, "owner": logicalFile.EthereumAddress})
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type usageResponse struct {
+	EthereumAddress string `json:"ethereumAddress"`
+	BytesStored     int64  `json:"bytesStored"`
+	FileCount       int64  `json:"fileCount"`
+	QuotaBytes      int64  `json:"quotaBytes"`
+	QuotaFiles      int64  `json:"quotaFiles"`
+	Overridden      bool   `json:"overridden"`
+}
+
+func newUsageResponse(ethereumAddress string, usage *service.Usage) usageResponse {
+	return usageResponse{
+		EthereumAddress: ethereumAddress,
+		BytesStored:     usage.BytesStored,
+		FileCount:       usage.FileCount,
+		QuotaBytes:      usage.QuotaBytes,
+		QuotaFiles:      usage.QuotaFiles,
+		Overridden:      usage.Overridden,
+	}
+}
+
+func (h *Handler) handleGetUsage(w http.ResponseWriter, r *http.Request) {
+	ethereumAddress := r.Header.Get("EthereumAddress")
+	usage, err := h.QuotaService.GetUsage(r.Context(), ethereumAddress)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, newUsageResponse(ethereumAddress, usage))
+}
+
+// handleSetQuota overrides a user's quotas. Omitted or null limits revert to the server defaults.
+func (h *Handler) handleSetQuota(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		QuotaBytes *int64 `json:"quotaBytes"`
+		QuotaFiles *int64 `json:"quotaFiles"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	ethereumAddress := model.CanonicalAddress(chi.URLParam(r, "address"))
+	usage, err := h.QuotaService.SetQuota(r.Context(), ethereumAddress, req.QuotaBytes, req.QuotaFiles)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, newUsageResponse(ethereumAddress, usage))
+}
+
+// quotaErrorStatus returns the status for uploads rejected by a quota: 413 when the file is
+// larger than the whole quota and 507 when it would fit once other files are removed. It
+// reports whether err was a quota error.
+func quotaErrorStatus(err error) (int, bool) {
+	var quotaErr *service.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		return 0, false
+	}
+	if quotaErr.TooLarge() {
+		return http.StatusRequestEntityTooLarge, true
+	}
+	return http.StatusInsufficientStorage, true
+}
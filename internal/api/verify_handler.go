@@ -0,0 +1,129 @@
+package api
+
+import (
+	"SafeTransfer/internal/service"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// maxVerifyFieldSize bounds the form fields read alongside a file in /verify.
+const maxVerifyFieldSize = 1024
+
+type storedMatchResponse struct {
+	CID        string    `json:"cid"`
+	Owner      string    `json:"owner"`
+	UploadedAt time.Time `json:"uploadedAt"`
+}
+
+type registryMatchResponse struct {
+	Status       string     `json:"status"`
+	CID          string     `json:"cid,omitempty"`
+	FileHash     string     `json:"fileHash,omitempty"`
+	Owner        string     `json:"owner,omitempty"`
+	RegisteredAt *time.Time `json:"registeredAt,omitempty"`
+}
+
+type verificationResponse struct {
+	Verified bool                   `json:"verified"`
+	SHA256   string                 `json:"sha256"`
+	CID      string                 `json:"cid,omitempty"`
+	Stored   []storedMatchResponse  `json:"stored"`
+	Registry *registryMatchResponse `json:"registry,omitempty"`
+}
+
+// handleVerifyFile checks a file against the registry without authentication, and against the
+// stored files the caller can read when signed in. It accepts either a multipart form with the
+// file and an optional cid, or a JSON body with the file's sha256 and an optional cid.
+func (h *Handler) handleVerifyFile(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SHA256 string `json:"sha256"`
+		CID    string `json:"cid"`
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		var err error
+		req.SHA256, req.CID, err = hashVerifyForm(w, r)
+		if err != nil {
+			RespondWithProblem(w, r, err)
+			return
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	result, err := h.VerifyService.Verify(r.Context(), r.Header.Get("EthereumAddress"), req.SHA256, req.CID)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	response := verificationResponse{
+		Verified: result.Verified(),
+		SHA256:   result.SHA256,
+		CID:      result.CID,
+		Stored:   make([]storedMatchResponse, 0, len(result.Stored)),
+	}
+	for _, match := range result.Stored {
+		response.Stored = append(response.Stored, storedMatchResponse(match))
+	}
+	if match := result.Registry; match != nil {
+		response.Registry = &registryMatchResponse{
+			Status:   match.Status,
+			CID:      match.CID,
+			FileHash: match.FileHash,
+			Owner:    match.Owner,
+		}
+		if !match.RegisteredAt.IsZero() {
+			response.Registry.RegisteredAt = &match.RegisteredAt
+		}
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// hashVerifyForm streams a multipart form, hashing the file part without storing it, and
+// returns its SHA-256 along with the cid field.
+func hashVerifyForm(w http.ResponseWriter, r *http.Request) (string, string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxMultipartFormSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return "", "", formError(err)
+	}
+
+	var sha256Hash, cid string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", "", formError(err)
+		}
+
+		switch part.FormName() {
+		case "file":
+			hash := sha256.New()
+			if _, err := io.Copy(hash, part); err != nil {
+				return "", "", formError(err)
+			}
+			sha256Hash = fmt.Sprintf("%x", hash.Sum(nil))
+		case "cid":
+			value, err := io.ReadAll(io.LimitReader(part, maxVerifyFieldSize))
+			if err != nil {
+				return "", "", formError(err)
+			}
+			cid = string(value)
+		}
+	}
+
+	if sha256Hash == "" {
+		return "", "", fmt.Errorf("%w: file is required", service.ErrInvalidForm)
+	}
+	return sha256Hash, cid, nil
+}
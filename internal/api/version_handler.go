@@ -0,0 +1,170 @@
+package api
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type versionListResponse struct {
+	FileID        uint           `json:"fileId"`
+	LatestVersion int            `json:"latestVersion"`
+	MaxVersions   int            `json:"maxVersions"`
+	Versions      []fileResponse `json:"versions"`
+}
+
+func (h *Handler) handleUploadVersion(w http.ResponseWriter, r *http.Request) {
+	fileID, err := parseID(chi.URLParam(r, "fileId"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	if err := parseMultipartForm(r); err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Failed to get file from form data")
+		return
+	}
+	defer file.Close()
+
+	opts := service.UploadOptions{
+		FileName:    fileHeader.Filename,
+		Description: r.FormValue("description"),
+		Tags:        parseTags(r.Form["tags"]),
+		MimeType:    fileHeader.Header.Get("Content-Type"),
+	}
+	fileMetadata, originalFileHash, err := h.VersionService.UploadVersion(r.Context(), r.Header.Get("EthereumAddress"), fileID, file, opts)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, h.uploadResponse(fileMetadata, originalFileHash))
+}
+
+func (h *Handler) handleListVersions(w http.ResponseWriter, r *http.Request) {
+	fileID, err := parseID(chi.URLParam(r, "fileId"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	logicalFile, versions, err := h.VersionService.ListVersions(r.Context(), r.Header.Get("EthereumAddress"), fileID)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	response := versionListResponse{
+		FileID:        logicalFile.ID,
+		LatestVersion: logicalFile.LatestVersion,
+		MaxVersions:   h.VersionService.RetainedVersions(logicalFile),
+		Versions:      make([]fileResponse, 0, len(versions)),
+	}
+	for i := range versions {
+		response.Versions = append(response.Versions, newFileResponse(&versions[i]))
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) handleDownloadVersion(w http.ResponseWriter, r *http.Request) {
+	fileID, version, ok := parseVersionParams(w, r)
+	if !ok {
+		return
+	}
+
+	download, err := h.VersionService.DownloadVersion(r.Context(), r.Header.Get("EthereumAddress"), fileID, version)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	sendDownload(w, r, download)
+}
+
+func (h *Handler) handleRestoreVersion(w http.ResponseWriter, r *http.Request) {
+	fileID, version, ok := parseVersionParams(w, r)
+	if !ok {
+		return
+	}
+
+	fileMetadata, originalFileHash, err := h.VersionService.RestoreVersion(r.Context(), r.Header.Get("EthereumAddress"), fileID, version)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, h.uploadResponse(fileMetadata, originalFileHash))
+}
+
+func (h *Handler) handleSetRetention(w http.ResponseWriter, r *http.Request) {
+	fileID, err := parseID(chi.URLParam(r, "fileId"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid file ID")
+		return
+	}
+
+	var req struct {
+		MaxVersions int `json:"maxVersions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	logicalFile, err := h.VersionService.SetRetention(r.Context(), r.Header.Get("EthereumAddress"), fileID, req.MaxVersions)
+	if err != nil {
+		RespondWithProblem(w, r, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"fileId":      logicalFile.ID,
+		"maxVersions": h.VersionService.RetainedVersions(logicalFile),
+	})
+}
+
+// uploadResponse describes a stored file version to the client that uploaded it, including a
+// signed receipt when receipts are enabled.
+func (h *Handler) uploadResponse(fileMetadata *model.File, originalFileHash string) map[string]interface{} {
+	response := map[string]interface{}{
+		"cid":              fileMetadata.CID,
+		"originalFileHash": originalFileHash,
+		"version":          fileMetadata.Version,
+	}
+	if fileMetadata.LogicalFileID != nil {
+		response["fileId"] = *fileMetadata.LogicalFileID
+	}
+	if fileMetadata.ChainStatus != "" {
+		response["chainStatus"] = fileMetadata.ChainStatus
+	}
+	if signed := h.issueReceipt(fileMetadata, originalFileHash); signed != nil {
+		response["receipt"] = signed
+	}
+	return response
+}
+
+// parseVersionParams reads the fileId and version path parameters, responding with 400 if either is invalid.
+func parseVersionParams(w http.ResponseWriter, r *http.Request) (uint, int, bool) {
+	fileID, err := parseID(chi.URLParam(r, "fileId"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid file ID")
+		return 0, 0, false
+	}
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil || version < 1 {
+		RespondWithError(w, http.StatusBadRequest, "Invalid version")
+		return 0, 0, false
+	}
+	return fileID, version, true
+}
@@ -0,0 +1,262 @@
+// Package chain talks to the FileRegistry contract defined in FileMetadataStore.sol.
+package chain
+
+//go:generate solc --optimize --abi --bin --overwrite -o . ../../FileMetadataStore.sol
+//go:generate abigen --abi FileRegistry.abi --bin FileRegistry.bin --pkg chain --type FileRegistry --out file_registry.go
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Registration statuses recorded on model.File.
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed"
+	StatusFailed    = "failed"
+	StatusDuplicate = "duplicate" // the hash was registered before, by an upload of the same content
+)
+
+const (
+	defaultPollInterval   = 2 * time.Second
+	defaultReceiptTimeout = 5 * time.Minute
+)
+
+var (
+	ErrTransactionReverted = errors.New("transaction reverted")
+	ErrNotRegistered       = errors.New("file not registered on chain")
+)
+
+// Registration is a file as recorded by the FileRegistry contract.
+type Registration struct {
+	CID       string
+	FileHash  string
+	Owner     common.Address
+	Timestamp time.Time
+}
+
+// Backend is the subset of an Ethereum client used by Registry. Both *ethclient.Client and the
+// client of go-ethereum's simulated backend satisfy it.
+type Backend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// Config holds the contract address, signer and gas settings used to submit registrations.
+type Config struct {
+	ContractAddress common.Address
+	SignerKey       *ecdsa.PrivateKey
+	GasLimit        uint64        // 0 estimates the gas of every transaction
+	GasFeeCap       *big.Int      // nil uses the node's suggestion
+	GasTipCap       *big.Int      // nil uses the node's suggestion
+	PollInterval    time.Duration // how often to poll for receipts
+	ReceiptTimeout  time.Duration // how long to wait for a transaction to be mined
+}
+
+// Registry submits file registrations to the FileRegistry contract and reads them back.
+type Registry struct {
+	backend  Backend
+	contract *FileRegistry
+	config   Config
+	chainID  *big.Int
+
+	// mu serializes submissions so that concurrent uploads are assigned distinct nonces.
+	mu sync.Mutex
+}
+
+// Dial connects to an Ethereum JSON-RPC endpoint.
+func Dial(ctx context.Context, rpcURL string) (*ethclient.Client, error) {
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Ethereum node: %w", err)
+	}
+	return client, nil
+}
+
+// ParseSignerKey decodes a hex-encoded secp256k1 private key, with or without 0x.
+func ParseSignerKey(hexKey string) (*ecdsa.PrivateKey, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(hexKey), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signer key: %w", err)
+	}
+	return key, nil
+}
+
+// NewRegistry binds the FileRegistry contract at config.ContractAddress on backend.
+func NewRegistry(ctx context.Context, backend Backend, config Config) (*Registry, error) {
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.ReceiptTimeout <= 0 {
+		config.ReceiptTimeout = defaultReceiptTimeout
+	}
+
+	contract, err := NewFileRegistry(config.ContractAddress, backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind FileRegistry contract: %w", err)
+	}
+
+	chainID, err := backend.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+
+	return &Registry{
+		backend:  backend,
+		contract: contract,
+		config:   config,
+		chainID:  chainID,
+	}, nil
+}
+
+// Contract returns the underlying contract binding for read-only calls and log filtering.
+func (r *Registry) Contract() *FileRegistry {
+	return r.contract
+}
+
+// Address returns the address of the FileRegistry contract.
+func (r *Registry) Address() common.Address {
+	return r.config.ContractAddress
+}
+
+// ChainID returns the ID of the chain the contract is deployed on.
+func (r *Registry) ChainID() *big.Int {
+	return new(big.Int).Set(r.chainID)
+}
+
+// BlockNumber returns the number of the latest block.
+func (r *Registry) BlockNumber(ctx context.Context) (uint64, error) {
+	header, err := r.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// Signer returns the address that submits registrations, which the contract records as owner.
+func (r *Registry) Signer() common.Address {
+	if r.config.SignerKey == nil {
+		return common.Address{}
+	}
+	return crypto.PubkeyToAddress(r.config.SignerKey.PublicKey)
+}
+
+// RegisterFile submits a registerFile transaction and returns it without waiting for it to be mined.
+func (r *Registry) RegisterFile(ctx context.Context, cid, fileHash string) (*types.Transaction, error) {
+	if r.config.SignerKey == nil {
+		return nil, errors.New("no signer key configured")
+	}
+
+	opts, err := bind.NewKeyedTransactorWithChainID(r.config.SignerKey, r.chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transactor: %w", err)
+	}
+	opts.Context = ctx
+	opts.GasLimit = r.config.GasLimit
+	opts.GasFeeCap = r.config.GasFeeCap
+	opts.GasTipCap = r.config.GasTipCap
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.contract.RegisterFile(opts, cid, fileHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit registerFile transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// WaitForReceipt polls for the receipt of a transaction until it is mined, ctx is done or the
+// configured timeout expires. A mined but reverted transaction returns its receipt along with
+// ErrTransactionReverted.
+func (r *Registry) WaitForReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.config.ReceiptTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		receipt, err := r.backend.TransactionReceipt(ctx, txHash)
+		if err == nil {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return receipt, ErrTransactionReverted
+			}
+			return receipt, nil
+		}
+		if ctx.Err() != nil {
+			// The request was cut short by the deadline, which the transport may report as its own error
+			return nil, fmt.Errorf("timed out waiting for transaction %s: %w", txHash.Hex(), ctx.Err())
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, fmt.Errorf("failed to get transaction receipt: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for transaction %s: %w", txHash.Hex(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Backend returns the client the registry talks to.
+func (r *Registry) Backend() Backend {
+	return r.backend
+}
+
+// LookupFile reads the registration of a CID through getFileHash and getFileDetails. It returns
+// ErrNotRegistered when the contract rejects the lookup because the CID or hash is unknown.
+func (r *Registry) LookupFile(ctx context.Context, cid string) (*Registration, error) {
+	fileHash, err := r.contract.GetFileHash(&bind.CallOpts{Context: ctx}, cid)
+	if err != nil {
+		return nil, callError("getFileHash", err)
+	}
+
+	registration, err := r.LookupFileHash(ctx, fileHash)
+	if err != nil {
+		return nil, err
+	}
+	registration.CID = cid
+	return registration, nil
+}
+
+// LookupFileHash reads the registration of a file hash through getFileDetails. The contract does
+// not record the CID of a hash, so it is left empty.
+func (r *Registry) LookupFileHash(ctx context.Context, fileHash string) (*Registration, error) {
+	details, err := r.contract.GetFileDetails(&bind.CallOpts{Context: ctx}, fileHash)
+	if err != nil {
+		return nil, callError("getFileDetails", err)
+	}
+
+	return &Registration{
+		FileHash:  details.FileHash,
+		Owner:     details.Owner,
+		Timestamp: time.Unix(details.Timestamp.Int64(), 0).UTC(),
+	}, nil
+}
+
+// callError distinguishes a reverted call, which the contract uses for unknown files, from a
+// failure to reach the node.
+func callError(method string, err error) error {
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		return fmt.Errorf("%w: %s reverted", ErrNotRegistered, method)
+	}
+	return fmt.Errorf("failed to call %s: %w", method, err)
+}
@@ -0,0 +1,498 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package chain
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+	_ = abi.ConvertType
+)
+
+// FileRegistryFileDetails is an auto generated low-level Go binding around an user-defined struct.
+type FileRegistryFileDetails struct {
+	Owner     common.Address
+	FileHash  string
+	Timestamp *big.Int
+}
+
+// FileRegistryMetaData contains all meta data concerning the FileRegistry contract.
+var FileRegistryMetaData = &bind.MetaData{
+	ABI: "[{\"anonymous\":false,\"inputs\":[{\"indexed\":true,\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"indexed\":false,\"internalType\":\"string\",\"name\":\"cid\",\"type\":\"string\"},{\"indexed\":false,\"internalType\":\"string\",\"name\":\"fileHash\",\"type\":\"string\"}],\"name\":\"FileRegistered\",\"type\":\"event\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"name\":\"cidToFileHash\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"name\":\"fileDetails\",\"outputs\":[{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"internalType\":\"string\",\"name\":\"fileHash\",\"type\":\"string\"},{\"internalType\":\"uint256\",\"name\":\"timestamp\",\"type\":\"uint256\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"fileHash\",\"type\":\"string\"}],\"name\":\"getFileDetails\",\"outputs\":[{\"components\":[{\"internalType\":\"address\",\"name\":\"owner\",\"type\":\"address\"},{\"internalType\":\"string\",\"name\":\"fileHash\",\"type\":\"string\"},{\"internalType\":\"uint256\",\"name\":\"timestamp\",\"type\":\"uint256\"}],\"internalType\":\"structFileRegistry.FileDetails\",\"name\":\"\",\"type\":\"tuple\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"cid\",\"type\":\"string\"}],\"name\":\"getFileHash\",\"outputs\":[{\"internalType\":\"string\",\"name\":\"\",\"type\":\"string\"}],\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"internalType\":\"string\",\"name\":\"cid\",\"type\":\"string\"},{\"internalType\":\"string\",\"name\":\"fileHash\",\"type\":\"string\"}],\"name\":\"registerFile\",\"outputs\":[],\"stateMutability\":\"nonpayable\",\"type\":\"function\"}]",
+}
+
+// FileRegistryABI is the input ABI used to generate the binding from.
+// Deprecated: Use FileRegistryMetaData.ABI instead.
+var FileRegistryABI = FileRegistryMetaData.ABI
+
+// FileRegistry is an auto generated Go binding around an Ethereum contract.
+type FileRegistry struct {
+	FileRegistryCaller     // Read-only binding to the contract
+	FileRegistryTransactor // Write-only binding to the contract
+	FileRegistryFilterer   // Log filterer for contract events
+}
+
+// FileRegistryCaller is an auto generated read-only Go binding around an Ethereum contract.
+type FileRegistryCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// FileRegistryTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type FileRegistryTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// FileRegistryFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type FileRegistryFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// FileRegistrySession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type FileRegistrySession struct {
+	Contract     *FileRegistry     // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// FileRegistryCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type FileRegistryCallerSession struct {
+	Contract *FileRegistryCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts       // Call options to use throughout this session
+}
+
+// FileRegistryTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type FileRegistryTransactorSession struct {
+	Contract     *FileRegistryTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts       // Transaction auth options to use throughout this session
+}
+
+// FileRegistryRaw is an auto generated low-level Go binding around an Ethereum contract.
+type FileRegistryRaw struct {
+	Contract *FileRegistry // Generic contract binding to access the raw methods on
+}
+
+// FileRegistryCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type FileRegistryCallerRaw struct {
+	Contract *FileRegistryCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// FileRegistryTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type FileRegistryTransactorRaw struct {
+	Contract *FileRegistryTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewFileRegistry creates a new instance of FileRegistry, bound to a specific deployed contract.
+func NewFileRegistry(address common.Address, backend bind.ContractBackend) (*FileRegistry, error) {
+	contract, err := bindFileRegistry(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRegistry{FileRegistryCaller: FileRegistryCaller{contract: contract}, FileRegistryTransactor: FileRegistryTransactor{contract: contract}, FileRegistryFilterer: FileRegistryFilterer{contract: contract}}, nil
+}
+
+// NewFileRegistryCaller creates a new read-only instance of FileRegistry, bound to a specific deployed contract.
+func NewFileRegistryCaller(address common.Address, caller bind.ContractCaller) (*FileRegistryCaller, error) {
+	contract, err := bindFileRegistry(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRegistryCaller{contract: contract}, nil
+}
+
+// NewFileRegistryTransactor creates a new write-only instance of FileRegistry, bound to a specific deployed contract.
+func NewFileRegistryTransactor(address common.Address, transactor bind.ContractTransactor) (*FileRegistryTransactor, error) {
+	contract, err := bindFileRegistry(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRegistryTransactor{contract: contract}, nil
+}
+
+// NewFileRegistryFilterer creates a new log filterer instance of FileRegistry, bound to a specific deployed contract.
+func NewFileRegistryFilterer(address common.Address, filterer bind.ContractFilterer) (*FileRegistryFilterer, error) {
+	contract, err := bindFileRegistry(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRegistryFilterer{contract: contract}, nil
+}
+
+// bindFileRegistry binds a generic wrapper to an already deployed contract.
+func bindFileRegistry(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := FileRegistryMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_FileRegistry *FileRegistryRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _FileRegistry.Contract.FileRegistryCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_FileRegistry *FileRegistryRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _FileRegistry.Contract.FileRegistryTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_FileRegistry *FileRegistryRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _FileRegistry.Contract.FileRegistryTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_FileRegistry *FileRegistryCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _FileRegistry.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_FileRegistry *FileRegistryTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _FileRegistry.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_FileRegistry *FileRegistryTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _FileRegistry.Contract.contract.Transact(opts, method, params...)
+}
+
+// CidToFileHash is a free data retrieval call binding the contract method 0x04f9ffc2.
+//
+// Solidity: function cidToFileHash(string ) view returns(string)
+func (_FileRegistry *FileRegistryCaller) CidToFileHash(opts *bind.CallOpts, arg0 string) (string, error) {
+	var out []interface{}
+	err := _FileRegistry.contract.Call(opts, &out, "cidToFileHash", arg0)
+
+	if err != nil {
+		return *new(string), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+
+	return out0, err
+
+}
+
+// CidToFileHash is a free data retrieval call binding the contract method 0x04f9ffc2.
+//
+// Solidity: function cidToFileHash(string ) view returns(string)
+func (_FileRegistry *FileRegistrySession) CidToFileHash(arg0 string) (string, error) {
+	return _FileRegistry.Contract.CidToFileHash(&_FileRegistry.CallOpts, arg0)
+}
+
+// CidToFileHash is a free data retrieval call binding the contract method 0x04f9ffc2.
+//
+// Solidity: function cidToFileHash(string ) view returns(string)
+func (_FileRegistry *FileRegistryCallerSession) CidToFileHash(arg0 string) (string, error) {
+	return _FileRegistry.Contract.CidToFileHash(&_FileRegistry.CallOpts, arg0)
+}
+
+// FileDetails is a free data retrieval call binding the contract method 0xb7c4f53e.
+//
+// Solidity: function fileDetails(string ) view returns(address owner, string fileHash, uint256 timestamp)
+func (_FileRegistry *FileRegistryCaller) FileDetails(opts *bind.CallOpts, arg0 string) (struct {
+	Owner     common.Address
+	FileHash  string
+	Timestamp *big.Int
+}, error) {
+	var out []interface{}
+	err := _FileRegistry.contract.Call(opts, &out, "fileDetails", arg0)
+
+	outstruct := new(struct {
+		Owner     common.Address
+		FileHash  string
+		Timestamp *big.Int
+	})
+	if err != nil {
+		return *outstruct, err
+	}
+
+	outstruct.Owner = *abi.ConvertType(out[0], new(common.Address)).(*common.Address)
+	outstruct.FileHash = *abi.ConvertType(out[1], new(string)).(*string)
+	outstruct.Timestamp = *abi.ConvertType(out[2], new(*big.Int)).(**big.Int)
+
+	return *outstruct, err
+
+}
+
+// FileDetails is a free data retrieval call binding the contract method 0xb7c4f53e.
+//
+// Solidity: function fileDetails(string ) view returns(address owner, string fileHash, uint256 timestamp)
+func (_FileRegistry *FileRegistrySession) FileDetails(arg0 string) (struct {
+	Owner     common.Address
+	FileHash  string
+	Timestamp *big.Int
+}, error) {
+	return _FileRegistry.Contract.FileDetails(&_FileRegistry.CallOpts, arg0)
+}
+
+// FileDetails is a free data retrieval call binding the contract method 0xb7c4f53e.
+//
+// Solidity: function fileDetails(string ) view returns(address owner, string fileHash, uint256 timestamp)
+func (_FileRegistry *FileRegistryCallerSession) FileDetails(arg0 string) (struct {
+	Owner     common.Address
+	FileHash  string
+	Timestamp *big.Int
+}, error) {
+	return _FileRegistry.Contract.FileDetails(&_FileRegistry.CallOpts, arg0)
+}
+
+// GetFileDetails is a free data retrieval call binding the contract method 0x04957505.
+//
+// Solidity: function getFileDetails(string fileHash) view returns((address,string,uint256))
+func (_FileRegistry *FileRegistryCaller) GetFileDetails(opts *bind.CallOpts, fileHash string) (FileRegistryFileDetails, error) {
+	var out []interface{}
+	err := _FileRegistry.contract.Call(opts, &out, "getFileDetails", fileHash)
+
+	if err != nil {
+		return *new(FileRegistryFileDetails), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(FileRegistryFileDetails)).(*FileRegistryFileDetails)
+
+	return out0, err
+
+}
+
+// GetFileDetails is a free data retrieval call binding the contract method 0x04957505.
+//
+// Solidity: function getFileDetails(string fileHash) view returns((address,string,uint256))
+func (_FileRegistry *FileRegistrySession) GetFileDetails(fileHash string) (FileRegistryFileDetails, error) {
+	return _FileRegistry.Contract.GetFileDetails(&_FileRegistry.CallOpts, fileHash)
+}
+
+// GetFileDetails is a free data retrieval call binding the contract method 0x04957505.
+//
+// Solidity: function getFileDetails(string fileHash) view returns((address,string,uint256))
+func (_FileRegistry *FileRegistryCallerSession) GetFileDetails(fileHash string) (FileRegistryFileDetails, error) {
+	return _FileRegistry.Contract.GetFileDetails(&_FileRegistry.CallOpts, fileHash)
+}
+
+// GetFileHash is a free data retrieval call binding the contract method 0xf880680b.
+//
+// Solidity: function getFileHash(string cid) view returns(string)
+func (_FileRegistry *FileRegistryCaller) GetFileHash(opts *bind.CallOpts, cid string) (string, error) {
+	var out []interface{}
+	err := _FileRegistry.contract.Call(opts, &out, "getFileHash", cid)
+
+	if err != nil {
+		return *new(string), err
+	}
+
+	out0 := *abi.ConvertType(out[0], new(string)).(*string)
+
+	return out0, err
+
+}
+
+// GetFileHash is a free data retrieval call binding the contract method 0xf880680b.
+//
+// Solidity: function getFileHash(string cid) view returns(string)
+func (_FileRegistry *FileRegistrySession) GetFileHash(cid string) (string, error) {
+	return _FileRegistry.Contract.GetFileHash(&_FileRegistry.CallOpts, cid)
+}
+
+// GetFileHash is a free data retrieval call binding the contract method 0xf880680b.
+//
+// Solidity: function getFileHash(string cid) view returns(string)
+func (_FileRegistry *FileRegistryCallerSession) GetFileHash(cid string) (string, error) {
+	return _FileRegistry.Contract.GetFileHash(&_FileRegistry.CallOpts, cid)
+}
+
+// RegisterFile is a paid mutator transaction binding the contract method 0xed791724.
+//
+// Solidity: function registerFile(string cid, string fileHash) returns()
+func (_FileRegistry *FileRegistryTransactor) RegisterFile(opts *bind.TransactOpts, cid string, fileHash string) (*types.Transaction, error) {
+	return _FileRegistry.contract.Transact(opts, "registerFile", cid, fileHash)
+}
+
+// RegisterFile is a paid mutator transaction binding the contract method 0xed791724.
+//
+// Solidity: function registerFile(string cid, string fileHash) returns()
+func (_FileRegistry *FileRegistrySession) RegisterFile(cid string, fileHash string) (*types.Transaction, error) {
+	return _FileRegistry.Contract.RegisterFile(&_FileRegistry.TransactOpts, cid, fileHash)
+}
+
+// RegisterFile is a paid mutator transaction binding the contract method 0xed791724.
+//
+// Solidity: function registerFile(string cid, string fileHash) returns()
+func (_FileRegistry *FileRegistryTransactorSession) RegisterFile(cid string, fileHash string) (*types.Transaction, error) {
+	return _FileRegistry.Contract.RegisterFile(&_FileRegistry.TransactOpts, cid, fileHash)
+}
+
+// FileRegistryFileRegisteredIterator is returned from FilterFileRegistered and is used to iterate over the raw logs and unpacked data for FileRegistered events raised by the FileRegistry contract.
+type FileRegistryFileRegisteredIterator struct {
+	Event *FileRegistryFileRegistered // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *FileRegistryFileRegisteredIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(FileRegistryFileRegistered)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(FileRegistryFileRegistered)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *FileRegistryFileRegisteredIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *FileRegistryFileRegisteredIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// FileRegistryFileRegistered represents a FileRegistered event raised by the FileRegistry contract.
+type FileRegistryFileRegistered struct {
+	Owner    common.Address
+	Cid      string
+	FileHash string
+	Raw      types.Log // Blockchain specific contextual infos
+}
+
+// FilterFileRegistered is a free log retrieval operation binding the contract event 0xa1333f8f53c8409decfbf5a6dcf670355c6a6150c50e21ce83d27c6d7c3f64b7.
+//
+// Solidity: event FileRegistered(address indexed owner, string cid, string fileHash)
+func (_FileRegistry *FileRegistryFilterer) FilterFileRegistered(opts *bind.FilterOpts, owner []common.Address) (*FileRegistryFileRegisteredIterator, error) {
+
+	var ownerRule []interface{}
+	for _, ownerItem := range owner {
+		ownerRule = append(ownerRule, ownerItem)
+	}
+
+	logs, sub, err := _FileRegistry.contract.FilterLogs(opts, "FileRegistered", ownerRule)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRegistryFileRegisteredIterator{contract: _FileRegistry.contract, event: "FileRegistered", logs: logs, sub: sub}, nil
+}
+
+// WatchFileRegistered is a free log subscription operation binding the contract event 0xa1333f8f53c8409decfbf5a6dcf670355c6a6150c50e21ce83d27c6d7c3f64b7.
+//
+// Solidity: event FileRegistered(address indexed owner, string cid, string fileHash)
+func (_FileRegistry *FileRegistryFilterer) WatchFileRegistered(opts *bind.WatchOpts, sink chan<- *FileRegistryFileRegistered, owner []common.Address) (event.Subscription, error) {
+
+	var ownerRule []interface{}
+	for _, ownerItem := range owner {
+		ownerRule = append(ownerRule, ownerItem)
+	}
+
+	logs, sub, err := _FileRegistry.contract.WatchLogs(opts, "FileRegistered", ownerRule)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(FileRegistryFileRegistered)
+				if err := _FileRegistry.contract.UnpackLog(event, "FileRegistered", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// ParseFileRegistered is a log parse operation binding the contract event 0xa1333f8f53c8409decfbf5a6dcf670355c6a6150c50e21ce83d27c6d7c3f64b7.
+//
+// Solidity: event FileRegistered(address indexed owner, string cid, string fileHash)
+func (_FileRegistry *FileRegistryFilterer) ParseFileRegistered(log types.Log) (*FileRegistryFileRegistered, error) {
+	event := new(FileRegistryFileRegistered)
+	if err := _FileRegistry.contract.UnpackLog(event, "FileRegistered", log); err != nil {
+		return nil, err
+	}
+	event.Raw = log
+	return event, nil
+}
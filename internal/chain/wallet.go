@@ -0,0 +1,74 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// erc1271MagicValue is returned by isValidSignature, bytes4(keccak256("isValidSignature(bytes32,bytes)")),
+// when a contract wallet accepts a signature.
+var erc1271MagicValue = [4]byte{0x16, 0x26, 0xba, 0x7e}
+
+const erc1271ABI = `[{"type":"function","name":"isValidSignature","stateMutability":"view",` +
+	`"inputs":[{"name":"hash","type":"bytes32"},{"name":"signature","type":"bytes"}],` +
+	`"outputs":[{"name":"magicValue","type":"bytes4"}]}]`
+
+var erc1271 = mustParseABI(erc1271ABI)
+
+// WalletBackend is the subset of an Ethereum client used to check contract wallet signatures.
+// Both *ethclient.Client and the client of go-ethereum's simulated backend satisfy it.
+type WalletBackend interface {
+	bind.ContractCaller
+}
+
+// IsContract reports whether code is deployed at address, as it is for Safe and other smart
+// contract wallets.
+func IsContract(ctx context.Context, backend WalletBackend, address common.Address) (bool, error) {
+	code, err := backend.CodeAt(ctx, address, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to get code of %s: %w", address.Hex(), err)
+	}
+	return len(code) > 0, nil
+}
+
+// IsValidSignature asks the contract wallet at address whether signature is valid for hash, as
+// defined by EIP-1271. A wallet that reverts or returns anything but the magic value rejects the
+// signature; an error is only returned when the node cannot be reached.
+func IsValidSignature(ctx context.Context, backend WalletBackend, address common.Address, hash common.Hash, signature []byte) (bool, error) {
+	input, err := erc1271.Pack("isValidSignature", hash, signature)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode isValidSignature: %w", err)
+	}
+
+	output, err := backend.CallContract(ctx, ethereum.CallMsg{To: &address, Data: input}, nil)
+	if err != nil {
+		var dataErr rpc.DataError
+		if errors.As(err, &dataErr) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to call isValidSignature: %w", err)
+	}
+
+	values, err := erc1271.Unpack("isValidSignature", output)
+	if err != nil || len(values) != 1 {
+		return false, nil
+	}
+	magicValue, ok := values[0].([4]byte)
+	return ok && magicValue == erc1271MagicValue, nil
+}
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
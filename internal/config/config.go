@@ -0,0 +1,98 @@
+// Package config loads the settings of the server and its commands from a YAML or TOML file, the
+// environment and command-line flags.
+//
+// Every setting has a key in the file, nested in its section, an environment variable and a flag
+// named after the variable: database.host, DB_HOST and -db-host. Flags take precedence over the
+// environment, the environment over the file and the file over the defaults. Secrets can also be
+// read from a file named by the same setting with a _file suffix: database.password_file,
+// DB_PASSWORD_FILE or -db-password-file.
+package config
+
+import (
+	"math/big"
+	"time"
+)
+
+// Config holds all settings. It is loaded and validated once on startup by Load and passed to
+// the components that need it.
+type Config struct {
+	Server     ServerConfig     `key:"server"`
+	Database   DatabaseConfig   `key:"database"`
+	IPFS       IPFSConfig       `key:"ipfs"`
+	Auth       AuthConfig       `key:"auth"`
+	Storage    StorageConfig    `key:"storage"`
+	Encryption EncryptionConfig `key:"encryption"`
+	Chain      ChainConfig      `key:"chain"`
+	Receipts   ReceiptsConfig   `key:"receipts"`
+	Tracing    TracingConfig    `key:"tracing"`
+}
+
+type ServerConfig struct {
+	Port         int    `key:"port" env:"PORT" default:"8083" usage:"port the API listens on"`
+	MetricsToken string `key:"metrics_token" env:"METRICS_TOKEN" secret:"" usage:"bearer token required to read /metrics; unset serves them to anyone"`
+}
+
+type DatabaseConfig struct {
+	Host        string `key:"host" env:"DB_HOST" default:"localhost" usage:"Postgres host"`
+	Port        int    `key:"port" env:"DB_PORT" default:"5432" usage:"Postgres port"`
+	Name        string `key:"name" env:"DB_NAME" default:"postgres" usage:"Postgres database"`
+	User        string `key:"user" env:"DB_USER" default:"postgres" usage:"Postgres user"`
+	Password    string `key:"password" env:"DB_PASSWORD" secret:"/run/secrets/db_password" usage:"Postgres password"`
+	SSLMode     string `key:"sslmode" env:"SSL_MODE" default:"disable" usage:"Postgres sslmode"`
+	AutoMigrate bool   `key:"auto_migrate" env:"DB_AUTO_MIGRATE" default:"true" usage:"apply pending migrations when the server starts"`
+}
+
+type IPFSConfig struct {
+	Address string `key:"address" env:"IPFS_ADDRESS" default:"/ip4/127.0.0.1/tcp/5001" usage:"multiaddr or URL of the IPFS HTTP API"`
+}
+
+type AuthConfig struct {
+	JWTSecret      string   `key:"jwt_secret" env:"JWT_SECRET" secret:"/run/secrets/jwt_secret" usage:"key tokens are signed with"`
+	ChainID        int64    `key:"chain_id" env:"AUTH_CHAIN_ID" default:"1" usage:"chain ID actions are signed for when on-chain registration is disabled"`
+	AdminAddresses []string `key:"admin_addresses" env:"ADMIN_ADDRESSES" usage:"comma-separated addresses made admins on startup"`
+}
+
+type StorageConfig struct {
+	VersionRetention  int   `key:"version_retention" env:"FILE_VERSION_RETENTION" default:"10" usage:"versions kept of each file unless it overrides it, 0 for all"`
+	DefaultQuotaBytes int64 `key:"default_quota_bytes" env:"DEFAULT_QUOTA_BYTES" default:"1073741824" usage:"bytes each user may store, 0 for unlimited"`
+	DefaultQuotaFiles int64 `key:"default_quota_files" env:"DEFAULT_QUOTA_FILES" default:"10000" usage:"file versions each user may store, 0 for unlimited"`
+}
+
+type EncryptionConfig struct {
+	KEK              string   `key:"kek" env:"ENCRYPTION_KEK" secret:"" usage:"hex-encoded key file encryption keys are wrapped with"`
+	PreviousKEKFiles []string `key:"previous_kek_files" env:"ENCRYPTION_PREVIOUS_KEK_FILES" usage:"comma-separated files holding KEKs keys may still be wrapped with"`
+}
+
+type ChainConfig struct {
+	RPCURL              string        `key:"rpc_url" env:"ETH_RPC_URL" usage:"Ethereum JSON-RPC endpoint; enables on-chain registration"`
+	RegistryAddress     string        `key:"registry_address" env:"FILE_REGISTRY_ADDRESS" usage:"address of the FileRegistry contract"`
+	SignerKey           string        `key:"signer_key" env:"CHAIN_SIGNER_KEY" secret:"/run/secrets/chain_signer_key" usage:"hex-encoded key registrations are signed with"`
+	GasLimit            uint64        `key:"gas_limit" env:"CHAIN_GAS_LIMIT" default:"0" usage:"gas limit of registrations, 0 to estimate"`
+	GasFeeCap           *big.Int      `key:"gas_fee_cap_wei" env:"CHAIN_GAS_FEE_CAP_WEI" usage:"gas fee cap in wei instead of the node's suggestion"`
+	GasTipCap           *big.Int      `key:"gas_tip_cap_wei" env:"CHAIN_GAS_TIP_CAP_WEI" usage:"gas tip cap in wei instead of the node's suggestion"`
+	ReceiptPollInterval time.Duration `key:"receipt_poll_interval" env:"CHAIN_RECEIPT_POLL_INTERVAL" default:"2s" usage:"how often to poll for transaction receipts"`
+	ReceiptTimeout      time.Duration `key:"receipt_timeout" env:"CHAIN_RECEIPT_TIMEOUT" default:"5m" usage:"how long to wait for a registration to be mined"`
+	IndexerStartBlock   uint64        `key:"indexer_start_block" env:"CHAIN_INDEXER_START_BLOCK" default:"0" usage:"block to index FileRegistered events from"`
+	Confirmations       uint64        `key:"confirmations" env:"CHAIN_CONFIRMATIONS" default:"12" usage:"blocks after which an event is confirmed"`
+	IndexerPollInterval time.Duration `key:"indexer_poll_interval" env:"CHAIN_INDEXER_POLL_INTERVAL" default:"15s" usage:"how often to poll for FileRegistered events"`
+	VerifyMode          string        `key:"verify_mode" env:"CHAIN_VERIFY_MODE" default:"off" usage:"how downloads are checked against the registry: off, advisory or strict"`
+}
+
+type ReceiptsConfig struct {
+	SigningKey string `key:"signing_key" env:"RECEIPT_SIGNING_KEY" secret:"" usage:"hex-encoded key upload receipts are signed with; enables receipts"`
+}
+
+type TracingConfig struct {
+	Endpoint    string `key:"endpoint" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT" usage:"URL of the OTLP/HTTP endpoint spans are exported to, such as http://collector:4318/v1/traces; enables tracing"`
+	ServiceName string `key:"service_name" env:"OTEL_SERVICE_NAME" default:"safetransfer" usage:"service name spans are exported with"`
+}
+
+// Enabled reports whether on-chain registration is configured.
+func (c ChainConfig) Enabled() bool {
+	return c.RPCURL != ""
+}
+
+// Enabled reports whether spans are exported.
+func (c TracingConfig) Enabled() bool {
+	return c.Endpoint != ""
+}
@@ -0,0 +1,337 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv is the environment variable naming the configuration file; the -config flag overrides it.
+const FileEnv = "SAFETRANSFER_CONFIG"
+
+// Error lists every problem found while loading the configuration.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// setting is a field of Config together with the names it is set by.
+type setting struct {
+	field        reflect.Value
+	key          string // key in the configuration file, such as database.host
+	env          string // environment variable, such as DB_HOST
+	flag         string // flag, such as db-host
+	defaultValue string
+	secret       bool   // whether it can be read from a file named by the _FILE variant
+	secretPath   string // file read when a secret is not set anywhere, if it exists
+	usage        string
+}
+
+// settings lists the settings of cfg in the order they are declared.
+func settings(cfg *Config) []setting {
+	var all []setting
+	sections := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Type().Field(i).Tag.Get("key")
+		fields := sections.Field(i)
+		for j := 0; j < fields.NumField(); j++ {
+			tag := fields.Type().Field(j).Tag
+			env := tag.Get("env")
+			secretPath, secret := tag.Lookup("secret")
+			all = append(all, setting{
+				field:        fields.Field(j),
+				key:          section + "." + tag.Get("key"),
+				env:          env,
+				flag:         strings.ReplaceAll(strings.ToLower(env), "_", "-"),
+				defaultValue: tag.Get("default"),
+				secret:       secret,
+				secretPath:   secretPath,
+				usage:        tag.Get("usage"),
+			})
+		}
+	}
+	return all
+}
+
+// RegisterFlags defines -config and a flag for every setting on flags. Secrets only have a flag
+// naming the file to read them from, so that they do not show up in process listings.
+func RegisterFlags(flags *flag.FlagSet) {
+	flags.String("config", "", "YAML or TOML `file` to read settings from ("+FileEnv+")")
+	for _, s := range settings(&Config{}) {
+		if s.secret {
+			flags.String(s.flag+"-file", "", "`file` holding the "+s.usage+" ("+s.env+"_FILE)")
+			continue
+		}
+		flags.String(s.flag, "", s.usage+" ("+s.env+")")
+	}
+}
+
+// origin is one of the places settings are read from.
+type origin struct {
+	description string
+	lookup      func(s *setting, secretFile bool) (string, bool)
+}
+
+// Load reads the configuration from the file named by -config or SAFETRANSFER_CONFIG, the
+// environment and the flags set on flags, which may be nil, and validates it. All problems are
+// reported together in an *Error.
+func Load(flags *flag.FlagSet) (*Config, error) {
+	flagValues := make(map[string]string)
+	if flags != nil {
+		flags.Visit(func(f *flag.Flag) {
+			flagValues[f.Name] = f.Value.String()
+		})
+	}
+
+	path, ok := flagValues["config"]
+	if !ok {
+		path = os.Getenv(FileEnv)
+	}
+	fileValues := make(map[string]string)
+	if path != "" {
+		var err error
+		if fileValues, err = readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	origins := []origin{
+		{"flag", func(s *setting, secretFile bool) (string, bool) {
+			if secretFile {
+				value, ok := flagValues[s.flag+"-file"]
+				return value, ok
+			}
+			value, ok := flagValues[s.flag]
+			return value, ok
+		}},
+		{"environment", func(s *setting, secretFile bool) (string, bool) {
+			name := s.env
+			if secretFile {
+				name += "_FILE"
+			}
+			// Empty variables are treated as unset, as Compose files often pass them on blank.
+			value := os.Getenv(name)
+			return value, value != ""
+		}},
+		{"config file", func(s *setting, secretFile bool) (string, bool) {
+			name := s.key
+			if secretFile {
+				name += "_file"
+			}
+			value, ok := fileValues[name]
+			return value, ok
+		}},
+	}
+
+	cfg := &Config{}
+	var problems []string
+	known := make(map[string]bool)
+	for _, s := range settings(cfg) {
+		known[s.key] = true
+		if s.secret {
+			known[s.key+"_file"] = true
+		}
+
+		value, from, err := resolve(&s, origins)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if err := s.set(value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %q (from %s) %v", s.env, value, from, err))
+			// Validate the default instead, so that the setting is not reported twice.
+			s.set(s.defaultValue)
+		}
+	}
+
+	var unknown []string
+	for key := range fileValues {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		problems = append(problems, fmt.Sprintf("unknown setting %q in %s", key, path))
+	}
+
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// resolve returns the raw value of a setting from the first origin that sets it, and where it
+// came from.
+func resolve(s *setting, origins []origin) (string, string, error) {
+	for _, o := range origins {
+		value, hasValue := o.lookup(s, false)
+		if !s.secret {
+			if hasValue {
+				return value, o.description, nil
+			}
+			continue
+		}
+
+		path, hasPath := o.lookup(s, true)
+		switch {
+		case hasValue && hasPath:
+			return "", "", fmt.Errorf("%s and %s_FILE are both set in the %s", s.env, s.env, o.description)
+		case hasValue:
+			return value, o.description, nil
+		case hasPath:
+			secret, err := readSecret(path)
+			if err != nil {
+				return "", "", fmt.Errorf("%s_FILE (from %s): %v", s.env, o.description, err)
+			}
+			return secret, path, nil
+		}
+	}
+
+	if s.secretPath != "" {
+		secret, err := readSecret(s.secretPath)
+		if err == nil {
+			return secret, s.secretPath, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", "", fmt.Errorf("%s: %v", s.env, err)
+		}
+	}
+	return s.defaultValue, "default", nil
+}
+
+// readSecret reads a secret from a file, without the line break editors usually end files with.
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// set parses value into the setting's field.
+func (s *setting) set(value string) error {
+	switch s.field.Interface().(type) {
+	case string:
+		s.field.SetString(value)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		s.field.SetBool(b)
+	case int, int64:
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, s.field.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		s.field.SetInt(n)
+	case uint64:
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		s.field.SetUint(n)
+	case time.Duration:
+		if value == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("must be a duration such as 30s")
+		}
+		s.field.SetInt(int64(d))
+	case *big.Int:
+		if value == "" {
+			return nil
+		}
+		n, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return errors.New("must be an integer")
+		}
+		s.field.Set(reflect.ValueOf(n))
+	case []string:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		s.field.Set(reflect.ValueOf(list))
+	default:
+		panic("config: unsupported type of " + s.key)
+	}
+	return nil
+}
+
+// readFile reads a YAML or TOML configuration file, depending on its extension, into a map from
+// keys such as database.host to their values. Lists are joined with commas.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	var sections map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &sections)
+	case ".toml":
+		err = toml.Unmarshal(data, &sections)
+	default:
+		return nil, fmt.Errorf("configuration file %s must end in .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse configuration file: %w", err)
+	}
+
+	values := make(map[string]string)
+	for name, section := range sections {
+		settings, ok := section.(map[string]interface{})
+		if !ok {
+			// Reported as an unknown setting
+			values[name] = fmt.Sprint(section)
+			continue
+		}
+		for key, value := range settings {
+			values[name+"."+key] = formatValue(value)
+		}
+	}
+	return values, nil
+}
+
+func formatValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = formatValue(item)
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// chainVerifyModes are the modes of service.DownloadService.
+var chainVerifyModes = []string{"off", "advisory", "strict"}
+
+// validate checks the settings against each other and returns every problem it finds. Problems
+// name settings by their environment variable and never include secrets.
+func (c *Config) validate() []string {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "PORT must be between 1 and 65535")
+	check(c.Database.Host != "", "DB_HOST must be set")
+	check(c.Database.Port > 0 && c.Database.Port < 65536, "DB_PORT must be between 1 and 65535")
+	check(c.IPFS.Address != "", "IPFS_ADDRESS must be set")
+
+	check(c.Auth.ChainID > 0, "AUTH_CHAIN_ID must be positive")
+	for _, address := range c.Auth.AdminAddresses {
+		check(common.IsHexAddress(address), "ADMIN_ADDRESSES: %q is not an Ethereum address", address)
+	}
+
+	check(c.Storage.VersionRetention >= 0, "FILE_VERSION_RETENTION must not be negative")
+	check(c.Storage.DefaultQuotaBytes >= 0, "DEFAULT_QUOTA_BYTES must not be negative")
+	check(c.Storage.DefaultQuotaFiles >= 0, "DEFAULT_QUOTA_FILES must not be negative")
+
+	if c.Encryption.KEK != "" {
+		check(isHexKey(c.Encryption.KEK), "ENCRYPTION_KEK must be a hex-encoded 32-byte key")
+	}
+	check(c.Encryption.KEK != "" || len(c.Encryption.PreviousKEKFiles) == 0, "ENCRYPTION_PREVIOUS_KEK_FILES requires ENCRYPTION_KEK")
+
+	chain := c.Chain
+	if chain.Enabled() {
+		check(common.IsHexAddress(chain.RegistryAddress), "FILE_REGISTRY_ADDRESS must be a contract address when ETH_RPC_URL is set")
+		check(chain.SignerKey != "", "CHAIN_SIGNER_KEY must be set when ETH_RPC_URL is set")
+		if chain.SignerKey != "" {
+			check(isHexKey(chain.SignerKey), "CHAIN_SIGNER_KEY must be a hex-encoded secp256k1 private key")
+		}
+	}
+	check(chain.GasFeeCap == nil || chain.GasFeeCap.Sign() >= 0, "CHAIN_GAS_FEE_CAP_WEI must not be negative")
+	check(chain.GasTipCap == nil || chain.GasTipCap.Sign() >= 0, "CHAIN_GAS_TIP_CAP_WEI must not be negative")
+	check(chain.ReceiptPollInterval > 0, "CHAIN_RECEIPT_POLL_INTERVAL must be positive")
+	check(chain.ReceiptTimeout > 0, "CHAIN_RECEIPT_TIMEOUT must be positive")
+	check(chain.IndexerPollInterval > 0, "CHAIN_INDEXER_POLL_INTERVAL must be positive")
+	validMode := false
+	for _, mode := range chainVerifyModes {
+		validMode = validMode || chain.VerifyMode == mode
+	}
+	check(validMode, "CHAIN_VERIFY_MODE must be off, advisory or strict")
+	check(chain.VerifyMode == "off" || !validMode || chain.Enabled(), "CHAIN_VERIFY_MODE %s requires ETH_RPC_URL", chain.VerifyMode)
+
+	if c.Receipts.SigningKey != "" {
+		check(isHexKey(c.Receipts.SigningKey), "RECEIPT_SIGNING_KEY must be a hex-encoded secp256k1 private key")
+	}
+
+	if c.Tracing.Enabled() {
+		endpoint, err := url.Parse(c.Tracing.Endpoint)
+		check(err == nil && (endpoint.Scheme == "http" || endpoint.Scheme == "https") && endpoint.Host != "", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT must be an http or https URL")
+	}
+	check(c.Tracing.ServiceName != "", "OTEL_SERVICE_NAME must be set")
+
+	return problems
+}
+
+// isHexKey reports whether key is a hex-encoded 32-byte key, with or without 0x.
+func isHexKey(key string) bool {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(key), "0x"))
+	return err == nil && len(decoded) == 32
+}
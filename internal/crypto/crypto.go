@@ -11,6 +11,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"time"
 )
 
 // newAesCtrStream initializes an AES cipher in CTR mode with the given key and IV.
@@ -22,7 +23,7 @@ func newAesCtrStream(key, iv []byte) (cipher.Stream, error) {
 	return cipher.NewCTR(block, iv), nil
 }
 
-func EncryptFile(file io.Reader, key []byte) (io.Reader, []byte, error) {
+func EncryptFile(file io.Reader, key []byte) (*CipherReader, []byte, error) {
 	iv := make([]byte, aes.BlockSize)
 	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
 		return nil, nil, fmt.Errorf("failed to generate IV: %w", err)
@@ -34,21 +35,50 @@ func EncryptFile(file io.Reader, key []byte) (io.Reader, []byte, error) {
 	}
 
 	bufferedFile := bufio.NewReader(file)
-	encryptedFile := &cipher.StreamReader{S: stream, R: bufferedFile}
+	encryptedFile := &CipherReader{S: stream, R: bufferedFile}
 	return encryptedFile, iv, nil
 }
 
-func DecryptFile(encryptedFile io.Reader, key []byte, iv []byte) (io.Reader, error) {
+func DecryptFile(encryptedFile io.Reader, key []byte, iv []byte) (*CipherReader, error) {
 	stream, err := newAesCtrStream(key, iv)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize AES CTR stream: %w", err)
 	}
 
 	bufferedFile := bufio.NewReader(encryptedFile)
-	decryptedFile := &cipher.StreamReader{S: stream, R: bufferedFile}
+	decryptedFile := &CipherReader{S: stream, R: bufferedFile}
 	return decryptedFile, nil
 }
 
+// CipherReader applies a cipher stream to a reader like cipher.StreamReader, timing the cipher.
+// Time spent reading the source, such as waiting for IPFS, is not included.
+type CipherReader struct {
+	S       cipher.Stream
+	R       io.Reader
+	OnEOF   func(elapsed time.Duration) // called with the time spent in the cipher once R is exhausted
+	elapsed time.Duration
+	done    bool
+}
+
+func (r *CipherReader) Read(p []byte) (int, error) {
+	n, err := r.R.Read(p)
+	start := time.Now()
+	r.S.XORKeyStream(p[:n], p[:n])
+	r.elapsed += time.Since(start)
+	if err == io.EOF && !r.done {
+		r.done = true
+		if r.OnEOF != nil {
+			r.OnEOF(r.elapsed)
+		}
+	}
+	return n, err
+}
+
+// Elapsed returns the time spent in the cipher so far.
+func (r *CipherReader) Elapsed() time.Duration {
+	return r.elapsed
+}
+
 // SignFile signs the given file using RSA.
 func SignFile(file io.Reader, privateKey *rsa.PrivateKey) ([]byte, error) {
 	hash := sha256.New()
@@ -71,12 +101,17 @@ func VerifyFile(file io.Reader, signature string, publicKey *rsa.PublicKey) erro
 		return fmt.Errorf("failed to hash file for verification: %w", err)
 	}
 
+	return VerifyHash(hash.Sum(nil), signature, publicKey)
+}
+
+// VerifyHash verifies a signature over an already computed SHA-256 digest using RSA.
+func VerifyHash(digest []byte, signature string, publicKey *rsa.PublicKey) error {
 	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
 	if err != nil {
 		return fmt.Errorf("failed to decode signature: %w", err)
 	}
 
-	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hash.Sum(nil), signatureBytes); err != nil {
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest, signatureBytes); err != nil {
 		return fmt.Errorf("failed to verify signature: %w", err)
 	}
 
@@ -0,0 +1,154 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KEKSize is the size of a key encryption key: 32 bytes for AES-256.
+const KEKSize = 32
+
+// wrappedKeyPrefix marks an encryption key wrapped by a KEK. Unwrapped keys are plain base64,
+// which never contains a colon.
+const wrappedKeyPrefix = "kek:"
+
+var (
+	ErrUnknownKEK        = errors.New("encryption key is wrapped by an unknown KEK")
+	errInvalidWrappedKey = errors.New("invalid wrapped encryption key")
+)
+
+// KeyRing wraps the per-file encryption keys stored in the database with a key encryption key
+// (KEK), so that a copy of the database alone does not decrypt any file. It holds what the
+// rotate-kek command needs and no more: new keys are wrapped with the current KEK, keys wrapped
+// with a previous KEK can still be unwrapped until they are rewrapped with the current one, and
+// the KEK ID in each wrapped key tells which keys are left to rewrap.
+//
+// A nil *KeyRing stores keys as plain base64, as was done before KEKs were introduced.
+type KeyRing struct {
+	currentID string
+	keks      map[string]cipher.AEAD
+}
+
+// NewKeyRing creates a key ring that wraps keys with current and also unwraps keys wrapped
+// with any of previous.
+func NewKeyRing(current []byte, previous ...[]byte) (*KeyRing, error) {
+	kr := &KeyRing{keks: make(map[string]cipher.AEAD)}
+	for i, kek := range append([][]byte{current}, previous...) {
+		if len(kek) != KEKSize {
+			return nil, fmt.Errorf("KEK must be %d bytes, got %d", KEKSize, len(kek))
+		}
+		block, err := aes.NewCipher(kek)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize KEK: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize KEK: %w", err)
+		}
+
+		id := kekID(kek)
+		if i == 0 {
+			kr.currentID = id
+		}
+		kr.keks[id] = aead
+	}
+	return kr, nil
+}
+
+// ParseKEK decodes a hex-encoded KEK, with or without 0x.
+func ParseKEK(hexKEK string) ([]byte, error) {
+	kek, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(hexKEK), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode KEK: %w", err)
+	}
+	if len(kek) != KEKSize {
+		return nil, fmt.Errorf("KEK must be %d bytes, got %d", KEKSize, len(kek))
+	}
+	return kek, nil
+}
+
+// LoadKEK reads a hex-encoded KEK from a file.
+func LoadKEK(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read KEK: %w", err)
+	}
+	return ParseKEK(string(data))
+}
+
+// kekID identifies a KEK in wrapped keys without revealing it.
+func kekID(kek []byte) string {
+	sum := sha256.Sum256(kek)
+	return hex.EncodeToString(sum[:4])
+}
+
+// CurrentKEKID returns the ID of the KEK new keys are wrapped with, or "" for a nil key ring.
+func (kr *KeyRing) CurrentKEKID() string {
+	if kr == nil {
+		return ""
+	}
+	return kr.currentID
+}
+
+// Wrap encodes key for storage, encrypting it with the current KEK as
+// "kek:<kek id>:<base64 of the GCM nonce and ciphertext>".
+func (kr *KeyRing) Wrap(key []byte) (string, error) {
+	if kr == nil {
+		return base64.StdEncoding.EncodeToString(key), nil
+	}
+
+	aead := kr.keks[kr.currentID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, key, []byte(kr.currentID))
+	return wrappedKeyPrefix + kr.currentID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Unwrap decodes a key stored by Wrap, or a plain base64 key stored without a KEK.
+func (kr *KeyRing) Unwrap(stored string) ([]byte, error) {
+	if !strings.HasPrefix(stored, wrappedKeyPrefix) {
+		key, err := base64.StdEncoding.DecodeString(stored)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode encryption key: %w", err)
+		}
+		return key, nil
+	}
+
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(stored, wrappedKeyPrefix), ":")
+	if !ok {
+		return nil, errInvalidWrappedKey
+	}
+	if kr == nil || kr.keks[id] == nil {
+		return nil, fmt.Errorf("%w %s", ErrUnknownKEK, id)
+	}
+	aead := kr.keks[id]
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, errInvalidWrappedKey
+	}
+	key, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidWrappedKey, err)
+	}
+	return key, nil
+}
+
+// NeedsRewrap reports whether a stored key is not wrapped with the current KEK. It is always
+// false for a nil key ring.
+func (kr *KeyRing) NeedsRewrap(stored string) bool {
+	if kr == nil {
+		return false
+	}
+	return !strings.HasPrefix(stored, wrappedKeyPrefix+kr.currentID+":")
+}
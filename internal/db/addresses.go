@@ -0,0 +1,159 @@
+package db
+
+import (
+	"SafeTransfer/internal/model"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// addressColumns lists the columns holding Ethereum addresses that no unique index covers.
+var addressColumns = []struct{ table, column string }{
+	{"logical_files", "ethereum_address"},
+	{"files", "ethereum_address"},
+	{"shares", "granted_by"},
+	{"api_keys", "ethereum_address"},
+	{"chain_events", "owner"},
+}
+
+// canonicalizeAddresses rewrites every stored address in its checksummed form. Rows that only
+// differed in the casing of their address used to belong to different accounts; they are merged,
+// so that the account keeps everything any of its spellings owned.
+func canonicalizeAddresses(tx *gorm.DB) error {
+	if err := mergeUsers(tx); err != nil {
+		return fmt.Errorf("failed to canonicalize user addresses: %w", err)
+	}
+	if err := mergeFolders(tx); err != nil {
+		return fmt.Errorf("failed to canonicalize folder addresses: %w", err)
+	}
+	if err := mergeShares(tx); err != nil {
+		return fmt.Errorf("failed to canonicalize share addresses: %w", err)
+	}
+	for _, c := range addressColumns {
+		var addresses []string
+		if err := tx.Table(c.table).Distinct(c.column).Pluck(c.column, &addresses).Error; err != nil {
+			return fmt.Errorf("failed to read %s.%s: %w", c.table, c.column, err)
+		}
+		for _, address := range addresses {
+			if canonical := model.CanonicalAddress(address); canonical != address {
+				err := tx.Table(c.table).Where(c.column+" = ?", address).Update(c.column, canonical).Error
+				if err != nil {
+					return fmt.Errorf("failed to canonicalize %s.%s: %w", c.table, c.column, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// mergeUsers gives every user their checksummed address. Of the users that share one, the one
+// already stored under it, or else the oldest, is kept with the usage of all of them, and stays
+// disabled if any of them was.
+func mergeUsers(tx *gorm.DB) error {
+	var users []model.User
+	if err := tx.Unscoped().Order("id").Find(&users).Error; err != nil {
+		return err
+	}
+	kept := make(map[string]*model.User)
+	for i := range users {
+		canonical := model.CanonicalAddress(users[i].EthereumAddress)
+		if _, ok := kept[canonical]; !ok || users[i].EthereumAddress == canonical {
+			kept[canonical] = &users[i]
+		}
+	}
+	for i := range users {
+		user := &users[i]
+		keeper := kept[model.CanonicalAddress(user.EthereumAddress)]
+		if keeper == user {
+			continue
+		}
+		keeper.BytesStored += user.BytesStored
+		keeper.FileCount += user.FileCount
+		if keeper.DisabledAt == nil {
+			keeper.DisabledAt = user.DisabledAt
+		}
+		if err := tx.Unscoped().Delete(&model.User{}, user.ID).Error; err != nil {
+			return err
+		}
+	}
+	for canonical, user := range kept {
+		err := tx.Unscoped().Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"ethereum_address": canonical,
+			"bytes_stored":     user.BytesStored,
+			"file_count":       user.FileCount,
+			"disabled_at":      user.DisabledAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeFolders gives every folder its owner's checksummed address. A folder whose path the owner
+// already has under that address is merged into the existing one, parents before their
+// subfolders, so that the subfolders are merged in turn.
+func mergeFolders(tx *gorm.DB) error {
+	var folders []model.Folder
+	if err := tx.Unscoped().Order("length(path), id").Find(&folders).Error; err != nil {
+		return err
+	}
+	for _, folder := range folders {
+		canonical := model.CanonicalAddress(folder.EthereumAddress)
+		if canonical == folder.EthereumAddress {
+			continue
+		}
+
+		var existing model.Folder
+		err := tx.Unscoped().Where("ethereum_address = ? AND path = ?", canonical, folder.Path).Limit(1).Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing.ID == 0 {
+			if err := tx.Unscoped().Model(&model.Folder{}).Where("id = ?", folder.ID).Update("ethereum_address", canonical).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Unscoped().Model(&model.Folder{}).Where("parent_id = ?", folder.ID).Update("parent_id", existing.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&model.File{}).Where("folder_id = ?", folder.ID).Update("folder_id", existing.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&model.Folder{}, folder.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeShares gives every grantee their checksummed address, dropping grants that the grantee
+// already has under that address.
+func mergeShares(tx *gorm.DB) error {
+	var shares []model.Share
+	if err := tx.Unscoped().Order("id").Find(&shares).Error; err != nil {
+		return err
+	}
+	for _, share := range shares {
+		canonical := model.CanonicalAddress(share.GranteeAddress)
+		if canonical == share.GranteeAddress {
+			continue
+		}
+
+		var count int64
+		err := tx.Unscoped().Model(&model.Share{}).Where("logical_file_id = ? AND grantee_address = ?", share.LogicalFileID, canonical).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			err = tx.Unscoped().Delete(&model.Share{}, share.ID).Error
+		} else {
+			err = tx.Unscoped().Model(&model.Share{}).Where("id = ?", share.ID).Update("grantee_address", canonical).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,7 +1,7 @@
 package db
 
 import (
-	"SafeTransfer/internal/model"
+	"context"
 	"fmt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -13,16 +13,15 @@ type Database struct {
 	*gorm.DB
 }
 
-// NewDatabase creates a new database connection.
+// NewDatabase creates a new database connection. It does not touch the schema, which is managed
+// by Migrate.
 func NewDatabase(dataSourceName string) (*Database, error) {
-	db, err := gorm.Open(postgres.Open(dataSourceName), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dataSourceName), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
-
-	// Automatically migrate your schema, ideally in development environments only.
-	if err := db.AutoMigrate(&model.File{}, &model.User{}); err != nil {
-		log.Fatalf("Failed to auto-migrate database schema: %v", err)
+	if err := registerInstrumentation(db); err != nil {
+		return nil, fmt.Errorf("failed to instrument database: %w", err)
 	}
 
 	log.Println("Successfully connected to database")
@@ -37,3 +36,15 @@ func (d *Database) Close() error {
 	}
 	return sqlDB.Close()
 }
+
+// Ping checks that the database is reachable.
+func (d *Database) Ping(ctx context.Context) error {
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return err
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to reach database: %w", err)
+	}
+	return nil
+}
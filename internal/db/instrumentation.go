@@ -0,0 +1,90 @@
+package db
+
+import (
+	"SafeTransfer/internal/metrics"
+	"SafeTransfer/internal/tracing"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	queryStartKey = "instrumentation:start"
+	querySpanKey  = "instrumentation:span"
+)
+
+var tracer = otel.Tracer("SafeTransfer/internal/db")
+
+// callbackRegisterer is a position in one of gorm's callback chains.
+type callbackRegisterer interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// registerInstrumentation times every query made through db by operation and table, and traces
+// it as a child of the span in the context the query was made with.
+func registerInstrumentation(db *gorm.DB) error {
+	callbacks := db.Callback()
+	chains := []struct {
+		operation     string
+		before, after callbackRegisterer
+	}{
+		{"create", callbacks.Create().Before("gorm:create"), callbacks.Create().After("gorm:create")},
+		{"query", callbacks.Query().Before("gorm:query"), callbacks.Query().After("gorm:query")},
+		{"update", callbacks.Update().Before("gorm:update"), callbacks.Update().After("gorm:update")},
+		{"delete", callbacks.Delete().Before("gorm:delete"), callbacks.Delete().After("gorm:delete")},
+		{"row", callbacks.Row().Before("gorm:row"), callbacks.Row().After("gorm:row")},
+		{"raw", callbacks.Raw().Before("gorm:raw"), callbacks.Raw().After("gorm:raw")},
+	}
+	for _, chain := range chains {
+		if err := chain.before.Register("instrumentation:before_"+chain.operation, startQuery(chain.operation)); err != nil {
+			return err
+		}
+		if err := chain.after.Register("instrumentation:after_"+chain.operation, finishQuery(chain.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startQuery(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		_, span := tracer.Start(tx.Statement.Context, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperation(operation)),
+		)
+		tx.InstanceSet(querySpanKey, span)
+		tx.InstanceSet(queryStartKey, time.Now())
+	}
+}
+
+func finishQuery(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		start, ok := tx.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		table := tx.Statement.Table
+		metrics.Since(metrics.DBQueryDuration.WithLabelValues(operation, table), start.(time.Time))
+		err := tx.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		if err != nil {
+			metrics.DBErrors.WithLabelValues(operation, table).Inc()
+		}
+
+		if value, ok := tx.InstanceGet(querySpanKey); ok {
+			span := value.(trace.Span)
+			if table != "" {
+				span.SetName("db." + operation + " " + table)
+			}
+			// The statement has placeholders rather than the values of its arguments.
+			span.SetAttributes(semconv.DBSQLTable(table), semconv.DBStatement(tx.Statement.SQL.String()))
+			tracing.End(span, err)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package db
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID identifies the advisory lock that serializes migrations, so that several
+// servers starting at once do not apply the same migration twice.
+const migrationLockID = 4_711_473_160
+
+var (
+	ErrSchemaTooNew   = errors.New("database schema is newer than this version of SafeTransfer")
+	ErrSchemaOutdated = errors.New("database schema is out of date, run the migrate command")
+)
+
+// Migration is a versioned change to the schema, with the SQL that applies and reverts it. They
+// are embedded from migrations/<version>_<name>.up.sql and .down.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	UpData  func(tx *gorm.DB) error // run after Up, for changes to the data that SQL cannot make
+}
+
+// dataMigrations holds the UpData of the migrations that have one, by version.
+var dataMigrations = map[int]func(tx *gorm.DB) error{
+	2: canonicalizeAddresses,
+}
+
+// MigrationStatus describes a migration and whether it has been applied.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // nil for pending migrations
+	Unknown   bool       // applied by a newer version of SafeTransfer
+}
+
+// schemaMigration is a row of the status table, recording an applied migration.
+type schemaMigration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migrations returns the embedded migrations, ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		sql, err := fs.ReadFile(migrationFiles, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration: %w", err)
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(sql)
+		} else {
+			migration.Down = string(sql)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d must have both an up and a down file", migration.Version)
+		}
+		migrations[i].UpData = dataMigrations[migration.Version]
+	}
+	return migrations, nil
+}
+
+// LatestVersion returns the version of the newest embedded migration.
+func LatestVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	return len(migrations), nil
+}
+
+// appliedMigrations returns the migrations recorded in the status table, ordered by version.
+func (d *Database) appliedMigrations() ([]schemaMigration, error) {
+	if !d.Migrator().HasTable(schemaMigration{}) {
+		return nil, nil
+	}
+	var applied []schemaMigration
+	if err := d.Order("version").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// SchemaVersion returns the version of the newest applied migration, 0 for an empty database.
+func (d *Database) SchemaVersion() (int, error) {
+	applied, err := d.appliedMigrations()
+	if err != nil || len(applied) == 0 {
+		return 0, err
+	}
+	return applied[len(applied)-1].Version, nil
+}
+
+// CheckSchema returns ErrSchemaTooNew if the database has migrations applied that this version
+// does not know, and ErrSchemaOutdated if it lacks some of the embedded migrations.
+func (d *Database) CheckSchema() error {
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	current, err := d.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case current > latest:
+		return fmt.Errorf("%w: version %d, this version supports up to %d", ErrSchemaTooNew, current, latest)
+	case current < latest:
+		return fmt.Errorf("%w: version %d, this version requires %d", ErrSchemaOutdated, current, latest)
+	}
+	return nil
+}
+
+// MigrationStatus lists the embedded migrations and any unknown applied ones, ordered by version.
+func (d *Database) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := d.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, migration := range migrations {
+		statuses[i] = MigrationStatus{Version: migration.Version, Name: migration.Name}
+	}
+	for _, row := range applied {
+		row := row
+		if row.Version <= len(statuses) {
+			statuses[row.Version-1].AppliedAt = &row.AppliedAt
+			continue
+		}
+		statuses = append(statuses, MigrationStatus{Version: row.Version, Name: row.Name, AppliedAt: &row.AppliedAt, Unknown: true})
+	}
+	return statuses, nil
+}
+
+// Migrate applies all pending migrations and returns them.
+func (d *Database) Migrate() ([]Migration, error) {
+	latest, err := LatestVersion()
+	if err != nil {
+		return nil, err
+	}
+	return d.MigrateTo(latest)
+}
+
+// MigrateTo applies or reverts migrations, one transaction each, until the schema is at version
+// target, and returns the migrations applied or reverted in that order. Version 0 reverts all
+// of them. It fails with ErrSchemaTooNew if the schema is newer than the embedded migrations.
+func (d *Database) MigrateTo(target int) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	if target < 0 || target > len(migrations) {
+		return nil, fmt.Errorf("unknown schema version %d, this version supports up to %d", target, len(migrations))
+	}
+
+	err = d.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration status table: %w", err)
+	}
+	current, err := d.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if current > len(migrations) {
+		return nil, fmt.Errorf("%w: version %d, this version supports up to %d", ErrSchemaTooNew, current, len(migrations))
+	}
+
+	var done []Migration
+	for current < target {
+		migration := migrations[current]
+		if err := d.step(migration, true); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+		current++
+	}
+	for current > target {
+		migration := migrations[current-1]
+		if err := d.step(migration, false); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+		current--
+	}
+	return done, nil
+}
+
+// step applies or reverts a single migration and records it in the status table. Another
+// process may have done so since the schema version was read, in which case it does nothing.
+func (d *Database) step(migration Migration, up bool) error {
+	err := d.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockID).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&schemaMigration{}).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+			return err
+		}
+		if applied := count > 0; applied == up {
+			return nil
+		}
+
+		if up {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			if migration.UpData != nil {
+				if err := migration.UpData(tx); err != nil {
+					return err
+				}
+			}
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		}
+		if err := tx.Exec(migration.Down).Error; err != nil {
+			return err
+		}
+		return tx.Where("version = ?", migration.Version).Delete(&schemaMigration{}).Error
+	})
+	if err != nil {
+		direction := "apply"
+		if !up {
+			direction = "revert"
+		}
+		return fmt.Errorf("failed to %s migration %d_%s: %w", direction, migration.Version, migration.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS shares;
+DROP TABLE IF EXISTS chain_cursors;
+DROP TABLE IF EXISTS chain_events;
+DROP TABLE IF EXISTS files;
+DROP TABLE IF EXISTS logical_files;
+DROP TABLE IF EXISTS folders;
+DROP TABLE IF EXISTS users;
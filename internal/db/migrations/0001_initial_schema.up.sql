@@ -0,0 +1,184 @@
+-- The schema as created by gorm's AutoMigrate before versioned migrations were introduced.
+-- Every statement is conditional, so that databases created that way are adopted as version 1,
+-- whichever release created them: columns added since the first release are added when missing,
+-- and files stored before versioning become the first version of a logical file of their own.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    ethereum_address text,
+    nonce text,
+    role varchar(16) NOT NULL DEFAULT 'user',
+    disabled_at timestamptz,
+    bytes_stored bigint NOT NULL DEFAULT 0,
+    file_count bigint NOT NULL DEFAULT 0,
+    quota_bytes bigint,
+    quota_files bigint
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role varchar(16) NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bytes_stored bigint NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS file_count bigint NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quota_bytes bigint;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quota_files bigint;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_ethereum_address ON users (ethereum_address);
+
+CREATE TABLE IF NOT EXISTS folders (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    ethereum_address varchar(255),
+    parent_id bigint,
+    name varchar(255),
+    path text
+);
+CREATE INDEX IF NOT EXISTS idx_folders_deleted_at ON folders (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_folders_owner_path ON folders (ethereum_address, path);
+CREATE INDEX IF NOT EXISTS idx_folders_parent_id ON folders (parent_id);
+
+CREATE TABLE IF NOT EXISTS logical_files (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    ethereum_address varchar(255),
+    latest_version bigint,
+    max_versions bigint
+);
+CREATE INDEX IF NOT EXISTS idx_logical_files_deleted_at ON logical_files (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_logical_files_ethereum_address ON logical_files (ethereum_address);
+
+CREATE TABLE IF NOT EXISTS files (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    cid varchar(255),
+    ethereum_address varchar(255),
+    logical_file_id bigint,
+    version bigint,
+    is_latest boolean DEFAULT true,
+    folder_id bigint,
+    name varchar(255),
+    description text,
+    tags text[],
+    size bigint,
+    mime_type varchar(255),
+    encryption_key varchar(255),
+    nonce varchar(255),
+    signature text,
+    public_key text,
+    file_hash varchar(64),
+    chain_tx_hash varchar(66),
+    chain_status varchar(16),
+    chain_error text,
+    search_vector tsvector
+);
+ALTER TABLE files ADD COLUMN IF NOT EXISTS logical_file_id bigint;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS version bigint;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS is_latest boolean DEFAULT true;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS folder_id bigint;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS name varchar(255);
+ALTER TABLE files ADD COLUMN IF NOT EXISTS description text;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS tags text[];
+ALTER TABLE files ADD COLUMN IF NOT EXISTS size bigint;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS mime_type varchar(255);
+ALTER TABLE files ADD COLUMN IF NOT EXISTS file_hash varchar(64);
+ALTER TABLE files ADD COLUMN IF NOT EXISTS chain_tx_hash varchar(66);
+ALTER TABLE files ADD COLUMN IF NOT EXISTS chain_status varchar(16);
+ALTER TABLE files ADD COLUMN IF NOT EXISTS chain_error text;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+-- Files stored before versioning have no logical file yet
+DO $$
+DECLARE
+    file record;
+    logical_file bigint;
+BEGIN
+    FOR file IN SELECT id, created_at, deleted_at, ethereum_address FROM files WHERE logical_file_id IS NULL ORDER BY id LOOP
+        INSERT INTO logical_files (created_at, updated_at, deleted_at, ethereum_address, latest_version, max_versions)
+        VALUES (file.created_at, file.created_at, file.deleted_at, file.ethereum_address, 1, 0)
+        RETURNING id INTO logical_file;
+        UPDATE files SET logical_file_id = logical_file, version = 1, is_latest = true WHERE id = file.id;
+    END LOOP;
+END $$;
+
+UPDATE files SET search_vector =
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(array_to_string(tags, ' '), '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+WHERE search_vector IS NULL;
+
+-- Usage is counted from the stored files, which also covers databases that predate quotas
+UPDATE users SET
+    bytes_stored = coalesce((SELECT sum(coalesce(size, 0)) FROM files WHERE files.ethereum_address = users.ethereum_address AND files.deleted_at IS NULL), 0),
+    file_count = (SELECT count(*) FROM files WHERE files.ethereum_address = users.ethereum_address AND files.deleted_at IS NULL);
+CREATE INDEX IF NOT EXISTS idx_files_deleted_at ON files (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_files_cid ON files (cid);
+CREATE INDEX IF NOT EXISTS idx_files_ethereum_address ON files (ethereum_address);
+CREATE INDEX IF NOT EXISTS idx_files_logical_file_id ON files (logical_file_id);
+CREATE INDEX IF NOT EXISTS idx_files_is_latest ON files (is_latest);
+CREATE INDEX IF NOT EXISTS idx_files_folder_id ON files (folder_id);
+CREATE INDEX IF NOT EXISTS idx_files_tags ON files USING gin (tags);
+CREATE INDEX IF NOT EXISTS idx_files_mime_type ON files (mime_type);
+CREATE INDEX IF NOT EXISTS idx_files_file_hash ON files (file_hash);
+CREATE INDEX IF NOT EXISTS idx_files_chain_tx_hash ON files (chain_tx_hash);
+CREATE INDEX IF NOT EXISTS idx_files_search_vector ON files USING gin (search_vector);
+
+CREATE TABLE IF NOT EXISTS chain_events (
+    id bigserial PRIMARY KEY,
+    contract_address varchar(42) NOT NULL,
+    block_number bigint NOT NULL,
+    log_index bigint NOT NULL,
+    block_hash varchar(66) NOT NULL,
+    tx_hash varchar(66) NOT NULL,
+    owner varchar(42) NOT NULL,
+    cid varchar(255) NOT NULL,
+    file_hash varchar(255) NOT NULL,
+    confirmed boolean NOT NULL DEFAULT false,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chain_events_position ON chain_events (contract_address, block_number, log_index);
+CREATE INDEX IF NOT EXISTS idx_chain_events_owner ON chain_events (owner);
+CREATE INDEX IF NOT EXISTS idx_chain_events_cid ON chain_events (cid);
+CREATE INDEX IF NOT EXISTS idx_chain_events_file_hash ON chain_events (file_hash);
+
+CREATE TABLE IF NOT EXISTS chain_cursors (
+    contract_address varchar(42) PRIMARY KEY,
+    last_block bigint NOT NULL,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS shares (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    logical_file_id bigint NOT NULL,
+    grantee_address varchar(42) NOT NULL,
+    granted_by varchar(42) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_shares_deleted_at ON shares (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shares_file_grantee ON shares (logical_file_id, grantee_address);
+CREATE INDEX IF NOT EXISTS idx_shares_grantee_address ON shares (grantee_address);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    ethereum_address varchar(42) NOT NULL,
+    name varchar(100) NOT NULL,
+    prefix varchar(16) NOT NULL,
+    key_hash varchar(64) NOT NULL,
+    scopes text[],
+    expires_at timestamptz,
+    last_used_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_deleted_at ON api_keys (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_api_keys_ethereum_address ON api_keys (ethereum_address);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys (prefix);
@@ -0,0 +1,3 @@
+-- Older versions look shares up by the grantee's address in lower case. The other addresses stay
+-- checksummed, which is one of the forms those versions already stored.
+UPDATE shares SET grantee_address = lower(grantee_address);
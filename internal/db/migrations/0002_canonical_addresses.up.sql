@@ -0,0 +1,3 @@
+-- Ethereum addresses used to be stored as clients sent them, shares in lower case. They are
+-- rewritten in their checksummed form by canonicalizeAddresses in addresses.go, since Postgres
+-- cannot compute the Keccak-256 hash that the checksum is based on.
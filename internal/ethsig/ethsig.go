@@ -0,0 +1,99 @@
+// Package ethsig parses and recovers the secp256k1 signatures made by Ethereum wallets.
+package ethsig
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Errors are grouped so that callers can tell a request that is malformed from a signature
+// that is well-formed but not acceptable: every error returned by this package matches either
+// ErrMalformedSignature or ErrInvalidSignature.
+var (
+	ErrMalformedSignature = errors.New("malformed signature")
+	ErrInvalidSignature   = errors.New("invalid signature")
+
+	ErrSignatureEncoding      = fmt.Errorf("%w: signature must be hex-encoded", ErrMalformedSignature)
+	ErrSignatureLength        = fmt.Errorf("%w: signature must be %d bytes", ErrMalformedSignature, crypto.SignatureLength)
+	ErrRecoveryID             = fmt.Errorf("%w: V must be 0, 1, 27, 28 or an EIP-155 value", ErrMalformedSignature)
+	ErrMalleableSignature     = fmt.Errorf("%w: S must be in the lower half of the curve order", ErrInvalidSignature)
+	ErrUnrecoverableSignature = fmt.Errorf("%w: no public key can be recovered", ErrInvalidSignature)
+)
+
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// Decode decodes a hex-encoded signature, with or without the 0x prefix, without checking its
+// length. Signatures of contract wallets may have any length.
+func Decode(signature string) ([]byte, error) {
+	sigBytes, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(signature, "0x"), "0X"))
+	if err != nil || len(sigBytes) == 0 {
+		return nil, ErrSignatureEncoding
+	}
+	return sigBytes, nil
+}
+
+// Parse decodes a 65-byte [R || S || V] signature and returns it with V normalized to the
+// recovery ID 0 or 1. V may be given as the recovery ID itself, as 27 or 28, or in the EIP-155
+// form chainId*2 + 35 + recoveryID; the chain ID is not checked.
+func Parse(signature string) ([]byte, error) {
+	sigBytes, err := Decode(signature)
+	if err != nil {
+		return nil, err
+	}
+	if len(sigBytes) != crypto.SignatureLength {
+		return nil, ErrSignatureLength
+	}
+
+	v := sigBytes[crypto.RecoveryIDOffset]
+	switch {
+	case v == 0 || v == 1:
+	case v == 27 || v == 28:
+		v -= 27
+	case v >= 35:
+		v = (v - 35) % 2
+	default:
+		return nil, ErrRecoveryID
+	}
+	sigBytes[crypto.RecoveryIDOffset] = v
+
+	r := new(big.Int).SetBytes(sigBytes[:32])
+	s := new(big.Int).SetBytes(sigBytes[32:64])
+	if r.Sign() == 0 || s.Sign() == 0 || r.Cmp(secp256k1N) >= 0 {
+		return nil, ErrUnrecoverableSignature
+	}
+	// A high S can be replaced by N - S to produce a second valid signature for the same digest
+	if s.Cmp(secp256k1HalfN) > 0 {
+		return nil, ErrMalleableSignature
+	}
+	return sigBytes, nil
+}
+
+// Recover returns the address that signed digest.
+func Recover(digest []byte, signature string) (common.Address, error) {
+	sigBytes, err := Parse(signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	publicKey, err := crypto.SigToPub(digest, sigBytes)
+	if err != nil {
+		return common.Address{}, ErrUnrecoverableSignature
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
+
+// RecoverMessage returns the address that signed message as an EIP-191 personal message, as
+// wallets do for personal_sign.
+func RecoverMessage(message []byte, signature string) (common.Address, error) {
+	return Recover(accounts.TextHash(message), signature)
+}
@@ -0,0 +1,146 @@
+// Package metrics defines the Prometheus metrics of the server, which are served at /metrics.
+// They are registered in Registry rather than the global registry, so that only SafeTransfer's
+// own metrics and the Go runtime and process collectors are exposed.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "safetransfer"
+
+// Registry holds every metric of the server.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+// transferBuckets cover operations whose duration grows with the size of a file, from 5ms to
+// about 80s.
+var transferBuckets = prometheus.ExponentialBuckets(0.005, 2, 15)
+
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time to serve HTTP requests by route, method and status code.",
+		Buckets:   transferBuckets,
+	}, []string{"route", "method", "status"})
+
+	BytesUploaded = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploaded_bytes_total",
+		Help:      "Bytes of file content stored, before encryption.",
+	})
+
+	BytesDownloaded = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloaded_bytes_total",
+		Help:      "Bytes of file content decrypted for downloads.",
+	})
+
+	ActiveTransfers = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_transfers",
+		Help:      "Uploads and downloads in progress.",
+	}, []string{"direction"})
+
+	CipherDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cipher_duration_seconds",
+		Help:      "Time spent encrypting or decrypting a file, excluding reading and writing it.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"operation"})
+
+	IPFSRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ipfs_request_duration_seconds",
+		Help:      "Time until the IPFS API responds, by operation. Adds include streaming the content.",
+		Buckets:   transferBuckets,
+	}, []string{"operation"})
+
+	IPFSErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ipfs_errors_total",
+		Help:      "Failed IPFS API requests by operation.",
+	}, []string{"operation"})
+
+	DBQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time to run database queries by operation and table.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"operation", "table"})
+
+	DBErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_errors_total",
+		Help:      "Failed database queries by operation and table, not counting missing records.",
+	}, []string{"operation", "table"})
+
+	AuthAttempts = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_attempts_total",
+		Help:      "Authentication attempts by method, result and, for failures, the problem code.",
+	}, []string{"method", "result", "reason"})
+)
+
+// Directions of ActiveTransfers.
+const (
+	Upload   = "upload"
+	Download = "download"
+)
+
+// Authentication methods of AuthAttempts.
+const (
+	AuthJWT       = "jwt"
+	AuthAPIKey    = "api_key"
+	AuthSignature = "signature"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Since observes the time elapsed since start in seconds.
+func Since(observer prometheus.Observer, start time.Time) {
+	observer.Observe(time.Since(start).Seconds())
+}
+
+// ObserveCipher returns a function observing the time spent in the cipher for operation,
+// "encrypt" or "decrypt".
+func ObserveCipher(operation string) func(elapsed time.Duration) {
+	observer := CipherDuration.WithLabelValues(operation)
+	return func(elapsed time.Duration) {
+		observer.Observe(elapsed.Seconds())
+	}
+}
+
+// StartTransfer counts a transfer as active until the returned function is called.
+func StartTransfer(direction string) (done func()) {
+	gauge := ActiveTransfers.WithLabelValues(direction)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// AuthSucceeded counts a successful authentication.
+func AuthSucceeded(method string) {
+	AuthAttempts.WithLabelValues(method, "success", "").Inc()
+}
+
+// AuthFailed counts a failed authentication, with the code of the problem it was rejected with.
+func AuthFailed(method, reason string) {
+	AuthAttempts.WithLabelValues(method, "failure", reason).Inc()
+}
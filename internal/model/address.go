@@ -0,0 +1,13 @@
+package model
+
+import "github.com/ethereum/go-ethereum/common"
+
+// CanonicalAddress returns an Ethereum address in its EIP-55 checksummed form, the one form in
+// which addresses are stored and compared, so that the same account is never told apart by the
+// casing a client sent. Strings that are not addresses are returned as they are, and match none.
+func CanonicalAddress(address string) string {
+	if !common.IsHexAddress(address) {
+		return address
+	}
+	return common.HexToAddress(address).Hex()
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey lets a user's scripts and CI pipelines call the API without a wallet. Only a hash of
+// the key is stored; the prefix identifies the key in listings and when it is presented.
+type APIKey struct {
+	gorm.Model
+	EthereumAddress string     `gorm:"column:ethereum_address;type:varchar(42);not null;index"`
+	Name            string     `gorm:"column:name;type:varchar(100);not null"`
+	Prefix          string     `gorm:"column:prefix;type:varchar(16);not null;uniqueIndex"`
+	KeyHash         string     `gorm:"column:key_hash;type:varchar(64);not null"` // hex-encoded SHA-256 of the whole key
+	Scopes          Tags       `gorm:"column:scopes;type:text[]"`
+	ExpiresAt       *time.Time `gorm:"column:expires_at"` // nil for keys that do not expire
+	LastUsedAt      *time.Time `gorm:"column:last_used_at"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// ChainEvent is a FileRegistered log emitted by the FileRegistry contract. Events stay
+// unconfirmed until enough blocks have been built on top of theirs, and are replaced while
+// unconfirmed if the chain reorganizes.
+type ChainEvent struct {
+	ID              uint   `gorm:"primarykey"`
+	ContractAddress string `gorm:"column:contract_address;type:varchar(42);not null;uniqueIndex:idx_chain_events_position,priority:1"`
+	BlockNumber     uint64 `gorm:"column:block_number;not null;uniqueIndex:idx_chain_events_position,priority:2"`
+	LogIndex        uint   `gorm:"column:log_index;not null;uniqueIndex:idx_chain_events_position,priority:3"`
+	BlockHash       string `gorm:"column:block_hash;type:varchar(66);not null"`
+	TxHash          string `gorm:"column:tx_hash;type:varchar(66);not null"`
+	Owner           string `gorm:"column:owner;type:varchar(42);not null;index"`
+	CID             string `gorm:"column:cid;type:varchar(255);not null;index"`
+	FileHash        string `gorm:"column:file_hash;type:varchar(255);not null;index"`
+	Confirmed       bool   `gorm:"column:confirmed;not null;default:false"`
+	CreatedAt       time.Time
+}
+
+// ChainCursor records the last block the indexer has scanned for a contract.
+type ChainCursor struct {
+	ContractAddress string `gorm:"column:contract_address;type:varchar(42);primaryKey"`
+	LastBlock       uint64 `gorm:"column:last_block;not null"`
+	UpdatedAt       time.Time
+}
@@ -6,8 +6,25 @@ type File struct {
 	gorm.Model
 	CID             string `gorm:"column:cid;type:varchar(255);uniqueIndex"`
 	EthereumAddress string `gorm:"column:ethereum_address;type:varchar(255);index"`
+	LogicalFileID   *uint  `gorm:"column:logical_file_id;index"`
+	Version         int    `gorm:"column:version"`
+	IsLatest        bool   `gorm:"column:is_latest;default:true;index"`
+	FolderID        *uint  `gorm:"column:folder_id;index"`
+	Name            string `gorm:"column:name;type:varchar(255)"`
+	Description     string `gorm:"column:description;type:text"`
+	Tags            Tags   `gorm:"column:tags;type:text[];index:idx_files_tags,type:gin"`
+	Size            int64  `gorm:"column:size"`
+	MimeType        string `gorm:"column:mime_type;type:varchar(255);index"`
 	EncryptionKey   string `gorm:"column:encryption_key;type:varchar(255)"`
 	Nonce           string `gorm:"column:nonce;type:varchar(255)"`
 	Signature       string `gorm:"column:signature;type:text"`
 	PublicKey       string `gorm:"column:public_key;type:text"`
+	FileHash        string `gorm:"column:file_hash;type:varchar(64);index"` // SHA-256 of the plaintext, empty for files uploaded before it was recorded
+	ChainTxHash     string `gorm:"column:chain_tx_hash;type:varchar(66);index"`
+	ChainStatus     string `gorm:"column:chain_status;type:varchar(16)"`
+	ChainError      string `gorm:"column:chain_error;type:text"`
+
+	// SearchVector is maintained by the repository from Name, Description and Tags
+	// and is only ever read inside search queries.
+	SearchVector string `gorm:"column:search_vector;type:tsvector;index:idx_files_search_vector,type:gin;->:false;<-:false"`
 }
@@ -0,0 +1,13 @@
+package model
+
+import "gorm.io/gorm"
+
+// Folder is a node in an owner's folder tree. Top-level folders have no parent.
+// Path is the slash-separated chain of folder names from the root and is unique per owner.
+type Folder struct {
+	gorm.Model
+	EthereumAddress string `gorm:"column:ethereum_address;type:varchar(255);uniqueIndex:idx_folders_owner_path,priority:1"`
+	ParentID        *uint  `gorm:"column:parent_id;index"`
+	Name            string `gorm:"column:name;type:varchar(255)"`
+	Path            string `gorm:"column:path;type:text;uniqueIndex:idx_folders_owner_path,priority:2"`
+}
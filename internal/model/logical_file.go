@@ -0,0 +1,12 @@
+package model
+
+import "gorm.io/gorm"
+
+// LogicalFile groups the successive versions of a document under a stable ID.
+// Each version is a File row pointing back at its LogicalFile.
+type LogicalFile struct {
+	gorm.Model
+	EthereumAddress string `gorm:"column:ethereum_address;type:varchar(255);index"`
+	LatestVersion   int    `gorm:"column:latest_version"`
+	MaxVersions     int    `gorm:"column:max_versions"` // 0 uses the server-wide retention limit
+}
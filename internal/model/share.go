@@ -0,0 +1,11 @@
+package model
+
+import "gorm.io/gorm"
+
+// Share grants another user read access to every version of a logical file.
+type Share struct {
+	gorm.Model
+	LogicalFileID  uint   `gorm:"not null;uniqueIndex:idx_shares_file_grantee,priority:1"`
+	GranteeAddress string `gorm:"type:varchar(42);not null;uniqueIndex:idx_shares_file_grantee,priority:2;index"` // lower-case Ethereum address
+	GrantedBy      string `gorm:"type:varchar(42);not null"`
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// Tags is a list of user-defined labels stored in a Postgres text[] column.
+type Tags []string
+
+// GormDataType tells gorm to create the column as a text array.
+func (Tags) GormDataType() string {
+	return "text[]"
+}
+
+// Value encodes the tags as a Postgres array literal.
+func (t Tags) Value() (driver.Value, error) {
+	if t == nil {
+		return "{}", nil
+	}
+	quoted := make([]string, len(t))
+	for i, tag := range t {
+		tag = strings.ReplaceAll(tag, `\`, `\\`)
+		tag = strings.ReplaceAll(tag, `"`, `\"`)
+		quoted[i] = `"` + tag + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}", nil
+}
+
+// Scan decodes a one-dimensional Postgres array literal into the tags.
+func (t *Tags) Scan(src interface{}) error {
+	var literal string
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case string:
+		literal = v
+	case []byte:
+		literal = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Tags", src)
+	}
+
+	if len(literal) < 2 || literal[0] != '{' || literal[len(literal)-1] != '}' {
+		return fmt.Errorf("invalid array literal: %q", literal)
+	}
+	body := literal[1 : len(literal)-1]
+
+	tags := Tags{}
+	var current strings.Builder
+	inQuotes, quoted := false, false
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '\\' && i+1 < len(body):
+			i++
+			current.WriteByte(body[i])
+		case c == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case c == ',' && !inQuotes:
+			tags = append(tags, current.String())
+			current.Reset()
+			quoted = false
+		default:
+			current.WriteByte(c)
+		}
+	}
+	if current.Len() > 0 || quoted || len(tags) > 0 {
+		tags = append(tags, current.String())
+	}
+
+	*t = tags
+	return nil
+}
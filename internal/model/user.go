@@ -3,11 +3,35 @@
 package model
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// Roles a user can have. Auditors can read everything under /admin; admins can also change it.
+const (
+	RoleUser    = "user"
+	RoleAuditor = "auditor"
+	RoleAdmin   = "admin"
+)
+
 type User struct {
 	gorm.Model
 	EthereumAddress string `gorm:"uniqueIndex"` // Unique Ethereum address of the user
 	Nonce           string
+	Role            string     `gorm:"type:varchar(16);not null;default:'user'"` // RoleUser, RoleAuditor or RoleAdmin
+	DisabledAt      *time.Time // When an admin disabled the account; disabled users cannot sign in or use their tokens
+	BytesStored     int64      `gorm:"not null;default:0"` // Total size of the user's stored file versions
+	FileCount       int64      `gorm:"not null;default:0"` // Number of the user's stored file versions
+	QuotaBytes      *int64     // Per-user override of the default byte quota; 0 means unlimited
+	QuotaFiles      *int64     // Per-user override of the default file count quota; 0 means unlimited
+}
+
+// ValidRole reports whether role is one of the supported roles.
+func ValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleAuditor, RoleAdmin:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"SafeTransfer/internal/db"
+	"SafeTransfer/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository defines the interface for operations on the API key entity.
+type APIKeyRepository interface {
+	SaveAPIKey(ctx context.Context, key *model.APIKey) error
+	FindAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKey, error)
+	ListAPIKeys(ctx context.Context, ethereumAddress string) ([]model.APIKey, error)
+	CountAPIKeys(ctx context.Context, ethereumAddress string) (int64, error)
+	DeleteAPIKey(ctx context.Context, ethereumAddress string, id uint) (bool, error)
+	UpdateLastUsed(ctx context.Context, id uint, lastUsedAt time.Time) error
+}
+
+// APIKeyRepositoryImpl is the concrete implementation of APIKeyRepository.
+type APIKeyRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new instance of APIKeyRepositoryImpl.
+func NewAPIKeyRepository(db *db.Database) APIKeyRepository {
+	return &APIKeyRepositoryImpl{DB: db.DB}
+}
+
+// SaveAPIKey saves a newly issued key to the database.
+func (repo *APIKeyRepositoryImpl) SaveAPIKey(ctx context.Context, key *model.APIKey) error {
+	key.EthereumAddress = model.CanonicalAddress(key.EthereumAddress)
+	return repo.DB.WithContext(ctx).Create(key).Error
+}
+
+// FindAPIKeyByPrefix retrieves the key presented with the given prefix. Revoked keys are not found.
+func (repo *APIKeyRepositoryImpl) FindAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := repo.DB.WithContext(ctx).Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys returns the user's keys that have not been revoked, oldest first.
+func (repo *APIKeyRepositoryImpl) ListAPIKeys(ctx context.Context, ethereumAddress string) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	err := repo.DB.WithContext(ctx).Where("ethereum_address = ?", model.CanonicalAddress(ethereumAddress)).Order("created_at").Find(&keys).Error
+	return keys, err
+}
+
+// CountAPIKeys returns the number of the user's keys that have not been revoked.
+func (repo *APIKeyRepositoryImpl) CountAPIKeys(ctx context.Context, ethereumAddress string) (int64, error) {
+	var count int64
+	err := repo.DB.WithContext(ctx).Model(&model.APIKey{}).Where("ethereum_address = ?", model.CanonicalAddress(ethereumAddress)).Count(&count).Error
+	return count, err
+}
+
+// DeleteAPIKey revokes one of the user's keys, reporting whether it existed. The row is kept,
+// soft-deleted, so that its prefix is never reused.
+func (repo *APIKeyRepositoryImpl) DeleteAPIKey(ctx context.Context, ethereumAddress string, id uint) (bool, error) {
+	result := repo.DB.WithContext(ctx).Where("ethereum_address = ? AND id = ?", model.CanonicalAddress(ethereumAddress), id).Delete(&model.APIKey{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateLastUsed records when a key was last presented.
+func (repo *APIKeyRepositoryImpl) UpdateLastUsed(ctx context.Context, id uint, lastUsedAt time.Time) error {
+	return repo.DB.WithContext(ctx).Model(&model.APIKey{}).Where("id = ?", id).Update("last_used_at", lastUsedAt).Error
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"SafeTransfer/internal/db"
+	"SafeTransfer/internal/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ChainEventRepository defines the interface for operations on the registry events read from
+// the chain and the cursor of the indexer that reads them.
+type ChainEventRepository interface {
+	GetCursor(ctx context.Context, contractAddress string) (uint64, bool, error)
+	SaveEvents(ctx context.Context, contractAddress string, fromBlock uint64, events []model.ChainEvent, confirmedThrough, lastBlock uint64) error
+	ConfirmFileRegistrations(ctx context.Context, contractAddress string) (int64, error)
+	ListUnmatchedEvents(ctx context.Context, contractAddress string, limit int) ([]model.ChainEvent, error)
+	ListUnregisteredFiles(ctx context.Context, contractAddress string, limit int) ([]model.File, error)
+}
+
+// ChainEventRepositoryImpl is the concrete implementation of ChainEventRepository.
+type ChainEventRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+// NewChainEventRepository creates a new instance of ChainEventRepositoryImpl.
+func NewChainEventRepository(db *db.Database) ChainEventRepository {
+	return &ChainEventRepositoryImpl{DB: db.DB}
+}
+
+// GetCursor returns the last block scanned for a contract, and false if it has never been scanned.
+func (repo *ChainEventRepositoryImpl) GetCursor(ctx context.Context, contractAddress string) (uint64, bool, error) {
+	var cursor model.ChainCursor
+	err := repo.DB.WithContext(ctx).Where("contract_address = ?", contractAddress).First(&cursor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+	return cursor.LastBlock, true, nil
+}
+
+// SaveEvents replaces the unconfirmed events from fromBlock onwards with events, which were read
+// from the chain as it is now, confirms the events up to confirmedThrough and moves the cursor
+// to lastBlock, all in one transaction.
+func (repo *ChainEventRepositoryImpl) SaveEvents(ctx context.Context, contractAddress string, fromBlock uint64, events []model.ChainEvent, confirmedThrough, lastBlock uint64) error {
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("contract_address = ? AND block_number >= ? AND NOT confirmed", contractAddress, fromBlock).
+			Delete(&model.ChainEvent{}).Error
+		if err != nil {
+			return err
+		}
+
+		if len(events) > 0 {
+			if err := tx.Create(&events).Error; err != nil {
+				return err
+			}
+		}
+
+		err = tx.Model(&model.ChainEvent{}).
+			Where("contract_address = ? AND block_number <= ? AND NOT confirmed", contractAddress, confirmedThrough).
+			Update("confirmed", true).Error
+		if err != nil {
+			return err
+		}
+
+		cursor := model.ChainCursor{ContractAddress: contractAddress, LastBlock: lastBlock}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "contract_address"}},
+			DoUpdates: clause.AssignmentColumns([]string{"last_block", "updated_at"}),
+		}).Create(&cursor).Error
+	})
+}
+
+// ConfirmFileRegistrations marks the files with a confirmed FileRegistered event as confirmed,
+// covering registrations whose receipt was never recorded. It returns the number of files updated.
+func (repo *ChainEventRepositoryImpl) ConfirmFileRegistrations(ctx context.Context, contractAddress string) (int64, error) {
+	result := repo.DB.WithContext(ctx).Exec(`UPDATE files SET chain_status = 'confirmed', chain_tx_hash = e.tx_hash, chain_error = ''
+		FROM chain_events e
+		WHERE e.cid = files.cid AND e.contract_address = ? AND e.confirmed
+			AND files.deleted_at IS NULL AND files.chain_status IS DISTINCT FROM 'confirmed'`, contractAddress)
+	return result.RowsAffected, result.Error
+}
+
+// ListUnmatchedEvents returns confirmed registrations whose CID has no stored file.
+func (repo *ChainEventRepositoryImpl) ListUnmatchedEvents(ctx context.Context, contractAddress string, limit int) ([]model.ChainEvent, error) {
+	var events []model.ChainEvent
+	err := repo.DB.WithContext(ctx).Where("contract_address = ? AND confirmed", contractAddress).
+		Where("NOT EXISTS (SELECT 1 FROM files WHERE files.cid = chain_events.cid AND files.deleted_at IS NULL)").
+		Order("block_number, log_index").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// ListUnregisteredFiles returns stored files that have no confirmed registration, by CID or, for
+// later uploads of registered content, by hash. Files uploaded before hashes were recorded only
+// match by CID.
+func (repo *ChainEventRepositoryImpl) ListUnregisteredFiles(ctx context.Context, contractAddress string, limit int) ([]model.File, error) {
+	var files []model.File
+	err := repo.DB.WithContext(ctx).Where("NOT EXISTS (SELECT 1 FROM chain_events e WHERE (e.cid = files.cid OR (files.file_hash <> '' AND e.file_hash = files.file_hash)) AND e.contract_address = ? AND e.confirmed)", contractAddress).
+		Order("created_at").Limit(limit).Find(&files).Error
+	return files, err
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "gorm.io/gorm"
+
+// Errors returned by repositories, so that services can handle them without depending on gorm.
+// The database is opened with TranslateError, so unique constraint violations surface as
+// ErrDuplicate.
+var (
+	ErrNotFound  = gorm.ErrRecordNotFound
+	ErrDuplicate = gorm.ErrDuplicatedKey
+)
@@ -5,15 +5,65 @@ package repository
 import (
 	"SafeTransfer/internal/db"
 	"SafeTransfer/internal/model"
+	"context"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
 )
 
 // FileRepository defines the interface for operations on the file entity.
 type FileRepository interface {
-	SaveFileMetadata(fileMetadata *model.File) error
-	GetFileMetadataByCID(cid string) (*model.File, error)
+	SaveFileMetadata(ctx context.Context, fileMetadata *model.File) error
+	GetFileMetadataByCID(ctx context.Context, cid string) (*model.File, error)
+	ListFilesInFolder(ctx context.Context, ethereumAddress string, folderID *uint, limit, offset int) ([]model.File, error)
+	CountFilesInFolder(ctx context.Context, ethereumAddress string, folderID *uint) (int64, error)
+	UpdateFileFolder(ctx context.Context, cid string, folderID *uint) error
+	UpdateFileDetails(ctx context.Context, cid, description string, tags model.Tags) error
+	SearchFiles(ctx context.Context, query FileSearchQuery) ([]model.File, int64, error)
+	GetLogicalFile(ctx context.Context, id uint) (*model.LogicalFile, error)
+	SaveFileVersion(ctx context.Context, fileMetadata *model.File) error
+	ListFileVersions(ctx context.Context, logicalFileID uint) ([]model.File, error)
+	GetFileVersion(ctx context.Context, logicalFileID uint, version int) (*model.File, error)
+	UpdateMaxVersions(ctx context.Context, logicalFileID uint, maxVersions int) error
+	PruneFileVersions(ctx context.Context, logicalFileID uint, keep int) ([]model.File, error)
+	UpdateChainStatus(ctx context.Context, cid, txHash, status, chainError string) error
+	FindFilesByHash(ctx context.Context, fileHash string, limit int) ([]model.File, error)
+	DeleteLogicalFile(ctx context.Context, logicalFileID uint) ([]model.File, error)
+	TransferLogicalFile(ctx context.Context, logicalFileID uint, fromAddress, toAddress string) error
+	StorageStats(ctx context.Context) (*StorageStats, error)
+	ListFilesAfter(ctx context.Context, ethereumAddress string, afterID uint, limit int) ([]model.File, error)
+	UpdateEncryptionKey(ctx context.Context, id uint, encryptionKey string) error
 }
 
+// StorageStats summarizes the files stored by all users.
+type StorageStats struct {
+	LogicalFiles int64
+	Versions     int64
+	BytesStored  int64
+}
+
+// FileSearchQuery describes a full-text search over an owner's file metadata.
+// Zero-valued fields do not restrict the results.
+type FileSearchQuery struct {
+	EthereumAddress string
+	Text            string
+	Tags            []string
+	CreatedAfter    time.Time
+	CreatedBefore   time.Time
+	MinSize         int64
+	MaxSize         int64
+	MimeType        string
+	Limit           int
+	Offset          int
+}
+
+// searchVectorSQL builds the document indexed for full-text search. Names and tags
+// weigh more than descriptions when ranking results.
+const searchVectorSQL = `setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(array_to_string(tags, ' '), '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(description, '')), 'B')`
+
 // FileRepositoryImpl is the concrete implementation of FileRepository.
 type FileRepositoryImpl struct {
 	DB *gorm.DB
@@ -24,18 +74,304 @@ func NewFileRepository(db *db.Database) FileRepository {
 	return &FileRepositoryImpl{DB: db.DB}
 }
 
-// SaveFileMetadata saves the metadata of a file to the database.
-func (repo *FileRepositoryImpl) SaveFileMetadata(fileMetadata *model.File) error {
-	result := repo.DB.Create(fileMetadata)
-	return result.Error
+// SaveFileMetadata saves the metadata of a newly uploaded file to the database as the first
+// version of a new logical file.
+func (repo *FileRepositoryImpl) SaveFileMetadata(ctx context.Context, fileMetadata *model.File) error {
+	fileMetadata.EthereumAddress = model.CanonicalAddress(fileMetadata.EthereumAddress)
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		logicalFile := &model.LogicalFile{
+			EthereumAddress: fileMetadata.EthereumAddress,
+			LatestVersion:   1,
+		}
+		if err := tx.Create(logicalFile).Error; err != nil {
+			return err
+		}
+
+		fileMetadata.LogicalFileID = &logicalFile.ID
+		fileMetadata.Version = 1
+		fileMetadata.IsLatest = true
+		if err := tx.Create(fileMetadata).Error; err != nil {
+			return err
+		}
+		return refreshSearchVector(tx.Where("id = ?", fileMetadata.ID))
+	})
 }
 
 // GetFileMetadataByCID retrieves file metadata by CID.
-func (repo *FileRepositoryImpl) GetFileMetadataByCID(cid string) (*model.File, error) {
+func (repo *FileRepositoryImpl) GetFileMetadataByCID(ctx context.Context, cid string) (*model.File, error) {
 	var fileMetadata model.File
-	result := repo.DB.Where("cid = ?", cid).First(&fileMetadata)
+	result := repo.DB.WithContext(ctx).Where("cid = ?", cid).First(&fileMetadata)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &fileMetadata, nil
 }
+
+// ListFilesInFolder returns one page of the files stored directly in a folder, ordered by name.
+// Only the latest version of each logical file is listed.
+// A nil folderID lists the owner's files that are not in any folder.
+func (repo *FileRepositoryImpl) ListFilesInFolder(ctx context.Context, ethereumAddress string, folderID *uint, limit, offset int) ([]model.File, error) {
+	var files []model.File
+	result := whereParent(repo.DB.WithContext(ctx).Where("ethereum_address = ? AND is_latest", model.CanonicalAddress(ethereumAddress)), "folder_id", folderID).
+		Order("name").Order("id").
+		Limit(limit).Offset(offset).
+		Find(&files)
+	return files, result.Error
+}
+
+// CountFilesInFolder returns the number of files stored directly in a folder.
+func (repo *FileRepositoryImpl) CountFilesInFolder(ctx context.Context, ethereumAddress string, folderID *uint) (int64, error) {
+	var count int64
+	result := whereParent(repo.DB.WithContext(ctx).Model(&model.File{}).Where("ethereum_address = ? AND is_latest", model.CanonicalAddress(ethereumAddress)), "folder_id", folderID).
+		Count(&count)
+	return count, result.Error
+}
+
+// UpdateFileFolder moves a file, together with all other versions of the same logical file,
+// into the given folder, or to the root when folderID is nil.
+func (repo *FileRepositoryImpl) UpdateFileFolder(ctx context.Context, cid string, folderID *uint) error {
+	versions := repo.DB.WithContext(ctx).Model(&model.File{}).Select("logical_file_id").Where("cid = ?", cid)
+	return repo.DB.WithContext(ctx).Model(&model.File{}).
+		Where("cid = ? OR logical_file_id IN (?)", cid, versions).
+		Update("folder_id", folderID).Error
+}
+
+// UpdateFileDetails replaces the user-editable description and tags of a file.
+func (repo *FileRepositoryImpl) UpdateFileDetails(ctx context.Context, cid, description string, tags model.Tags) error {
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.File{}).Where("cid = ?", cid).
+			Updates(map[string]interface{}{"description": description, "tags": tags}).Error
+		if err != nil {
+			return err
+		}
+		return refreshSearchVector(tx.Where("cid = ?", cid))
+	})
+}
+
+// SearchFiles returns one page of the files matching query, best matches first when
+// searching by text and newest first otherwise, along with the total number of matches.
+func (repo *FileRepositoryImpl) SearchFiles(ctx context.Context, query FileSearchQuery) ([]model.File, int64, error) {
+	db := repo.DB.WithContext(ctx).Model(&model.File{}).Where("ethereum_address = ? AND is_latest", model.CanonicalAddress(query.EthereumAddress))
+	if query.Text != "" {
+		db = db.Where("search_vector @@ websearch_to_tsquery('english', ?)", query.Text)
+	}
+	if len(query.Tags) > 0 {
+		db = db.Where("tags @> ?", model.Tags(query.Tags))
+	}
+	if !query.CreatedAfter.IsZero() {
+		db = db.Where("created_at >= ?", query.CreatedAfter)
+	}
+	if !query.CreatedBefore.IsZero() {
+		db = db.Where("created_at < ?", query.CreatedBefore)
+	}
+	if query.MinSize > 0 {
+		db = db.Where("size >= ?", query.MinSize)
+	}
+	if query.MaxSize > 0 {
+		db = db.Where("size <= ?", query.MaxSize)
+	}
+	if prefix, ok := strings.CutSuffix(query.MimeType, "/*"); ok {
+		db = db.Where("mime_type LIKE ?", escapeLike(prefix)+"/%")
+	} else if query.MimeType != "" {
+		db = db.Where("mime_type = ?", query.MimeType)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if query.Text != "" {
+		db = db.Order(gorm.Expr("ts_rank(search_vector, websearch_to_tsquery('english', ?)) DESC", query.Text))
+	}
+	var files []model.File
+	result := db.Order("created_at DESC").Order("id DESC").
+		Limit(query.Limit).Offset(query.Offset).
+		Find(&files)
+	return files, total, result.Error
+}
+
+// GetLogicalFile retrieves a logical file by its ID.
+func (repo *FileRepositoryImpl) GetLogicalFile(ctx context.Context, id uint) (*model.LogicalFile, error) {
+	var logicalFile model.LogicalFile
+	result := repo.DB.WithContext(ctx).First(&logicalFile, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &logicalFile, nil
+}
+
+// SaveFileVersion saves fileMetadata as the next version of the logical file it points at and
+// marks it as the latest. The logical file row is locked so concurrent uploads get distinct numbers.
+func (repo *FileRepositoryImpl) SaveFileVersion(ctx context.Context, fileMetadata *model.File) error {
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var logicalFile model.LogicalFile
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&logicalFile, *fileMetadata.LogicalFileID).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&model.File{}).
+			Where("logical_file_id = ? AND is_latest", logicalFile.ID).
+			Update("is_latest", false).Error
+		if err != nil {
+			return err
+		}
+
+		logicalFile.LatestVersion++
+		fileMetadata.Version = logicalFile.LatestVersion
+		fileMetadata.IsLatest = true
+		if err := tx.Create(fileMetadata).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&logicalFile).Update("latest_version", logicalFile.LatestVersion).Error; err != nil {
+			return err
+		}
+		return refreshSearchVector(tx.Where("id = ?", fileMetadata.ID))
+	})
+}
+
+// ListFileVersions returns all retained versions of a logical file, newest first.
+func (repo *FileRepositoryImpl) ListFileVersions(ctx context.Context, logicalFileID uint) ([]model.File, error) {
+	var files []model.File
+	result := repo.DB.WithContext(ctx).Where("logical_file_id = ?", logicalFileID).Order("version DESC").Find(&files)
+	return files, result.Error
+}
+
+// GetFileVersion retrieves a single version of a logical file.
+func (repo *FileRepositoryImpl) GetFileVersion(ctx context.Context, logicalFileID uint, version int) (*model.File, error) {
+	var fileMetadata model.File
+	result := repo.DB.WithContext(ctx).Where("logical_file_id = ? AND version = ?", logicalFileID, version).First(&fileMetadata)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &fileMetadata, nil
+}
+
+// UpdateMaxVersions sets the per-file retention limit of a logical file.
+func (repo *FileRepositoryImpl) UpdateMaxVersions(ctx context.Context, logicalFileID uint, maxVersions int) error {
+	return repo.DB.WithContext(ctx).Model(&model.LogicalFile{}).Where("id = ?", logicalFileID).Update("max_versions", maxVersions).Error
+}
+
+// PruneFileVersions deletes all but the newest keep versions of a logical file and returns
+// the deleted rows so their content can be released from storage.
+func (repo *FileRepositoryImpl) PruneFileVersions(ctx context.Context, logicalFileID uint, keep int) ([]model.File, error) {
+	var pruned []model.File
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("logical_file_id = ? AND NOT is_latest", logicalFileID).
+			Order("version DESC").Offset(max(keep-1, 0)).
+			Find(&pruned).Error
+		if err != nil || len(pruned) == 0 {
+			return err
+		}
+		ids := make([]uint, len(pruned))
+		for i := range pruned {
+			ids[i] = pruned[i].ID
+		}
+		return tx.Delete(&model.File{}, ids).Error
+	})
+	return pruned, err
+}
+
+// DeleteLogicalFile deletes a logical file together with all of its versions and shares, and
+// returns the deleted versions.
+func (repo *FileRepositoryImpl) DeleteLogicalFile(ctx context.Context, logicalFileID uint) ([]model.File, error) {
+	var versions []model.File
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("logical_file_id = ?", logicalFileID).Find(&versions).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("logical_file_id = ?", logicalFileID).Delete(&model.File{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("logical_file_id = ?", logicalFileID).Delete(&model.Share{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.LogicalFile{}, logicalFileID).Error
+	})
+	return versions, err
+}
+
+// TransferLogicalFile hands a logical file and all of its versions over to another owner,
+// moving them to the new owner's root folder and revoking its shares. It returns
+// gorm.ErrRecordNotFound if fromAddress no longer owns the file.
+func (repo *FileRepositoryImpl) TransferLogicalFile(ctx context.Context, logicalFileID uint, fromAddress, toAddress string) error {
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.LogicalFile{}).
+			Where("id = ? AND ethereum_address = ?", logicalFileID, model.CanonicalAddress(fromAddress)).
+			Update("ethereum_address", model.CanonicalAddress(toAddress))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		err := tx.Model(&model.File{}).Where("logical_file_id = ?", logicalFileID).
+			Updates(map[string]interface{}{"ethereum_address": model.CanonicalAddress(toAddress), "folder_id": nil}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("logical_file_id = ?", logicalFileID).Delete(&model.Share{}).Error
+	})
+}
+
+// UpdateChainStatus records the progress of a file's on-chain registration.
+func (repo *FileRepositoryImpl) UpdateChainStatus(ctx context.Context, cid, txHash, status, chainError string) error {
+	return repo.DB.WithContext(ctx).Model(&model.File{}).Where("cid = ?", cid).
+		Updates(map[string]interface{}{
+			"chain_tx_hash": txHash,
+			"chain_status":  status,
+			"chain_error":   chainError,
+		}).Error
+}
+
+// FindFilesByHash returns the oldest stored files whose plaintext has the given SHA-256.
+func (repo *FileRepositoryImpl) FindFilesByHash(ctx context.Context, fileHash string, limit int) ([]model.File, error) {
+	var files []model.File
+	err := repo.DB.WithContext(ctx).Where("file_hash = ?", fileHash).Order("created_at").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+// refreshSearchVector recomputes the search document of the files selected by query.
+func refreshSearchVector(query *gorm.DB) error {
+	return query.Model(&model.File{}).Update("search_vector", gorm.Expr(searchVectorSQL)).Error
+}
+
+// StorageStats counts the logical files and stored versions of all users.
+func (repo *FileRepositoryImpl) StorageStats(ctx context.Context) (*StorageStats, error) {
+	var versions struct {
+		Versions    int64
+		BytesStored int64
+	}
+	err := repo.DB.WithContext(ctx).Model(&model.File{}).
+		Select("COUNT(*) AS versions, COALESCE(SUM(size), 0) AS bytes_stored").
+		Scan(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &StorageStats{Versions: versions.Versions, BytesStored: versions.BytesStored}
+	if err := repo.DB.WithContext(ctx).Model(&model.LogicalFile{}).Count(&stats.LogicalFiles).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ListFilesAfter returns up to limit stored file versions with an ID above afterID, in ID order,
+// so that every version can be visited in batches. An empty ethereumAddress lists the versions
+// of all owners.
+func (repo *FileRepositoryImpl) ListFilesAfter(ctx context.Context, ethereumAddress string, afterID uint, limit int) ([]model.File, error) {
+	query := repo.DB.WithContext(ctx).Where("id > ?", afterID)
+	if ethereumAddress != "" {
+		query = query.Where("ethereum_address = ?", model.CanonicalAddress(ethereumAddress))
+	}
+	var files []model.File
+	err := query.Order("id").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+// UpdateEncryptionKey replaces the stored encryption key of a file version.
+func (repo *FileRepositoryImpl) UpdateEncryptionKey(ctx context.Context, id uint, encryptionKey string) error {
+	return repo.DB.WithContext(ctx).Model(&model.File{}).Where("id = ?", id).Update("encryption_key", encryptionKey).Error
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"SafeTransfer/internal/db"
+	"SafeTransfer/internal/model"
+	"context"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// FolderRepository defines the interface for operations on the folder entity.
+type FolderRepository interface {
+	CreateFolder(ctx context.Context, folder *model.Folder) error
+	GetFolderByID(ctx context.Context, id uint) (*model.Folder, error)
+	UpdateFolder(ctx context.Context, folder *model.Folder, oldPath string) error
+	DeleteFolder(ctx context.Context, id uint) error
+	ListSubfolders(ctx context.Context, ethereumAddress string, parentID *uint, limit, offset int) ([]model.Folder, error)
+	CountSubfolders(ctx context.Context, ethereumAddress string, parentID *uint) (int64, error)
+	ListFolders(ctx context.Context, ethereumAddress string) ([]model.Folder, error)
+}
+
+// FolderRepositoryImpl is the concrete implementation of FolderRepository.
+type FolderRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+// NewFolderRepository creates a new instance of FolderRepositoryImpl.
+func NewFolderRepository(db *db.Database) FolderRepository {
+	return &FolderRepositoryImpl{DB: db.DB}
+}
+
+// CreateFolder saves a new folder to the database.
+func (repo *FolderRepositoryImpl) CreateFolder(ctx context.Context, folder *model.Folder) error {
+	folder.EthereumAddress = model.CanonicalAddress(folder.EthereumAddress)
+	return repo.DB.WithContext(ctx).Create(folder).Error
+}
+
+// GetFolderByID retrieves a folder by its ID.
+func (repo *FolderRepositoryImpl) GetFolderByID(ctx context.Context, id uint) (*model.Folder, error) {
+	var folder model.Folder
+	result := repo.DB.WithContext(ctx).First(&folder, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &folder, nil
+}
+
+// UpdateFolder saves a renamed or moved folder and rewrites the paths of all of its
+// descendants, which still start with oldPath, in a single transaction.
+func (repo *FolderRepositoryImpl) UpdateFolder(ctx context.Context, folder *model.Folder, oldPath string) error {
+	return repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(folder).Error; err != nil {
+			return err
+		}
+		if folder.Path == oldPath {
+			return nil
+		}
+		return tx.Model(&model.Folder{}).
+			Where("ethereum_address = ? AND path LIKE ?", model.CanonicalAddress(folder.EthereumAddress), escapeLike(oldPath)+"/%").
+			Update("path", gorm.Expr("? || substr(path, char_length(?) + 1)", folder.Path, oldPath)).Error
+	})
+}
+
+// DeleteFolder permanently removes a folder so that its path can be reused.
+func (repo *FolderRepositoryImpl) DeleteFolder(ctx context.Context, id uint) error {
+	return repo.DB.WithContext(ctx).Unscoped().Delete(&model.Folder{}, id).Error
+}
+
+// ListSubfolders returns one page of the direct children of a folder, ordered by name.
+// A nil parentID lists the owner's top-level folders.
+func (repo *FolderRepositoryImpl) ListSubfolders(ctx context.Context, ethereumAddress string, parentID *uint, limit, offset int) ([]model.Folder, error) {
+	var folders []model.Folder
+	result := whereParent(repo.DB.WithContext(ctx).Where("ethereum_address = ?", model.CanonicalAddress(ethereumAddress)), "parent_id", parentID).
+		Order("name").Order("id").
+		Limit(limit).Offset(offset).
+		Find(&folders)
+	return folders, result.Error
+}
+
+// CountSubfolders returns the number of direct children of a folder.
+func (repo *FolderRepositoryImpl) CountSubfolders(ctx context.Context, ethereumAddress string, parentID *uint) (int64, error) {
+	var count int64
+	result := whereParent(repo.DB.WithContext(ctx).Model(&model.Folder{}).Where("ethereum_address = ?", model.CanonicalAddress(ethereumAddress)), "parent_id", parentID).
+		Count(&count)
+	return count, result.Error
+}
+
+// whereParent restricts a query to rows whose parent column matches parentID, treating nil as the root.
+func whereParent(query *gorm.DB, column string, parentID *uint) *gorm.DB {
+	if parentID == nil {
+		return query.Where(column + " IS NULL")
+	}
+	return query.Where(column+" = ?", *parentID)
+}
+
+// escapeLike escapes the LIKE wildcards in s so it can be used as a literal prefix.
+func escapeLike(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(s)
+}
+
+// ListFolders returns all of an owner's folders, ordered by path.
+func (repo *FolderRepositoryImpl) ListFolders(ctx context.Context, ethereumAddress string) ([]model.Folder, error) {
+	var folders []model.Folder
+	err := repo.DB.WithContext(ctx).Where("ethereum_address = ?", model.CanonicalAddress(ethereumAddress)).Order("path").Find(&folders).Error
+	return folders, err
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"SafeTransfer/internal/db"
+	"SafeTransfer/internal/model"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ShareRepository interface {
+	SaveShare(ctx context.Context, share *model.Share) error
+	DeleteShare(ctx context.Context, logicalFileID uint, granteeAddress string) (bool, error)
+	HasShare(ctx context.Context, logicalFileID uint, granteeAddress string) (bool, error)
+	ListShares(ctx context.Context, logicalFileID uint) ([]model.Share, error)
+	ListSharedFiles(ctx context.Context, granteeAddress string, limit, offset int) ([]model.File, int64, error)
+	ListSharesByGrantee(ctx context.Context, granteeAddress string) ([]model.Share, error)
+}
+
+type ShareRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewShareRepository(db *db.Database) ShareRepository {
+	return &ShareRepositoryImpl{DB: db.DB}
+}
+
+// SaveShare grants a share, leaving an existing grant to the same user in place.
+func (repo *ShareRepositoryImpl) SaveShare(ctx context.Context, share *model.Share) error {
+	share.GranteeAddress = model.CanonicalAddress(share.GranteeAddress)
+	share.GrantedBy = model.CanonicalAddress(share.GrantedBy)
+	return repo.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(share).Error
+}
+
+// DeleteShare revokes a share, reporting whether it existed.
+func (repo *ShareRepositoryImpl) DeleteShare(ctx context.Context, logicalFileID uint, granteeAddress string) (bool, error) {
+	result := repo.DB.WithContext(ctx).Unscoped().
+		Where("logical_file_id = ? AND grantee_address = ?", logicalFileID, model.CanonicalAddress(granteeAddress)).
+		Delete(&model.Share{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (repo *ShareRepositoryImpl) HasShare(ctx context.Context, logicalFileID uint, granteeAddress string) (bool, error) {
+	var count int64
+	err := repo.DB.WithContext(ctx).Model(&model.Share{}).
+		Where("logical_file_id = ? AND grantee_address = ?", logicalFileID, model.CanonicalAddress(granteeAddress)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (repo *ShareRepositoryImpl) ListShares(ctx context.Context, logicalFileID uint) ([]model.Share, error) {
+	var shares []model.Share
+	err := repo.DB.WithContext(ctx).Where("logical_file_id = ?", logicalFileID).Order("created_at").Find(&shares).Error
+	return shares, err
+}
+
+// ListSharedFiles returns a page of the latest versions of the files shared with a user, most
+// recently shared first, along with their total number.
+func (repo *ShareRepositoryImpl) ListSharedFiles(ctx context.Context, granteeAddress string, limit, offset int) ([]model.File, int64, error) {
+	query := repo.DB.WithContext(ctx).Model(&model.File{}).
+		Joins("JOIN shares ON shares.logical_file_id = files.logical_file_id AND shares.deleted_at IS NULL").
+		Where("shares.grantee_address = ? AND files.is_latest", model.CanonicalAddress(granteeAddress))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var files []model.File
+	err := query.Select("files.*").Order("shares.created_at DESC").Order("files.id DESC").
+		Limit(limit).Offset(offset).Find(&files).Error
+	return files, total, err
+}
+
+// ListSharesByGrantee returns the shares granted to a user, oldest first.
+func (repo *ShareRepositoryImpl) ListSharesByGrantee(ctx context.Context, granteeAddress string) ([]model.Share, error) {
+	var shares []model.Share
+	err := repo.DB.WithContext(ctx).Where("grantee_address = ?", model.CanonicalAddress(granteeAddress)).Order("created_at").Find(&shares).Error
+	return shares, err
+}
@@ -3,13 +3,30 @@ package repository
 import (
 	"SafeTransfer/internal/db"
 	"SafeTransfer/internal/model"
+	"context"
 	"errors"
 	"gorm.io/gorm"
+	"time"
 )
 
 type UserRepository interface {
-	SaveOrUpdateUser(user *model.User) error
-	FindByEthereumAddress(ethereumAddress string) (*model.User, error)
+	SaveOrUpdateUser(ctx context.Context, user *model.User) error
+	FindByEthereumAddress(ctx context.Context, ethereumAddress string) (*model.User, error)
+	ReserveStorage(ctx context.Context, ethereumAddress string, bytes, files, defaultQuotaBytes, defaultQuotaFiles int64) (bool, error)
+	ReleaseStorage(ctx context.Context, ethereumAddress string, bytes, files int64) error
+	SetQuota(ctx context.Context, ethereumAddress string, quotaBytes, quotaFiles *int64) error
+	ConsumeNonce(ctx context.Context, ethereumAddress, nonce, nextNonce string) (bool, error)
+	ListUsers(ctx context.Context, limit, offset int) ([]model.User, int64, error)
+	UpdateRole(ctx context.Context, ethereumAddress, role string) error
+	UpdateDisabledAt(ctx context.Context, ethereumAddress string, disabledAt *time.Time) error
+	CountUsers(ctx context.Context) (*UserCounts, error)
+}
+
+// UserCounts summarizes the registered users.
+type UserCounts struct {
+	Total    int64
+	Disabled int64
+	ByRole   map[string]int64
 }
 
 type UserRepositoryImpl struct {
@@ -20,25 +37,147 @@ func NewUserRepository(db *db.Database) UserRepository {
 	return &UserRepositoryImpl{DB: db.DB}
 }
 
-func (repo *UserRepositoryImpl) SaveOrUpdateUser(user *model.User) error {
+func (repo *UserRepositoryImpl) SaveOrUpdateUser(ctx context.Context, user *model.User) error {
+	user.EthereumAddress = model.CanonicalAddress(user.EthereumAddress)
 	var existingUser model.User
-	result := repo.DB.Where("ethereum_address = ?", user.EthereumAddress).First(&existingUser)
+	result := repo.DB.WithContext(ctx).Where("ethereum_address = ?", user.EthereumAddress).First(&existingUser)
 
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return repo.DB.Create(user).Error
+		return repo.DB.WithContext(ctx).Create(user).Error
 	} else if result.Error != nil {
 		return result.Error
 	}
 
 	existingUser.Nonce = user.Nonce
-	return repo.DB.Save(&existingUser).Error
+	return repo.DB.WithContext(ctx).Save(&existingUser).Error
 }
 
-func (repo *UserRepositoryImpl) FindByEthereumAddress(ethereumAddress string) (*model.User, error) {
+func (repo *UserRepositoryImpl) FindByEthereumAddress(ctx context.Context, ethereumAddress string) (*model.User, error) {
 	var user model.User
-	result := repo.DB.Where("ethereum_address = ?", ethereumAddress).First(&user)
+	result := repo.DB.WithContext(ctx).Where("ethereum_address = ?", model.CanonicalAddress(ethereumAddress)).First(&user)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &user, nil
 }
+
+// ReserveStorage atomically adds files totalling the given size to the user's usage, provided
+// that the result stays within the user's quotas. The defaults apply where the user has no
+// override, and a quota of zero means unlimited. It reports false when a quota would be exceeded.
+func (repo *UserRepositoryImpl) ReserveStorage(ctx context.Context, ethereumAddress string, bytes, files, defaultQuotaBytes, defaultQuotaFiles int64) (bool, error) {
+	result := repo.DB.WithContext(ctx).Model(&model.User{}).
+		Where("ethereum_address = ?", model.CanonicalAddress(ethereumAddress)).
+		Where("(COALESCE(quota_bytes, ?) <= 0 OR bytes_stored + ? <= COALESCE(quota_bytes, ?))", defaultQuotaBytes, bytes, defaultQuotaBytes).
+		Where("(COALESCE(quota_files, ?) <= 0 OR file_count + ? <= COALESCE(quota_files, ?))", defaultQuotaFiles, files, defaultQuotaFiles).
+		Updates(map[string]interface{}{
+			"bytes_stored": gorm.Expr("bytes_stored + ?", bytes),
+			"file_count":   gorm.Expr("file_count + ?", files),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseStorage subtracts deleted or failed uploads from the user's usage.
+func (repo *UserRepositoryImpl) ReleaseStorage(ctx context.Context, ethereumAddress string, bytes, files int64) error {
+	return repo.DB.WithContext(ctx).Model(&model.User{}).
+		Where("ethereum_address = ?", model.CanonicalAddress(ethereumAddress)).
+		Updates(map[string]interface{}{
+			"bytes_stored": gorm.Expr("GREATEST(bytes_stored - ?, 0)", bytes),
+			"file_count":   gorm.Expr("GREATEST(file_count - ?, 0)", files),
+		}).Error
+}
+
+// SetQuota sets or, when nil, clears the user's quota overrides.
+func (repo *UserRepositoryImpl) SetQuota(ctx context.Context, ethereumAddress string, quotaBytes, quotaFiles *int64) error {
+	result := repo.DB.WithContext(ctx).Model(&model.User{}).
+		Where("ethereum_address = ?", model.CanonicalAddress(ethereumAddress)).
+		Updates(map[string]interface{}{
+			"quota_bytes": quotaBytes,
+			"quota_files": quotaFiles,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ConsumeNonce replaces the user's nonce with nextNonce if it is still nonce, so that a nonce
+// can authorize only one action. It reports whether the nonce matched.
+func (repo *UserRepositoryImpl) ConsumeNonce(ctx context.Context, ethereumAddress, nonce, nextNonce string) (bool, error) {
+	result := repo.DB.WithContext(ctx).Model(&model.User{}).
+		Where("ethereum_address = ? AND nonce = ?", model.CanonicalAddress(ethereumAddress), nonce).
+		Update("nonce", nextNonce)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ListUsers returns a page of users in order of registration, with the total number of users.
+func (repo *UserRepositoryImpl) ListUsers(ctx context.Context, limit, offset int) ([]model.User, int64, error) {
+	var total int64
+	if err := repo.DB.WithContext(ctx).Model(&model.User{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []model.User
+	err := repo.DB.WithContext(ctx).Order("id").Limit(limit).Offset(offset).Find(&users).Error
+	return users, total, err
+}
+
+// UpdateRole changes the user's role.
+func (repo *UserRepositoryImpl) UpdateRole(ctx context.Context, ethereumAddress, role string) error {
+	result := repo.DB.WithContext(ctx).Model(&model.User{}).
+		Where("ethereum_address = ?", model.CanonicalAddress(ethereumAddress)).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateDisabledAt disables the user's account as of disabledAt or, when nil, enables it.
+func (repo *UserRepositoryImpl) UpdateDisabledAt(ctx context.Context, ethereumAddress string, disabledAt *time.Time) error {
+	result := repo.DB.WithContext(ctx).Model(&model.User{}).
+		Where("ethereum_address = ?", model.CanonicalAddress(ethereumAddress)).
+		Update("disabled_at", disabledAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CountUsers counts the users by role and the disabled accounts.
+func (repo *UserRepositoryImpl) CountUsers(ctx context.Context) (*UserCounts, error) {
+	var rows []struct {
+		Role     string
+		Total    int64
+		Disabled int64
+	}
+	err := repo.DB.WithContext(ctx).Model(&model.User{}).
+		Select("role, COUNT(*) AS total, COUNT(disabled_at) AS disabled").
+		Group("role").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := &UserCounts{ByRole: make(map[string]int64)}
+	for _, row := range rows {
+		counts.Total += row.Total
+		counts.Disabled += row.Disabled
+		counts.ByRole[row.Role] = row.Total
+	}
+	return counts, nil
+}
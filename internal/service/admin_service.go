@@ -0,0 +1,152 @@
+package service
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var (
+	ErrInvalidRole = newError(ErrInvalidInput, "invalid_role", "role must be user, auditor or admin")
+	ErrOwnAccount  = newError(ErrForbidden, "own_account", "admins cannot change their own role or disable their own account")
+)
+
+// SystemStats summarizes the users and storage of the whole system.
+type SystemStats struct {
+	Users   repository.UserCounts
+	Storage repository.StorageStats
+}
+
+// AdminService implements the /admin API: inspecting and moderating users and their files.
+// Every change is logged with the admin who made it.
+type AdminService struct {
+	UserRepo    repository.UserRepository
+	FileRepo    repository.FileRepository
+	FileService *FileService
+}
+
+// NewAdminService creates a new instance of AdminService with dependencies injected.
+func NewAdminService(userRepo repository.UserRepository, fileRepo repository.FileRepository, fileService *FileService) *AdminService {
+	return &AdminService{
+		UserRepo:    userRepo,
+		FileRepo:    fileRepo,
+		FileService: fileService,
+	}
+}
+
+// ListUsers returns a page of users with the total number of users.
+func (as *AdminService) ListUsers(ctx context.Context, page, pageSize int) ([]model.User, int64, error) {
+	users, total, err := as.UserRepo.ListUsers(ctx, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, total, nil
+}
+
+// GetUser returns a user whether or not their account is disabled.
+func (as *AdminService) GetUser(ctx context.Context, ethereumAddress string) (*model.User, error) {
+	user, err := as.UserRepo.FindByEthereumAddress(ctx, model.CanonicalAddress(ethereumAddress))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// SetRole changes a user's role. The user has to sign in again for it to take effect.
+func (as *AdminService) SetRole(ctx context.Context, admin, ethereumAddress, role string) (*model.User, error) {
+	if !model.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	ethereumAddress = model.CanonicalAddress(ethereumAddress)
+	if sameAddress(admin, ethereumAddress) {
+		return nil, ErrOwnAccount
+	}
+
+	err := as.UserRepo.UpdateRole(ctx, ethereumAddress, role)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+
+	log.Printf("Admin %s set the role of %s to %s", admin, ethereumAddress, role)
+	return as.GetUser(ctx, ethereumAddress)
+}
+
+// SetDisabled disables or re-enables a user's account. A disabled user cannot sign in, and
+// their existing tokens are rejected.
+func (as *AdminService) SetDisabled(ctx context.Context, admin, ethereumAddress string, disabled bool) (*model.User, error) {
+	ethereumAddress = model.CanonicalAddress(ethereumAddress)
+	if sameAddress(admin, ethereumAddress) {
+		return nil, ErrOwnAccount
+	}
+
+	var disabledAt *time.Time
+	if disabled {
+		now := time.Now()
+		disabledAt = &now
+	}
+	err := as.UserRepo.UpdateDisabledAt(ctx, ethereumAddress, disabledAt)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to update account: %w", err)
+	}
+
+	if disabled {
+		log.Printf("Admin %s disabled the account of %s", admin, ethereumAddress)
+	} else {
+		log.Printf("Admin %s enabled the account of %s", admin, ethereumAddress)
+	}
+	return as.GetUser(ctx, ethereumAddress)
+}
+
+// ForceDeleteFile deletes any user's file with all of its versions, without their signature.
+func (as *AdminService) ForceDeleteFile(ctx context.Context, admin string, logicalFileID uint) error {
+	logicalFile, err := as.FileService.ForceDeleteFile(ctx, logicalFileID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Admin %s deleted file %d of %s", admin, logicalFile.ID, logicalFile.EthereumAddress)
+	return nil
+}
+
+// Stats counts the users and the files they store.
+func (as *AdminService) Stats(ctx context.Context) (*SystemStats, error) {
+	users, err := as.UserRepo.CountUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+	storage, err := as.FileRepo.StorageStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count files: %w", err)
+	}
+	return &SystemStats{Users: *users, Storage: *storage}, nil
+}
+
+// BootstrapAdmins gives the admin role to the given addresses, creating users that have not
+// signed in yet, so that a new deployment has someone to manage roles through the API.
+func (as *AdminService) BootstrapAdmins(ctx context.Context, addresses []string) error {
+	for _, address := range addresses {
+		address = model.CanonicalAddress(address)
+		err := as.UserRepo.UpdateRole(ctx, address, model.RoleAdmin)
+		if errors.Is(err, repository.ErrNotFound) {
+			nonce, nonceErr := newNonce()
+			if nonceErr != nil {
+				return nonceErr
+			}
+			err = as.UserRepo.SaveOrUpdateUser(ctx, &model.User{EthereumAddress: address, Nonce: nonce, Role: model.RoleAdmin})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to make %s an admin: %w", address, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Scopes an API key can be granted. Requests authenticated with a JWT have all of them.
+const (
+	ScopeUpload       = "upload"
+	ScopeDownload     = "download"
+	ScopeReadMetadata = "read-metadata"
+)
+
+const (
+	// APIKeyPrefix starts every API key, so that leaked keys are easy to recognize.
+	APIKeyPrefix = "st_"
+
+	apiKeyIDSize     = 6  // random bytes identifying a key; shown in listings as its prefix
+	apiKeySecretSize = 32 // random bytes of the secret part of a key
+	maxAPIKeys       = 20 // per user
+	maxAPIKeyName    = 100
+
+	// apiKeyLastUsedResolution limits how often using a key writes its last-used time.
+	apiKeyLastUsedResolution = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey        = newError(ErrUnauthorized, "invalid_api_key", "invalid API key")
+	ErrAPIKeyExpired        = newError(ErrUnauthorized, "api_key_expired", "API key has expired")
+	ErrInvalidAPIKeyRequest = newError(ErrInvalidInput, "invalid_api_key_request", "invalid API key request")
+	ErrTooManyAPIKeys       = newError(ErrConflict, "too_many_api_keys", fmt.Sprintf("at most %d API keys are allowed per user", maxAPIKeys))
+	ErrAPIKeyNotFound       = newError(ErrNotFound, "api_key_not_found", "API key not found")
+)
+
+// APIKeyService issues API keys and authenticates requests made with them. Keys have the form
+// st_<id>_<secret>, where st_<id> is the key's prefix.
+type APIKeyService struct {
+	APIKeyRepo repository.APIKeyRepository
+}
+
+// NewAPIKeyService creates a new instance of APIKeyService with dependencies injected.
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{APIKeyRepo: apiKeyRepo}
+}
+
+// CreateAPIKey issues a key with the given scopes, which expires at expiresAt unless it is nil.
+// The key itself is returned only here; afterwards only its prefix is known.
+func (ks *APIKeyService) CreateAPIKey(ctx context.Context, ethereumAddress, name string, scopes []string, expiresAt *time.Time) (*model.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAPIKeyName {
+		return nil, "", fmt.Errorf("%w: name must be between 1 and %d bytes", ErrInvalidAPIKeyRequest, maxAPIKeyName)
+	}
+	normalized, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%w: expiresAt must be in the future", ErrInvalidAPIKeyRequest)
+	}
+
+	count, err := ks.APIKeyRepo.CountAPIKeys(ctx, ethereumAddress)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to count API keys: %w", err)
+	}
+	if count >= maxAPIKeys {
+		return nil, "", ErrTooManyAPIKeys
+	}
+
+	id, err := randomHex(apiKeyIDSize)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(apiKeySecretSize)
+	if err != nil {
+		return nil, "", err
+	}
+	prefix := APIKeyPrefix + id
+	key := prefix + "_" + secret
+
+	apiKey := &model.APIKey{
+		EthereumAddress: ethereumAddress,
+		Name:            name,
+		Prefix:          prefix,
+		KeyHash:         hashAPIKey(key),
+		Scopes:          normalized,
+		ExpiresAt:       expiresAt,
+	}
+	if err := ks.APIKeyRepo.SaveAPIKey(ctx, apiKey); err != nil {
+		return nil, "", fmt.Errorf("failed to save API key: %w", err)
+	}
+	return apiKey, key, nil
+}
+
+// ListAPIKeys returns the user's keys that have not been revoked, including expired ones.
+func (ks *APIKeyService) ListAPIKeys(ctx context.Context, ethereumAddress string) ([]model.APIKey, error) {
+	keys, err := ks.APIKeyRepo.ListAPIKeys(ctx, ethereumAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes one of the user's keys. It stops working immediately.
+func (ks *APIKeyService) RevokeAPIKey(ctx context.Context, ethereumAddress string, id uint) error {
+	deleted, err := ks.APIKeyRepo.DeleteAPIKey(ctx, ethereumAddress, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if !deleted {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate returns the key matching a presented API key, recording when it was used.
+func (ks *APIKeyService) Authenticate(ctx context.Context, key string) (*model.APIKey, error) {
+	prefix, ok := apiKeyPrefixOf(key)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := ks.APIKeyRepo.FindAPIKeyByPrefix(ctx, prefix)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find API key: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(apiKey.KeyHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt) {
+		return nil, ErrAPIKeyExpired
+	}
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyLastUsedResolution {
+		if err := ks.APIKeyRepo.UpdateLastUsed(ctx, apiKey.ID, now); err != nil {
+			log.Printf("Failed to record use of API key %s: %v", apiKey.Prefix, err)
+		}
+		apiKey.LastUsedAt = &now
+	}
+	return apiKey, nil
+}
+
+// HasScope reports whether the key was granted scope.
+func HasScope(apiKey *model.APIKey, scope string) bool {
+	for _, granted := range apiKey.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKeyPrefixOf extracts the prefix from a key of the form st_<id>_<secret>.
+func apiKeyPrefixOf(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, APIKeyPrefix)
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != 2*apiKeyIDSize || len(secret) != 2*apiKeySecretSize {
+		return "", false
+	}
+	return APIKeyPrefix + id, true
+}
+
+func normalizeScopes(scopes []string) (model.Tags, error) {
+	normalized := model.Tags{}
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		switch scope {
+		case ScopeUpload, ScopeDownload, ScopeReadMetadata:
+		default:
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKeyRequest, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyRequest)
+	}
+	return normalized, nil
+}
+
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func randomHex(size int) (string, error) {
+	randomBytes := make([]byte, size)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(randomBytes), nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"SafeTransfer/internal/chain"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Chain verification modes for downloads.
+const (
+	ChainVerifyOff      = "off"      // downloads are not checked against the registry
+	ChainVerifyAdvisory = "advisory" // the result is reported but never blocks a download
+	ChainVerifyStrict   = "strict"   // downloads fail unless the registry confirms the file
+)
+
+// Outcomes of checking a download against the FileRegistry contract.
+const (
+	ChainVerified     = "verified"
+	ChainMismatch     = "mismatch"
+	ChainUnregistered = "unregistered"
+	ChainUnavailable  = "unavailable"
+)
+
+// chainLookupTimeout bounds the registry calls made for one download.
+const chainLookupTimeout = 10 * time.Second
+
+var ErrChainVerificationFailed = newError(ErrIntegrity, "chain_verification_failed", "on-chain verification failed")
+
+// ChainVerification is the result of comparing a downloaded file with its on-chain registration.
+type ChainVerification struct {
+	Status       string
+	Owner        string
+	RegisteredAt time.Time
+	Reason       string
+}
+
+// ChainVerificationError is returned in strict mode when a download could not be verified on
+// chain. It matches ErrChainVerificationFailed.
+type ChainVerificationError struct {
+	CID          string
+	Verification *ChainVerification
+}
+
+func (e *ChainVerificationError) Error() string {
+	return fmt.Sprintf("on-chain verification of %s failed: %s: %s", e.CID, e.Verification.Status, e.Verification.Reason)
+}
+
+func (e *ChainVerificationError) Unwrap() error {
+	return ErrChainVerificationFailed
+}
+
+// ValidChainVerifyMode reports whether mode is one of the supported verification modes.
+func ValidChainVerifyMode(mode string) bool {
+	switch mode {
+	case ChainVerifyOff, ChainVerifyAdvisory, ChainVerifyStrict:
+		return true
+	}
+	return false
+}
+
+// verifyOnChain compares the SHA-256 of a file's decrypted content with the registry. The
+// registrant is reported but not checked: the server's signer registers every upload, so it
+// would not tell one uploader from another, and who owns a file is recorded by the server alone.
+// It returns nil when verification is disabled, and a *ChainVerificationError in strict mode
+// unless the file is verified.
+func (ds *DownloadService) verifyOnChain(ctx context.Context, fileMetadata *model.File, sha256Hash string) (*ChainVerification, error) {
+	if ds.Registry == nil || ds.ChainVerifyMode == ChainVerifyOff || ds.ChainVerifyMode == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, chainLookupTimeout)
+	defer cancel()
+
+	verification := &ChainVerification{}
+	lookupCtx, span := tracer.Start(ctx, "chain.lookup", trace.WithSpanKind(trace.SpanKindClient))
+	registration, err := lookupRegistration(lookupCtx, ds.Registry, fileMetadata.CID, sha256Hash)
+	if errors.Is(err, chain.ErrNotRegistered) {
+		span.End() // an answer rather than a failed lookup
+	} else {
+		tracing.End(span, err)
+	}
+	switch {
+	case errors.Is(err, chain.ErrNotRegistered):
+		verification.Status = ChainUnregistered
+		verification.Reason = "neither the CID nor the hash is registered"
+	case err != nil:
+		verification.Status = ChainUnavailable
+		verification.Reason = err.Error()
+	default:
+		verification.Owner = registration.Owner.Hex()
+		verification.RegisteredAt = registration.Timestamp
+
+		if !strings.EqualFold(registration.FileHash, sha256Hash) {
+			verification.Status = ChainMismatch
+			verification.Reason = "registered hash does not match the file content"
+		} else {
+			verification.Status = ChainVerified
+		}
+	}
+
+	if ds.ChainVerifyMode == ChainVerifyStrict && verification.Status != ChainVerified {
+		return verification, &ChainVerificationError{CID: fileMetadata.CID, Verification: verification}
+	}
+	return verification, nil
+}
+
+// lookupRegistration looks a file up by CID, falling back to its hash: the contract refuses to
+// register a hash twice, so later uploads of the same content are only registered by hash.
+func lookupRegistration(ctx context.Context, registry *chain.Registry, cid, fileHash string) (*chain.Registration, error) {
+	registration, err := registry.LookupFile(ctx, cid)
+	if errors.Is(err, chain.ErrNotRegistered) {
+		return registry.LookupFileHash(ctx, fileHash)
+	}
+	return registration, err
+}
@@ -1,92 +1,276 @@
 package service
 
 import (
+	"SafeTransfer/internal/chain"
 	"SafeTransfer/internal/crypto"
+	"SafeTransfer/internal/metrics"
+	"SafeTransfer/internal/model"
 	"SafeTransfer/internal/repository"
 	"SafeTransfer/internal/storage"
-	"bytes"
-	"crypto/rsa"
+	"SafeTransfer/internal/tracing"
+	"archive/zip"
+	"context"
 	"crypto/sha256"
-	"crypto/x509"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"path"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const MaxArchiveFiles = 100
+
+var (
+	ErrInvalidArchive     = newError(ErrInvalidInput, "invalid_archive", "invalid archive request")
+	ErrStorageUnavailable = newError(ErrUnavailable, "storage_unavailable", "file storage is unavailable")
+	ErrFileIntegrity      = newError(ErrIntegrity, "file_integrity_failed", "file failed signature verification")
 )
 
 type DownloadService struct {
 	IPFSStorage *storage.IPFSStorage
 	FileRepo    repository.FileRepository
+	ShareRepo   repository.ShareRepository
+
+	// Registry, when set, is used to check downloads against the FileRegistry contract
+	// according to ChainVerifyMode.
+	Registry        *chain.Registry
+	ChainVerifyMode string
+
+	// KeyRing unwraps the encryption keys of stored files.
+	KeyRing *crypto.KeyRing
+}
+
+// Download is a readable file ready to be streamed with Stream. Files with a recorded hash have
+// already been checked on chain, so that the result can be sent before the content.
+type Download struct {
+	File  *model.File
+	Hash  string             // SHA-256 of the content as a hexadecimal string, empty until streamed for files uploaded before it was recorded
+	Chain *ChainVerification // nil when on-chain verification is disabled, or until streamed along with Hash
+
+	ds *DownloadService
 }
 
 // NewDownloadService creates a new instance of DownloadService with dependencies injected.
-func NewDownloadService(ipfsStorage *storage.IPFSStorage, fileRepo repository.FileRepository) *DownloadService {
+func NewDownloadService(ipfsStorage *storage.IPFSStorage, fileRepo repository.FileRepository, shareRepo repository.ShareRepository, registry *chain.Registry, chainVerifyMode string, keyRing *crypto.KeyRing) *DownloadService {
 	return &DownloadService{
-		IPFSStorage: ipfsStorage,
-		FileRepo:    fileRepo,
+		IPFSStorage:     ipfsStorage,
+		FileRepo:        fileRepo,
+		ShareRepo:       shareRepo,
+		Registry:        registry,
+		ChainVerifyMode: chainVerifyMode,
+		KeyRing:         keyRing,
 	}
 }
 
-// DownloadFile handles the downloading of a file by its CID and returns the file content along with its SHA-256 hash as a hexadecimal string.
-func (ds *DownloadService) DownloadFile(cid string) (io.Reader, string, error) {
-	fileMetadata, err := ds.FileRepo.GetFileMetadataByCID(cid)
+// DownloadFile looks up a file by its CID so that it can be streamed with Download.Stream.
+// Files that are neither owned by nor shared with ethereumAddress are reported as not found
+// before anything is fetched from IPFS.
+func (ds *DownloadService) DownloadFile(ctx context.Context, ethereumAddress, cid string) (*Download, error) {
+	fileMetadata, err := ds.getReadableFile(ctx, ethereumAddress, cid)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get file metadata: %w", err)
+		return nil, err
+	}
+	return ds.download(ctx, fileMetadata)
+}
+
+// download prepares a file for streaming, checking its recorded hash on chain when enabled.
+func (ds *DownloadService) download(ctx context.Context, fileMetadata *model.File) (*Download, error) {
+	download := &Download{File: fileMetadata, Hash: fileMetadata.FileHash, ds: ds}
+	if download.Hash == "" {
+		return download, nil
 	}
 
-	encryptionKey, err := base64.StdEncoding.DecodeString(fileMetadata.EncryptionKey)
+	verification, err := ds.verifyOnChain(ctx, fileMetadata, download.Hash)
+	if err != nil {
+		return nil, err
+	}
+	download.Chain = verification
+	return download, nil
+}
+
+// Stream decrypts the file into w without buffering it, verifying its signature and recorded
+// hash once all of the content has been written. For files without a recorded hash, Hash and
+// Chain are only set then, and in the strict chain verification mode the file is checked on
+// chain at that point. On an error, w has already received content that must not be trusted.
+func (d *Download) Stream(ctx context.Context, w io.Writer) error {
+	sha256Hash, err := d.ds.StreamFile(ctx, d.File, w)
+	if err != nil {
+		return err
+	}
+	if d.Hash != "" {
+		if sha256Hash != d.Hash {
+			return fmt.Errorf("%w: content hash %s does not match the recorded %s", ErrFileIntegrity, sha256Hash, d.Hash)
+		}
+		return nil
+	}
+
+	d.Hash = sha256Hash
+	d.Chain, err = d.ds.verifyOnChain(ctx, d.File, sha256Hash)
+	return err
+}
+
+// StreamFile decrypts a file into w without buffering it and verifies its signature once all of
+// the content has been written. It returns the SHA-256 hash of the decrypted content. On a
+// verification error, w has already received content that must not be trusted.
+func (ds *DownloadService) StreamFile(ctx context.Context, fileMetadata *model.File, w io.Writer) (string, error) {
+	ctx, span := tracer.Start(ctx, "file.download", trace.WithAttributes(attribute.String("ipfs.cid", fileMetadata.CID)))
+	sha256Hash, err := ds.streamFile(ctx, fileMetadata, w)
+	tracing.End(span, err)
+	return sha256Hash, err
+}
+
+func (ds *DownloadService) streamFile(ctx context.Context, fileMetadata *model.File, w io.Writer) (string, error) {
+	encryptionKey, err := ds.KeyRing.Unwrap(fileMetadata.EncryptionKey)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to decode encryption key: %w", err)
+		return "", fmt.Errorf("failed to unwrap encryption key: %w", err)
 	}
 
 	nonce, err := base64.StdEncoding.DecodeString(fileMetadata.Nonce)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to decode nonce: %w", err)
+		return "", fmt.Errorf("failed to decode nonce: %w", err)
 	}
 
-	encryptedFile, err := ds.IPFSStorage.DownloadFileFromIPFS(cid)
+	publicKey, err := parsePublicKey(fileMetadata.PublicKey)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to download file from IPFS: %w", err)
+		return "", err
 	}
-	defer encryptedFile.Close()
 
-	// First, decrypt the file
-	decryptedContent, err := crypto.DecryptFile(encryptedFile, encryptionKey, nonce)
+	defer metrics.StartTransfer(metrics.Download)()
+
+	encryptedFile, err := ds.IPFSStorage.DownloadFileFromIPFS(ctx, fileMetadata.CID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to decrypt file: %w", err)
+		return "", classify(ErrStorageUnavailable, fmt.Errorf("failed to download file from IPFS: %w", err))
 	}
+	defer encryptedFile.Close()
 
-	decryptedData, err := io.ReadAll(decryptedContent)
+	source := &timedReader{r: encryptedFile}
+	decryptedContent, err := crypto.DecryptFile(source, encryptionKey, nonce)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to read decrypted file content: %w", err)
+		return "", fmt.Errorf("failed to decrypt file: %w", err)
 	}
+	decryptedContent.OnEOF = metrics.ObserveCipher("decrypt")
 
-	// Calculate SHA-256 hash of the decrypted file content
+	// Hash the content on its way to w. The content is streamed from IPFS during the copy, so
+	// the span records how long was spent waiting for IPFS and in the cipher; the rest of it is
+	// mostly spent writing to w.
+	_, decryptSpan := tracer.Start(ctx, "file.decrypt")
 	hash := sha256.New()
-	hash.Write(decryptedData)
-	sha256Hash := fmt.Sprintf("%x", hash.Sum(nil))
-
-	// Then, verify the signature
-	publicKeyBytes, err := base64.StdEncoding.DecodeString(fileMetadata.PublicKey)
+	written, err := io.Copy(io.MultiWriter(w, hash), decryptedContent)
+	metrics.BytesDownloaded.Add(float64(written))
+	decryptSpan.SetAttributes(
+		attribute.Int64("safetransfer.bytes", written),
+		tracing.Milliseconds("safetransfer.ipfs_read_ms", source.elapsed),
+		tracing.Milliseconds("safetransfer.cipher_ms", decryptedContent.Elapsed()),
+	)
+	tracing.End(decryptSpan, err)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to decode public key: %w", err)
+		return "", fmt.Errorf("failed to read decrypted file content: %w", err)
 	}
+	digest := hash.Sum(nil)
 
-	publicKey, err := x509.ParsePKIXPublicKey(publicKeyBytes)
+	_, verifySpan := tracer.Start(ctx, "file.verify_signature")
+	err = crypto.VerifyHash(digest, fileMetadata.Signature, publicKey)
+	tracing.End(verifySpan, err)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to parse public key: %w", err)
+		return "", classify(ErrFileIntegrity, fmt.Errorf("file verification failed: %w", err))
+	}
+
+	return fmt.Sprintf("%x", digest), nil
+}
+
+// ResolveArchive looks up the files to be included in an archive, so that unknown CIDs, and
+// those of files that are neither owned by nor shared with ethereumAddress, can be reported
+// before any content is streamed.
+func (ds *DownloadService) ResolveArchive(ctx context.Context, ethereumAddress string, cids []string) ([]*model.File, error) {
+	if len(cids) == 0 || len(cids) > MaxArchiveFiles {
+		return nil, fmt.Errorf("%w: between 1 and %d CIDs are required", ErrInvalidArchive, MaxArchiveFiles)
 	}
 
-	rsaPublicKey, ok := publicKey.(*rsa.PublicKey)
-	if !ok {
-		return nil, "", fmt.Errorf("invalid public key type")
+	files := make([]*model.File, 0, len(cids))
+	seen := make(map[string]bool)
+	for _, cid := range cids {
+		if seen[cid] {
+			continue
+		}
+		seen[cid] = true
+
+		fileMetadata, err := ds.getReadableFile(ctx, ethereumAddress, cid)
+		if errors.Is(err, ErrFileNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrFileNotFound, cid)
+		} else if err != nil {
+			return nil, err
+		}
+		files = append(files, fileMetadata)
 	}
+	return files, nil
+}
+
+// WriteArchive streams a ZIP archive of the given files to w, decrypting and verifying each one
+// on the fly. Entries are named after the original file names, falling back to the CID. In the
+// strict chain verification mode each file is also checked on chain once it has been written.
+func (ds *DownloadService) WriteArchive(ctx context.Context, files []*model.File, w io.Writer) error {
+	archive := zip.NewWriter(w)
+	names := make(map[string]int)
 
-	// Convert decrypted data back to a reader for signature verification
-	decryptedReader := bytes.NewReader(decryptedData)
-	if err := crypto.VerifyFile(decryptedReader, fileMetadata.Signature, rsaPublicKey); err != nil {
-		return nil, "", fmt.Errorf("file verification failed: %w", err)
+	for _, fileMetadata := range files {
+		header := &zip.FileHeader{
+			Name:     archiveEntryName(fileMetadata, names),
+			Method:   zip.Deflate,
+			Modified: fileMetadata.CreatedAt,
+		}
+		entry, err := archive.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("failed to create archive entry: %w", err)
+		}
+		sha256Hash, err := ds.StreamFile(ctx, fileMetadata, entry)
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", fileMetadata.CID, err)
+		}
+		if ds.ChainVerifyMode == ChainVerifyStrict {
+			if _, err := ds.verifyOnChain(ctx, fileMetadata, sha256Hash); err != nil {
+				return fmt.Errorf("failed to archive %s: %w", fileMetadata.CID, err)
+			}
+		}
 	}
 
-	// Return the decrypted content as a reader along with the SHA-256 hash string
-	return bytes.NewReader(decryptedData), sha256Hash, nil
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return nil
+}
+
+// archiveEntryName returns a unique, flat entry name for a file, numbering repeated names.
+func archiveEntryName(fileMetadata *model.File, names map[string]int) string {
+	name := path.Base(strings.ReplaceAll(fileMetadata.Name, `\`, "/"))
+	if name == "" || name == "." || name == "/" {
+		name = fileMetadata.CID
+	}
+
+	names[name]++
+	if count := names[name]; count > 1 {
+		ext := path.Ext(name)
+		name = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), count, ext)
+		names[name]++
+	}
+	return name
+}
+
+// getReadableFile retrieves the metadata of a file version, reporting ErrFileNotFound unless its
+// logical file is owned by or shared with ethereumAddress.
+func (ds *DownloadService) getReadableFile(ctx context.Context, ethereumAddress, cid string) (*model.File, error) {
+	fileMetadata, err := ds.FileRepo.GetFileMetadataByCID(ctx, cid)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrFileNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get file metadata: %w", err)
+	}
+
+	if err := checkReadableFile(ctx, ds.FileRepo, ds.ShareRepo, ethereumAddress, fileMetadata); err != nil {
+		return nil, err
+	}
+	return fileMetadata, nil
 }
@@ -0,0 +1,56 @@
+package service
+
+import "errors"
+
+// Kinds of errors. Every error that the services return deliberately matches exactly one of
+// them with errors.Is, which determines how the API reports it. Any other error is internal.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrConflict     = errors.New("conflict")
+	ErrIntegrity    = errors.New("integrity check failed")
+	ErrTooLarge     = errors.New("too large")
+	ErrUnavailable  = errors.New("upstream service unavailable")
+)
+
+// Error is a sentinel error of one of the kinds above, with a stable machine-readable code
+// such as "file_not_found".
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+}
+
+func newError(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// classifiedError attributes an error from a dependency to one of the sentinels while keeping
+// the original message and chain.
+type classifiedError struct {
+	sentinel *Error
+	err      error
+}
+
+// classify makes err match sentinel, and its kind, without changing its message.
+func classify(sentinel *Error, err error) error {
+	return &classifiedError{sentinel: sentinel, err: err}
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.sentinel, e.err}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ExportService collects everything stored about a user, for example to answer a data access
+// request. Exports hold metadata only: neither file content nor key material is included.
+type ExportService struct {
+	UserRepo   repository.UserRepository
+	FileRepo   repository.FileRepository
+	FolderRepo repository.FolderRepository
+	ShareRepo  repository.ShareRepository
+	APIKeyRepo repository.APIKeyRepository
+}
+
+// UserExport is everything stored about a user.
+type UserExport struct {
+	ExportedAt     time.Time        `json:"exportedAt"`
+	User           ExportedUser     `json:"user"`
+	Folders        []ExportedFolder `json:"folders"`
+	Files          []ExportedFile   `json:"files"` // every stored version
+	SharesGranted  []ExportedShare  `json:"sharesGranted"`
+	SharesReceived []ExportedShare  `json:"sharesReceived"`
+	APIKeys        []ExportedAPIKey `json:"apiKeys"`
+}
+
+type ExportedUser struct {
+	EthereumAddress string     `json:"ethereumAddress"`
+	Role            string     `json:"role"`
+	DisabledAt      *time.Time `json:"disabledAt"`
+	BytesStored     int64      `json:"bytesStored"`
+	FileCount       int64      `json:"fileCount"`
+	QuotaBytes      *int64     `json:"quotaBytes"`
+	QuotaFiles      *int64     `json:"quotaFiles"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+type ExportedFolder struct {
+	ID        uint      `json:"id"`
+	ParentID  *uint     `json:"parentId"`
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ExportedFile struct {
+	CID         string    `json:"cid"`
+	FileID      *uint     `json:"fileId"`
+	Version     int       `json:"version"`
+	IsLatest    bool      `json:"isLatest"`
+	Name        string    `json:"name"`
+	FolderID    *uint     `json:"folderId"`
+	Description string    `json:"description"`
+	Tags        []string  `json:"tags"`
+	Size        int64     `json:"size"`
+	MimeType    string    `json:"mimeType"`
+	FileHash    string    `json:"fileHash"`
+	ChainStatus string    `json:"chainStatus,omitempty"`
+	ChainTxHash string    `json:"chainTxHash,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type ExportedShare struct {
+	FileID    uint      `json:"fileId"`
+	Grantee   string    `json:"grantee"`
+	GrantedBy string    `json:"grantedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ExportedAPIKey struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// NewExportService creates a new instance of ExportService with dependencies injected.
+func NewExportService(userRepo repository.UserRepository, fileRepo repository.FileRepository, folderRepo repository.FolderRepository, shareRepo repository.ShareRepository, apiKeyRepo repository.APIKeyRepository) *ExportService {
+	return &ExportService{
+		UserRepo:   userRepo,
+		FileRepo:   fileRepo,
+		FolderRepo: folderRepo,
+		ShareRepo:  shareRepo,
+		APIKeyRepo: apiKeyRepo,
+	}
+}
+
+// ExportUser collects the account, folders, file versions, shares and API keys of a user.
+func (es *ExportService) ExportUser(ctx context.Context, ethereumAddress string) (*UserExport, error) {
+	user, err := es.UserRepo.FindByEthereumAddress(ctx, ethereumAddress)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	export := &UserExport{
+		ExportedAt: time.Now().UTC(),
+		User: ExportedUser{
+			EthereumAddress: user.EthereumAddress,
+			Role:            user.Role,
+			DisabledAt:      user.DisabledAt,
+			BytesStored:     user.BytesStored,
+			FileCount:       user.FileCount,
+			QuotaBytes:      user.QuotaBytes,
+			QuotaFiles:      user.QuotaFiles,
+			CreatedAt:       user.CreatedAt,
+		},
+		Folders:        []ExportedFolder{},
+		Files:          []ExportedFile{},
+		SharesGranted:  []ExportedShare{},
+		SharesReceived: []ExportedShare{},
+		APIKeys:        []ExportedAPIKey{},
+	}
+
+	folders, err := es.FolderRepo.ListFolders(ctx, user.EthereumAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folders: %w", err)
+	}
+	for _, folder := range folders {
+		export.Folders = append(export.Folders, ExportedFolder{
+			ID:        folder.ID,
+			ParentID:  folder.ParentID,
+			Name:      folder.Name,
+			Path:      folder.Path,
+			CreatedAt: folder.CreatedAt,
+		})
+	}
+
+	var logicalFileIDs []uint
+	seen := make(map[uint]bool)
+	var afterID uint
+	for {
+		files, err := es.FileRepo.ListFilesAfter(ctx, user.EthereumAddress, afterID, maintenanceBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+		for _, file := range files {
+			export.Files = append(export.Files, newExportedFile(&file))
+			if file.LogicalFileID != nil && !seen[*file.LogicalFileID] {
+				seen[*file.LogicalFileID] = true
+				logicalFileIDs = append(logicalFileIDs, *file.LogicalFileID)
+			}
+		}
+		if len(files) < maintenanceBatchSize {
+			break
+		}
+		afterID = files[len(files)-1].ID
+	}
+
+	for _, logicalFileID := range logicalFileIDs {
+		shares, err := es.ShareRepo.ListShares(ctx, logicalFileID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list shares: %w", err)
+		}
+		for _, share := range shares {
+			export.SharesGranted = append(export.SharesGranted, newExportedShare(&share))
+		}
+	}
+
+	received, err := es.ShareRepo.ListSharesByGrantee(ctx, model.CanonicalAddress(user.EthereumAddress))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list received shares: %w", err)
+	}
+	for _, share := range received {
+		export.SharesReceived = append(export.SharesReceived, newExportedShare(&share))
+	}
+
+	keys, err := es.APIKeyRepo.ListAPIKeys(ctx, user.EthereumAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	for _, key := range keys {
+		export.APIKeys = append(export.APIKeys, ExportedAPIKey{
+			ID:         key.ID,
+			Name:       key.Name,
+			Prefix:     key.Prefix,
+			Scopes:     []string(key.Scopes),
+			ExpiresAt:  key.ExpiresAt,
+			LastUsedAt: key.LastUsedAt,
+			CreatedAt:  key.CreatedAt,
+		})
+	}
+
+	return export, nil
+}
+
+func newExportedFile(file *model.File) ExportedFile {
+	tags := []string(file.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	return ExportedFile{
+		CID:         file.CID,
+		FileID:      file.LogicalFileID,
+		Version:     file.Version,
+		IsLatest:    file.IsLatest,
+		Name:        file.Name,
+		FolderID:    file.FolderID,
+		Description: file.Description,
+		Tags:        tags,
+		Size:        file.Size,
+		MimeType:    file.MimeType,
+		FileHash:    file.FileHash,
+		ChainStatus: file.ChainStatus,
+		ChainTxHash: file.ChainTxHash,
+		CreatedAt:   file.CreatedAt,
+	}
+}
+
+func newExportedShare(share *model.Share) ExportedShare {
+	return ExportedShare{
+		FileID:    share.LogicalFileID,
+		Grantee:   share.GranteeAddress,
+		GrantedBy: share.GrantedBy,
+		CreatedAt: share.CreatedAt,
+	}
+}
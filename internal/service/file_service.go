@@ -1,88 +1,453 @@
 package service
 
 import (
+	"SafeTransfer/internal/chain"
 	"SafeTransfer/internal/crypto"
+	"SafeTransfer/internal/metrics"
 	"SafeTransfer/internal/model"
 	"SafeTransfer/internal/repository"
 	"SafeTransfer/internal/storage"
+	"SafeTransfer/internal/tracing"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"mime"
 	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	MaxMultipartFormSize = 10 << 20 // 10 MB
 	encryptionKeySize    = 32       // 32 bytes for AES-256
+	maxTags              = 32
+	maxTagSize           = 64
+	maxDescriptionSize   = 4096
+	MaxBatchFiles        = 50
+)
+
+var (
+	ErrInvalidFileDetails = newError(ErrInvalidInput, "invalid_file_details", "invalid file details")
+	ErrInvalidBatch       = newError(ErrInvalidInput, "invalid_batch", "invalid batch upload")
+	ErrInvalidTransfer    = newError(ErrInvalidInput, "invalid_transfer", "new owner must be another user's Ethereum address")
+	ErrInvalidForm        = newError(ErrInvalidInput, "invalid_form", "invalid multipart form")
+	ErrFormTooLarge       = newError(ErrTooLarge, "form_too_large", "multipart form is too large")
 )
 
 type FileService struct {
-	IPFSStorage *storage.IPFSStorage
-	FileRepo    repository.FileRepository
+	IPFSStorage  *storage.IPFSStorage
+	FileRepo     repository.FileRepository
+	FolderRepo   repository.FolderRepository
+	QuotaService *QuotaService
+
+	// RegistrationService, when set, registers every stored file in the FileRegistry contract.
+	RegistrationService *RegistrationService
+
+	// KeyRing wraps the encryption keys of stored files. When nil, keys are stored unwrapped.
+	KeyRing *crypto.KeyRing
+}
+
+// UploadOptions carries the optional metadata supplied alongside an uploaded file.
+type UploadOptions struct {
+	FileName    string
+	FolderID    *uint
+	Description string
+	Tags        []string
+	MimeType    string
+}
+
+// BatchUploadResult is the outcome of storing one file of a batch upload.
+type BatchUploadResult struct {
+	FileName         string
+	File             *model.File
+	OriginalFileHash string
+	Err              error
+}
+
+// FileSearchOptions filters a search over the caller's files. See repository.FileSearchQuery.
+type FileSearchOptions struct {
+	Text          string
+	Tags          []string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	MinSize       int64
+	MaxSize       int64
+	MimeType      string
+	Page          int
+	PageSize      int
 }
 
 // NewFileService creates a new instance of FileService with dependencies injected.
-func NewFileService(ipfsStorage *storage.IPFSStorage, fileRepo repository.FileRepository) *FileService {
+func NewFileService(ipfsStorage *storage.IPFSStorage, fileRepo repository.FileRepository, folderRepo repository.FolderRepository, quotaService *QuotaService, registrationService *RegistrationService, keyRing *crypto.KeyRing) *FileService {
 	return &FileService{
-		IPFSStorage: ipfsStorage,
-		FileRepo:    fileRepo,
+		IPFSStorage:         ipfsStorage,
+		FileRepo:            fileRepo,
+		FolderRepo:          folderRepo,
+		QuotaService:        quotaService,
+		RegistrationService: registrationService,
+		KeyRing:             keyRing,
 	}
 }
 
 // UploadFile handles the uploading of a file, including processing, encryption, and storage.
-func (fs *FileService) UploadFile(file multipart.File, ethereumAddress string) (string, string, error) {
+// The file is saved as the first version of a new logical file.
+func (fs *FileService) UploadFile(ctx context.Context, file multipart.File, ethereumAddress string, opts UploadOptions) (*model.File, string, error) {
+	return fs.uploadWithinQuota(ctx, file, ethereumAddress, opts, fs.FileRepo.SaveFileMetadata)
+}
+
+// UploadFiles stores every file of a batch through the same pipeline as UploadFile. Files are
+// processed independently, so a failure only affects its own result. The file name and MIME type
+// in opts are taken from each file header.
+func (fs *FileService) UploadFiles(ctx context.Context, fileHeaders []*multipart.FileHeader, ethereumAddress string, opts UploadOptions) ([]BatchUploadResult, error) {
+	if len(fileHeaders) == 0 || len(fileHeaders) > MaxBatchFiles {
+		return nil, fmt.Errorf("%w: between 1 and %d files are required", ErrInvalidBatch, MaxBatchFiles)
+	}
+
+	results := make([]BatchUploadResult, 0, len(fileHeaders))
+	for _, fileHeader := range fileHeaders {
+		result := BatchUploadResult{FileName: fileHeader.Filename}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			result.Err = fmt.Errorf("failed to open uploaded file: %w", err)
+			results = append(results, result)
+			continue
+		}
+
+		fileOpts := opts
+		fileOpts.FileName = fileHeader.Filename
+		fileOpts.MimeType = fileHeader.Header.Get("Content-Type")
+		result.File, result.OriginalFileHash, result.Err = fs.UploadFile(ctx, file, ethereumAddress, fileOpts)
+		file.Close()
+
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// uploadWithinQuota reserves quota for the file before anything is written, then stores it and
+// persists its metadata with save. The reservation is released if any step fails. Saved files
+// are registered on chain when registration is enabled.
+func (fs *FileService) uploadWithinQuota(ctx context.Context, file io.ReadSeeker, ethereumAddress string, opts UploadOptions, save func(context.Context, *model.File) error) (*model.File, string, error) {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to determine file size: %w", err)
+	}
+
+	ctx, span := tracer.Start(ctx, "file.upload", trace.WithAttributes(attribute.Int64("safetransfer.bytes", size)))
+	fileMetadata, originalFileHash, err := fs.upload(ctx, file, size, ethereumAddress, opts, save)
+	if err == nil {
+		span.SetAttributes(attribute.String("ipfs.cid", fileMetadata.CID))
+	}
+	tracing.End(span, err)
+	return fileMetadata, originalFileHash, err
+}
+
+func (fs *FileService) upload(ctx context.Context, file io.ReadSeeker, size int64, ethereumAddress string, opts UploadOptions, save func(context.Context, *model.File) error) (*model.File, string, error) {
+	if err := fs.QuotaService.Reserve(ctx, ethereumAddress, size); err != nil {
+		return nil, "", err
+	}
+
+	done := metrics.StartTransfer(metrics.Upload)
+	fileMetadata, originalFileHash, err := fs.storeFile(ctx, file, ethereumAddress, opts)
+	done()
+	if err == nil {
+		if fs.RegistrationService != nil {
+			fileMetadata.ChainStatus = chain.StatusPending
+		}
+		err = save(ctx, fileMetadata)
+	}
+	if err != nil {
+		fs.QuotaService.Release(ctx, ethereumAddress, size, 1)
+		return nil, "", err
+	}
+
+	metrics.BytesUploaded.Add(float64(size))
+	if fs.RegistrationService != nil {
+		fs.RegistrationService.RegisterAsync(ctx, fileMetadata, originalFileHash)
+	}
+	return fileMetadata, originalFileHash, nil
+}
+
+// storeFile signs, encrypts and uploads a file to IPFS, returning its metadata, not yet saved,
+// along with the SHA-256 hash of the original content.
+func (fs *FileService) storeFile(ctx context.Context, file io.ReadSeeker, ethereumAddress string, opts UploadOptions) (*model.File, string, error) {
+	tags, err := NormalizeTags(opts.Tags)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(opts.Description) > maxDescriptionSize {
+		return nil, "", fmt.Errorf("%w: description exceeds %d bytes", ErrInvalidFileDetails, maxDescriptionSize)
+	}
+
+	if opts.FolderID != nil {
+		folder, err := fs.FolderRepo.GetFolderByID(ctx, *opts.FolderID)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && !sameAddress(folder.EthereumAddress, ethereumAddress)) {
+			return nil, "", ErrFolderNotFound
+		} else if err != nil {
+			return nil, "", fmt.Errorf("failed to get folder: %w", err)
+		}
+	}
+
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to determine file size: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, "", fmt.Errorf("failed to reset file reader: %w", err)
+	}
+	mimeType, err := detectMimeType(file, opts.MimeType)
+	if err != nil {
+		return nil, "", err
+	}
+
 	// Generate a new key pair for each file
+	_, signSpan := tracer.Start(ctx, "file.sign")
 	privateKey, err := generateRSAKeyPair(2048)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to generate private key: %w", err)
+		tracing.End(signSpan, err)
+		return nil, "", fmt.Errorf("failed to generate private key: %w", err)
 	}
 
 	signatureStr, publicKeyStr, key, originalFileHashStr, err := fs.processFile(file, privateKey)
+	tracing.End(signSpan, err)
 	if err != nil {
-		return "", "", err
+		return nil, "", err
 	}
 
 	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		return "", "", fmt.Errorf("failed to reset file reader: %w", err)
+		return nil, "", fmt.Errorf("failed to reset file reader: %w", err)
 	}
 
 	// Verify the file signature
 	publicKey, err := parsePublicKey(publicKeyStr)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to parse public key: %w", err)
+		return nil, "", fmt.Errorf("failed to parse public key: %w", err)
 	}
-	if err := crypto.VerifyFile(file, signatureStr, publicKey); err != nil {
-		return "", "", fmt.Errorf("file verification failed: %w", err)
+	_, verifySpan := tracer.Start(ctx, "file.verify_signature")
+	err = crypto.VerifyFile(file, signatureStr, publicKey)
+	tracing.End(verifySpan, err)
+	if err != nil {
+		return nil, "", fmt.Errorf("file verification failed: %w", err)
 	}
 
 	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		return "", "", fmt.Errorf("failed to reset file reader: %w", err)
+		return nil, "", fmt.Errorf("failed to reset file reader: %w", err)
+	}
+
+	cid, nonce, err := fs.IPFSStorage.UploadFileToIPFS(ctx, file, key)
+	if err != nil {
+		return nil, "", classify(ErrStorageUnavailable, err)
 	}
 
-	cid, nonce, err := fs.IPFSStorage.UploadFileToIPFS(file, key)
+	wrappedKey, err := fs.KeyRing.Wrap(key)
 	if err != nil {
-		return "", "", err
+		return nil, "", fmt.Errorf("failed to wrap encryption key: %w", err)
 	}
 
 	nonceStr := base64.StdEncoding.EncodeToString(nonce)
 	fileMetadata := &model.File{
 		CID:             cid,
-		EncryptionKey:   base64.StdEncoding.EncodeToString(key),
+		EncryptionKey:   wrappedKey,
 		Nonce:           nonceStr,
 		Signature:       signatureStr,
 		EthereumAddress: ethereumAddress,
 		PublicKey:       publicKeyStr,
+		FolderID:        opts.FolderID,
+		Name:            opts.FileName,
+		Description:     opts.Description,
+		Tags:            tags,
+		Size:            size,
+		MimeType:        mimeType,
+		FileHash:        originalFileHashStr,
+	}
+
+	return fileMetadata, originalFileHashStr, nil
+}
+
+// DeleteFile deletes a logical file with all of its versions, unpins their content and returns
+// their storage to the owner's quota.
+func (fs *FileService) DeleteFile(ctx context.Context, ethereumAddress string, logicalFileID uint) error {
+	logicalFile, err := getOwnedLogicalFile(ctx, fs.FileRepo, ethereumAddress, logicalFileID)
+	if err != nil {
+		return err
+	}
+	return fs.deleteLogicalFile(ctx, logicalFile)
+}
+
+// ForceDeleteFile deletes a logical file regardless of its owner, for moderation by admins.
+// It returns the deleted file so that the caller can record whose content was removed.
+func (fs *FileService) ForceDeleteFile(ctx context.Context, logicalFileID uint) (*model.LogicalFile, error) {
+	logicalFile, err := getLogicalFile(ctx, fs.FileRepo, logicalFileID)
+	if err != nil {
+		return nil, err
+	}
+	return logicalFile, fs.deleteLogicalFile(ctx, logicalFile)
+}
+
+// deleteLogicalFile deletes a logical file with its versions and shares, unpins the versions
+// and releases the quota they used.
+func (fs *FileService) deleteLogicalFile(ctx context.Context, logicalFile *model.LogicalFile) error {
+	versions, err := fs.FileRepo.DeleteLogicalFile(ctx, logicalFile.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	for _, version := range versions {
+		if err := fs.IPFSStorage.UnpinFile(ctx, version.CID); err != nil {
+			log.Printf("Failed to unpin deleted version %s: %v", version.CID, err)
+		}
+		fs.QuotaService.Release(ctx, version.EthereumAddress, version.Size, 1)
+	}
+	return nil
+}
+
+// TransferOwnership hands a logical file and all of its versions over to newOwner, who must
+// have signed in before and have room for them in their quota. The file is placed in the new
+// owner's root folder and its shares are revoked.
+func (fs *FileService) TransferOwnership(ctx context.Context, ethereumAddress string, logicalFileID uint, newOwner string) (*model.LogicalFile, error) {
+	if !common.IsHexAddress(newOwner) || sameAddress(newOwner, ethereumAddress) {
+		return nil, ErrInvalidTransfer
+	}
+
+	logicalFile, err := getOwnedLogicalFile(ctx, fs.FileRepo, ethereumAddress, logicalFileID)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := fs.FileRepo.ListFileVersions(ctx, logicalFile.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list file versions: %w", err)
+	}
+	var size int64
+	for _, version := range versions {
+		size += version.Size
+	}
+	files := int64(len(versions))
+
+	if err := fs.QuotaService.ReserveFiles(ctx, newOwner, size, files); err != nil {
+		return nil, err
+	}
+	err = fs.FileRepo.TransferLogicalFile(ctx, logicalFile.ID, ethereumAddress, newOwner)
+	if errors.Is(err, repository.ErrNotFound) {
+		err = ErrFileNotFound
+	} else if err != nil {
+		err = fmt.Errorf("failed to transfer file: %w", err)
+	}
+	if err != nil {
+		fs.QuotaService.Release(ctx, newOwner, size, files)
+		return nil, err
 	}
+	fs.QuotaService.Release(ctx, ethereumAddress, size, files)
 
-	if err := fs.FileRepo.SaveFileMetadata(fileMetadata); err != nil {
-		return "", "", err
+	logicalFile.EthereumAddress = newOwner
+	return logicalFile, nil
+}
+
+// UpdateFileDetails replaces the description and tags of a file owned by ethereumAddress.
+func (fs *FileService) UpdateFileDetails(ctx context.Context, ethereumAddress, cid, description string, tags []string) (*model.File, error) {
+	normalized, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(description) > maxDescriptionSize {
+		return nil, fmt.Errorf("%w: description exceeds %d bytes", ErrInvalidFileDetails, maxDescriptionSize)
+	}
+
+	file, err := fs.FileRepo.GetFileMetadataByCID(ctx, cid)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !sameAddress(file.EthereumAddress, ethereumAddress)) {
+		return nil, ErrFileNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get file metadata: %w", err)
+	}
+
+	if err := fs.FileRepo.UpdateFileDetails(ctx, cid, description, normalized); err != nil {
+		return nil, fmt.Errorf("failed to update file details: %w", err)
+	}
+	file.Description = description
+	file.Tags = normalized
+	return file, nil
+}
+
+// SearchFiles runs a full-text search over the metadata of the files owned by ethereumAddress
+// and returns one page of results along with the total number of matches.
+func (fs *FileService) SearchFiles(ctx context.Context, ethereumAddress string, opts FileSearchOptions) ([]model.File, int64, error) {
+	tags, err := NormalizeTags(opts.Tags)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	files, total, err := fs.FileRepo.SearchFiles(ctx, repository.FileSearchQuery{
+		EthereumAddress: ethereumAddress,
+		Text:            strings.TrimSpace(opts.Text),
+		Tags:            tags,
+		CreatedAfter:    opts.CreatedAfter,
+		CreatedBefore:   opts.CreatedBefore,
+		MinSize:         opts.MinSize,
+		MaxSize:         opts.MaxSize,
+		MimeType:        strings.ToLower(opts.MimeType),
+		Limit:           opts.PageSize,
+		Offset:          (opts.Page - 1) * opts.PageSize,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search files: %w", err)
+	}
+	return files, total, nil
+}
+
+// NormalizeTags lowercases, trims and de-duplicates tags, rejecting lists that are too long
+// or contain oversized tags.
+func NormalizeTags(tags []string) (model.Tags, error) {
+	normalized := model.Tags{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagSize {
+			return nil, fmt.Errorf("%w: tag %q exceeds %d bytes", ErrInvalidFileDetails, tag, maxTagSize)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTags {
+		return nil, fmt.Errorf("%w: at most %d tags are allowed", ErrInvalidFileDetails, maxTags)
+	}
+	return normalized, nil
+}
+
+// detectMimeType returns the declared MIME type, or sniffs it from the first bytes of the file
+// when the client did not send a specific one. The file must be positioned at its start and is
+// left there.
+func detectMimeType(file io.ReadSeeker, declared string) (string, error) {
+	if mediaType, _, err := mime.ParseMediaType(declared); err == nil && mediaType != "application/octet-stream" {
+		return mediaType, nil
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read file header: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to reset file reader: %w", err)
 	}
 
-	return cid, originalFileHashStr, nil
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	return mediaType, nil
 }
 
 // parsePublicKey parses a base64-encoded public key string into an *rsa.PublicKey.
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	DefaultPageSize   = 50
+	MaxPageSize       = 200
+	maxFolderNameSize = 255
+)
+
+var (
+	ErrFolderNotFound    = newError(ErrNotFound, "folder_not_found", "folder not found")
+	ErrFolderExists      = newError(ErrConflict, "folder_exists", "a folder with this name already exists here")
+	ErrFolderNotEmpty    = newError(ErrConflict, "folder_not_empty", "folder is not empty")
+	ErrInvalidFolderName = newError(ErrInvalidInput, "invalid_folder_name", "invalid folder name")
+	ErrInvalidFolderMove = newError(ErrInvalidInput, "invalid_folder_move", "a folder cannot be moved into itself or one of its subfolders")
+	ErrFileNotFound      = newError(ErrNotFound, "file_not_found", "file not found")
+	ErrInvalidPagination = newError(ErrInvalidInput, "invalid_pagination", "invalid pagination")
+)
+
+type FolderService struct {
+	FolderRepo repository.FolderRepository
+	FileRepo   repository.FileRepository
+}
+
+// FolderContents is one page of a folder listing. Subfolders are listed before files.
+type FolderContents struct {
+	Folder   *model.Folder
+	Folders  []model.Folder
+	Files    []model.File
+	Page     int
+	PageSize int
+	Total    int64
+}
+
+// NewFolderService creates a new instance of FolderService with dependencies injected.
+func NewFolderService(folderRepo repository.FolderRepository, fileRepo repository.FileRepository) *FolderService {
+	return &FolderService{
+		FolderRepo: folderRepo,
+		FileRepo:   fileRepo,
+	}
+}
+
+// CreateFolder creates a folder owned by ethereumAddress under parentID, or at the root when parentID is nil.
+func (fs *FolderService) CreateFolder(ctx context.Context, ethereumAddress, name string, parentID *uint) (*model.Folder, error) {
+	name, err := validateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	parentPath := ""
+	if parentID != nil {
+		parent, err := fs.GetOwnedFolder(ctx, ethereumAddress, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		parentPath = parent.Path
+	}
+
+	folder := &model.Folder{
+		EthereumAddress: ethereumAddress,
+		ParentID:        parentID,
+		Name:            name,
+		Path:            parentPath + "/" + name,
+	}
+	if err := fs.FolderRepo.CreateFolder(ctx, folder); err != nil {
+		return nil, translateFolderError(err)
+	}
+	return folder, nil
+}
+
+// RenameFolder changes the name of a folder, updating the paths of everything beneath it.
+func (fs *FolderService) RenameFolder(ctx context.Context, ethereumAddress string, id uint, name string) (*model.Folder, error) {
+	name, err := validateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := fs.GetOwnedFolder(ctx, ethereumAddress, id)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPath := folder.Path
+	folder.Name = name
+	folder.Path = oldPath[:strings.LastIndex(oldPath, "/")+1] + name
+	if err := fs.FolderRepo.UpdateFolder(ctx, folder, oldPath); err != nil {
+		return nil, translateFolderError(err)
+	}
+	return folder, nil
+}
+
+// MoveFolder moves a folder under a new parent, or to the root when parentID is nil.
+func (fs *FolderService) MoveFolder(ctx context.Context, ethereumAddress string, id uint, parentID *uint) (*model.Folder, error) {
+	folder, err := fs.GetOwnedFolder(ctx, ethereumAddress, id)
+	if err != nil {
+		return nil, err
+	}
+
+	parentPath := ""
+	if parentID != nil {
+		parent, err := fs.GetOwnedFolder(ctx, ethereumAddress, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.ID == folder.ID || strings.HasPrefix(parent.Path, folder.Path+"/") {
+			return nil, ErrInvalidFolderMove
+		}
+		parentPath = parent.Path
+	}
+
+	oldPath := folder.Path
+	folder.ParentID = parentID
+	folder.Path = parentPath + "/" + folder.Name
+	if err := fs.FolderRepo.UpdateFolder(ctx, folder, oldPath); err != nil {
+		return nil, translateFolderError(err)
+	}
+	return folder, nil
+}
+
+// DeleteFolder deletes an empty folder.
+func (fs *FolderService) DeleteFolder(ctx context.Context, ethereumAddress string, id uint) error {
+	folder, err := fs.GetOwnedFolder(ctx, ethereumAddress, id)
+	if err != nil {
+		return err
+	}
+
+	subfolders, err := fs.FolderRepo.CountSubfolders(ctx, ethereumAddress, &folder.ID)
+	if err != nil {
+		return fmt.Errorf("failed to count subfolders: %w", err)
+	}
+	files, err := fs.FileRepo.CountFilesInFolder(ctx, ethereumAddress, &folder.ID)
+	if err != nil {
+		return fmt.Errorf("failed to count files: %w", err)
+	}
+	if subfolders+files > 0 {
+		return ErrFolderNotEmpty
+	}
+
+	return fs.FolderRepo.DeleteFolder(ctx, folder.ID)
+}
+
+// ListFolder returns one page of a folder's subfolders followed by its files.
+// A nil id lists the owner's root folder.
+func (fs *FolderService) ListFolder(ctx context.Context, ethereumAddress string, id *uint, page, pageSize int) (*FolderContents, error) {
+	contents := &FolderContents{Page: page, PageSize: pageSize}
+	if id != nil {
+		folder, err := fs.GetOwnedFolder(ctx, ethereumAddress, *id)
+		if err != nil {
+			return nil, err
+		}
+		contents.Folder = folder
+	}
+
+	folderCount, err := fs.FolderRepo.CountSubfolders(ctx, ethereumAddress, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count subfolders: %w", err)
+	}
+	fileCount, err := fs.FileRepo.CountFilesInFolder(ctx, ethereumAddress, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count files: %w", err)
+	}
+	contents.Total = folderCount + fileCount
+
+	// Subfolders fill the first pages; files continue where they run out.
+	offset := int64((page - 1) * pageSize)
+	if offset < folderCount {
+		contents.Folders, err = fs.FolderRepo.ListSubfolders(ctx, ethereumAddress, id, pageSize, int(offset))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list subfolders: %w", err)
+		}
+	}
+
+	remaining := pageSize - len(contents.Folders)
+	if remaining > 0 && offset+int64(len(contents.Folders)) < contents.Total {
+		fileOffset := max(offset-folderCount, 0)
+		contents.Files, err = fs.FileRepo.ListFilesInFolder(ctx, ethereumAddress, id, remaining, int(fileOffset))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+	}
+
+	return contents, nil
+}
+
+// MoveFile moves a file owned by ethereumAddress into a folder, or to the root when folderID is nil.
+func (fs *FolderService) MoveFile(ctx context.Context, ethereumAddress, cid string, folderID *uint) error {
+	file, err := fs.FileRepo.GetFileMetadataByCID(ctx, cid)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !sameAddress(file.EthereumAddress, ethereumAddress)) {
+		return ErrFileNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to get file metadata: %w", err)
+	}
+
+	if folderID != nil {
+		if _, err := fs.GetOwnedFolder(ctx, ethereumAddress, *folderID); err != nil {
+			return err
+		}
+	}
+
+	return fs.FileRepo.UpdateFileFolder(ctx, cid, folderID)
+}
+
+// GetOwnedFolder retrieves a folder by ID, reporting ErrFolderNotFound if it belongs to someone else.
+func (fs *FolderService) GetOwnedFolder(ctx context.Context, ethereumAddress string, id uint) (*model.Folder, error) {
+	folder, err := fs.FolderRepo.GetFolderByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrFolderNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get folder: %w", err)
+	}
+	if !sameAddress(folder.EthereumAddress, ethereumAddress) {
+		return nil, ErrFolderNotFound
+	}
+	return folder, nil
+}
+
+// validateFolderName trims a folder name and checks that it can be used as a path segment.
+func validateFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") || len(name) > maxFolderNameSize {
+		return "", ErrInvalidFolderName
+	}
+	return name, nil
+}
+
+// translateFolderError maps a unique path violation to ErrFolderExists.
+func translateFolderError(err error) error {
+	if errors.Is(err, repository.ErrDuplicate) {
+		return ErrFolderExists
+	}
+	return fmt.Errorf("failed to save folder: %w", err)
+}
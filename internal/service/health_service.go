@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Statuses of a readiness report and of its checks.
+const (
+	HealthOK          = "ok"
+	HealthDegraded    = "degraded"    // a non-critical dependency is failing
+	HealthUnavailable = "unavailable" // a critical dependency is failing
+)
+
+// HealthCheck checks that a dependency is reachable.
+type HealthCheck struct {
+	Name     string
+	Check    func(ctx context.Context) error
+	Critical bool // whether the server is not ready while the check fails
+}
+
+// DependencyStatus is the result of a HealthCheck.
+type DependencyStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport is the result of all checks.
+type HealthReport struct {
+	Status       string             `json:"status"`
+	CheckedAt    time.Time          `json:"checkedAt"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// Ready reports whether every critical dependency is reachable.
+func (r *HealthReport) Ready() bool {
+	return r.Status != HealthUnavailable
+}
+
+// HealthService checks the dependencies of the server for readiness probes. Reports are cached
+// for CacheTTL, so that frequent probes from several sources do not hammer the dependencies.
+type HealthService struct {
+	Checks   []HealthCheck
+	Timeout  time.Duration // per check
+	CacheTTL time.Duration
+
+	mu     sync.Mutex
+	report *HealthReport
+}
+
+func NewHealthService(checks ...HealthCheck) *HealthService {
+	return &HealthService{
+		Checks:   checks,
+		Timeout:  2 * time.Second,
+		CacheTTL: 5 * time.Second,
+	}
+}
+
+// Readiness returns the cached report, or runs the checks concurrently when it has expired.
+// Concurrent callers wait for the same run instead of starting their own.
+func (hs *HealthService) Readiness() *HealthReport {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.report != nil && time.Since(hs.report.CheckedAt) < hs.CacheTTL {
+		return hs.report
+	}
+
+	report := &HealthReport{
+		Status:       HealthOK,
+		CheckedAt:    time.Now(),
+		Dependencies: make([]DependencyStatus, len(hs.Checks)),
+	}
+	var wg sync.WaitGroup
+	for i, check := range hs.Checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			report.Dependencies[i] = hs.run(check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, dependency := range report.Dependencies {
+		if dependency.Status == HealthOK {
+			continue
+		}
+		if dependency.Critical {
+			report.Status = HealthUnavailable
+		} else if report.Status == HealthOK {
+			report.Status = HealthDegraded
+		}
+	}
+
+	hs.report = report
+	return report
+}
+
+// run runs a single check. The check is not bound to the request that triggered it, since its
+// result is shared with other callers. Errors are logged but not reported, as the endpoint is
+// public and they may reveal internal addresses.
+func (hs *HealthService) run(check HealthCheck) DependencyStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), hs.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	status := DependencyStatus{
+		Name:      check.Name,
+		Status:    HealthOK,
+		Critical:  check.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		log.Printf("Health check %s failed: %v", check.Name, err)
+		status.Status = HealthUnavailable
+		status.Error = "unreachable"
+		if errors.Is(err, context.DeadlineExceeded) {
+			status.Error = "timed out"
+		}
+	}
+	return status
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"SafeTransfer/internal/chain"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+const (
+	DefaultIndexerBatchSize  = 2000
+	MaxReconciliationResults = 1000
+)
+
+// Reconciliation compares the indexed registrations with the stored files.
+type Reconciliation struct {
+	LastBlock      uint64
+	MissingLocally []model.ChainEvent // registered on chain, but no file is stored with the CID
+	MissingOnChain []model.File       // stored, but without a confirmed registration
+}
+
+// ChainIndexer polls FileRegistered logs from the FileRegistry contract into the database.
+// Logs are indexed as soon as they are mined but only confirmed once Confirmations blocks
+// include them; unconfirmed logs are re-read on every poll, so a reorganization within that
+// window replaces them with the logs of the new chain.
+type ChainIndexer struct {
+	Registry      *chain.Registry
+	EventRepo     repository.ChainEventRepository
+	StartBlock    uint64 // block to start scanning from, usually the contract's deployment block
+	Confirmations uint64 // number of blocks, including its own, before a log is confirmed
+	BatchSize     uint64 // maximum number of blocks requested per eth_getLogs call
+	PollInterval  time.Duration
+}
+
+// NewChainIndexer creates a new instance of ChainIndexer with dependencies injected.
+func NewChainIndexer(registry *chain.Registry, eventRepo repository.ChainEventRepository, startBlock, confirmations uint64, pollInterval time.Duration) *ChainIndexer {
+	if confirmations == 0 {
+		confirmations = 1
+	}
+	return &ChainIndexer{
+		Registry:      registry,
+		EventRepo:     eventRepo,
+		StartBlock:    startBlock,
+		Confirmations: confirmations,
+		BatchSize:     DefaultIndexerBatchSize,
+		PollInterval:  pollInterval,
+	}
+}
+
+// Run polls until ctx is done. Failures are logged and retried on the next poll.
+func (ci *ChainIndexer) Run(ctx context.Context) {
+	ticker := time.NewTicker(ci.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := ci.Poll(ctx); err != nil {
+			log.Printf("Failed to index FileRegistered events: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll indexes the logs up to the latest block, re-reading the blocks that are not yet
+// confirmed, and marks the files whose registration has been confirmed.
+func (ci *ChainIndexer) Poll(ctx context.Context) error {
+	contract := ci.contractAddress()
+
+	head, err := ci.Registry.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	lastBlock, scanned, err := ci.EventRepo.GetCursor(ctx, contract)
+	if err != nil {
+		return fmt.Errorf("failed to get indexer cursor: %w", err)
+	}
+
+	// Resume from the first block that the previous poll left unconfirmed
+	from := ci.StartBlock
+	if scanned && lastBlock+2 > ci.Confirmations {
+		from = max(from, lastBlock+2-ci.Confirmations)
+	}
+
+	var confirmedThrough uint64
+	if head+1 > ci.Confirmations {
+		confirmedThrough = head + 1 - ci.Confirmations
+	}
+
+	for from <= head {
+		to := min(head, from+ci.BatchSize-1)
+
+		events, err := ci.fetchEvents(ctx, from, to)
+		if err != nil {
+			return err
+		}
+		if err := ci.EventRepo.SaveEvents(ctx, contract, from, events, confirmedThrough, to); err != nil {
+			return fmt.Errorf("failed to save FileRegistered events: %w", err)
+		}
+		from = to + 1
+	}
+
+	confirmed, err := ci.EventRepo.ConfirmFileRegistrations(ctx, contract)
+	if err != nil {
+		return fmt.Errorf("failed to confirm file registrations: %w", err)
+	}
+	if confirmed > 0 {
+		log.Printf("Confirmed the on-chain registration of %d files from indexed events", confirmed)
+	}
+	return nil
+}
+
+// Reconcile reports the registrations without a stored file and the stored files without a
+// confirmed registration, returning at most limit of each.
+func (ci *ChainIndexer) Reconcile(ctx context.Context, limit int) (*Reconciliation, error) {
+	if limit <= 0 || limit > MaxReconciliationResults {
+		limit = MaxReconciliationResults
+	}
+	contract := ci.contractAddress()
+
+	lastBlock, _, err := ci.EventRepo.GetCursor(ctx, contract)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get indexer cursor: %w", err)
+	}
+
+	missingLocally, err := ci.EventRepo.ListUnmatchedEvents(ctx, contract, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unmatched events: %w", err)
+	}
+
+	missingOnChain, err := ci.EventRepo.ListUnregisteredFiles(ctx, contract, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unregistered files: %w", err)
+	}
+
+	return &Reconciliation{
+		LastBlock:      lastBlock,
+		MissingLocally: missingLocally,
+		MissingOnChain: missingOnChain,
+	}, nil
+}
+
+// fetchEvents reads the FileRegistered logs of blocks from through to.
+func (ci *ChainIndexer) fetchEvents(ctx context.Context, from, to uint64) ([]model.ChainEvent, error) {
+	iter, err := ci.Registry.Contract().FilterFileRegistered(&bind.FilterOpts{Start: from, End: &to, Context: ctx}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter FileRegistered events: %w", err)
+	}
+	defer iter.Close()
+
+	var events []model.ChainEvent
+	for iter.Next() {
+		event := iter.Event
+		if event.Raw.Removed {
+			continue
+		}
+		events = append(events, model.ChainEvent{
+			ContractAddress: ci.contractAddress(),
+			BlockNumber:     event.Raw.BlockNumber,
+			LogIndex:        event.Raw.Index,
+			BlockHash:       event.Raw.BlockHash.Hex(),
+			TxHash:          event.Raw.TxHash.Hex(),
+			Owner:           event.Owner.Hex(),
+			CID:             event.Cid,
+			FileHash:        event.FileHash,
+		})
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to read FileRegistered events: %w", err)
+	}
+	return events, nil
+}
+
+func (ci *ChainIndexer) contractAddress() string {
+	return ci.Registry.Address().Hex()
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"SafeTransfer/internal/crypto"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// maintenanceBatchSize is how many file versions the maintenance tasks load at a time.
+const maintenanceBatchSize = 500
+
+var ErrNoKEK = errors.New("no KEK is configured")
+
+// MaintenanceService runs the operator tasks that go over every stored file version: rewrapping
+// encryption keys, checking the integrity of stored content and releasing orphaned content.
+type MaintenanceService struct {
+	IPFSStorage     *storage.IPFSStorage
+	FileRepo        repository.FileRepository
+	DownloadService *DownloadService
+	KeyRing         *crypto.KeyRing
+}
+
+// VerifyResult is the outcome of checking one stored file version.
+type VerifyResult struct {
+	File *model.File
+	Hash string // SHA-256 of the decrypted content, empty if it could not be read
+	Err  error
+}
+
+// NewMaintenanceService creates a new instance of MaintenanceService with dependencies injected.
+func NewMaintenanceService(ipfsStorage *storage.IPFSStorage, fileRepo repository.FileRepository, downloadService *DownloadService, keyRing *crypto.KeyRing) *MaintenanceService {
+	return &MaintenanceService{
+		IPFSStorage:     ipfsStorage,
+		FileRepo:        fileRepo,
+		DownloadService: downloadService,
+		KeyRing:         keyRing,
+	}
+}
+
+// eachFile calls fn with every stored file version, in ID order.
+func (ms *MaintenanceService) eachFile(ctx context.Context, fn func(*model.File) error) error {
+	var afterID uint
+	for {
+		files, err := ms.FileRepo.ListFilesAfter(ctx, "", afterID, maintenanceBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		for i := range files {
+			if err := fn(&files[i]); err != nil {
+				return err
+			}
+		}
+		if len(files) < maintenanceBatchSize {
+			return nil
+		}
+		afterID = files[len(files)-1].ID
+	}
+}
+
+// RotateKeys rewraps every encryption key that is not wrapped with the current KEK, including
+// keys stored before a KEK was configured, and returns how many were rewrapped. It stops at the
+// first key it cannot unwrap, leaving the keys rewrapped so far in place, so it can be run again
+// once the missing KEK is configured as a previous KEK.
+func (ms *MaintenanceService) RotateKeys(ctx context.Context) (int, error) {
+	if ms.KeyRing == nil {
+		return 0, ErrNoKEK
+	}
+
+	rewrapped := 0
+	err := ms.eachFile(ctx, func(file *model.File) error {
+		if !ms.KeyRing.NeedsRewrap(file.EncryptionKey) {
+			return nil
+		}
+		key, err := ms.KeyRing.Unwrap(file.EncryptionKey)
+		if err != nil {
+			return fmt.Errorf("failed to unwrap the key of %s: %w", file.CID, err)
+		}
+		wrapped, err := ms.KeyRing.Wrap(key)
+		if err != nil {
+			return fmt.Errorf("failed to wrap the key of %s: %w", file.CID, err)
+		}
+		if err := ms.FileRepo.UpdateEncryptionKey(ctx, file.ID, wrapped); err != nil {
+			return fmt.Errorf("failed to update the key of %s: %w", file.CID, err)
+		}
+		rewrapped++
+		return nil
+	})
+	return rewrapped, err
+}
+
+// VerifyAll downloads, decrypts and checks the signature of every stored file version, and
+// compares its content with the recorded hash where there is one. Each result is passed to
+// report. It returns the number of versions checked and of those that failed; an error is only
+// returned if the files cannot be listed.
+func (ms *MaintenanceService) VerifyAll(ctx context.Context, report func(VerifyResult)) (checked, failed int, err error) {
+	err = ms.eachFile(ctx, func(file *model.File) error {
+		hash, err := ms.DownloadService.StreamFile(ctx, file, io.Discard)
+		if err == nil && file.FileHash != "" && hash != file.FileHash {
+			err = fmt.Errorf("%w: content hash %s does not match the recorded %s", ErrFileIntegrity, hash, file.FileHash)
+		}
+
+		checked++
+		if err != nil {
+			failed++
+		}
+		report(VerifyResult{File: file, Hash: hash, Err: err})
+		return nil
+	})
+	return checked, failed, err
+}
+
+// FindOrphanedPins returns, sorted, the CIDs pinned on the IPFS node that no stored file version
+// refers to. They include the content of uploads still in progress, so they should only be
+// unpinned with UnpinOrphans after a grace period.
+func (ms *MaintenanceService) FindOrphanedPins(ctx context.Context) ([]string, error) {
+	pins, err := ms.IPFSStorage.ListPins(ctx)
+	if err != nil {
+		return nil, classify(ErrStorageUnavailable, err)
+	}
+
+	referenced := make(map[string]bool)
+	err = ms.eachFile(ctx, func(file *model.File) error {
+		referenced[file.CID] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []string
+	for _, cid := range pins {
+		if !referenced[cid] {
+			orphans = append(orphans, cid)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans, nil
+}
+
+// UnpinOrphans unpins those of cids that are still not referenced by any stored file version,
+// returning the CIDs it unpinned.
+func (ms *MaintenanceService) UnpinOrphans(ctx context.Context, cids []string) ([]string, error) {
+	var unpinned []string
+	for _, cid := range cids {
+		_, err := ms.FileRepo.GetFileMetadataByCID(ctx, cid)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return unpinned, fmt.Errorf("failed to get file metadata: %w", err)
+		}
+
+		if err := ms.IPFSStorage.UnpinFile(ctx, cid); err != nil {
+			return unpinned, classify(ErrStorageUnavailable, err)
+		}
+		unpinned = append(unpinned, cid)
+	}
+	return unpinned, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"SafeTransfer/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+var (
+	ErrQuotaExceeded = newError(ErrConflict, "quota_exceeded", "storage quota exceeded")
+	ErrUserNotFound  = newError(ErrNotFound, "user_not_found", "user not found")
+	ErrInvalidQuota  = newError(ErrInvalidInput, "invalid_quota", "quotas must be zero (unlimited) or positive")
+)
+
+// QuotaExceededError reports which quota an upload would exceed. It matches ErrQuotaExceeded.
+type QuotaExceededError struct {
+	Resource  string // "bytes" or "files"
+	Used      int64
+	Requested int64
+	Limit     int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("storage quota exceeded: %d of %d %s used, upload needs %d more", e.Used, e.Limit, e.Resource, e.Requested)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// TooLarge reports whether the upload could never fit, even with no other files stored.
+func (e *QuotaExceededError) TooLarge() bool {
+	return e.Requested > e.Limit
+}
+
+// Usage describes how much storage a user consumes and the quotas that apply to them.
+// A limit of zero means unlimited.
+type Usage struct {
+	BytesStored int64
+	FileCount   int64
+	QuotaBytes  int64
+	QuotaFiles  int64
+	Overridden  bool
+}
+
+// QuotaService tracks per-user storage usage and enforces quotas.
+type QuotaService struct {
+	UserRepo          repository.UserRepository
+	DefaultQuotaBytes int64
+	DefaultQuotaFiles int64
+}
+
+// NewQuotaService creates a new instance of QuotaService with dependencies injected.
+func NewQuotaService(userRepo repository.UserRepository, defaultQuotaBytes, defaultQuotaFiles int64) *QuotaService {
+	return &QuotaService{
+		UserRepo:          userRepo,
+		DefaultQuotaBytes: defaultQuotaBytes,
+		DefaultQuotaFiles: defaultQuotaFiles,
+	}
+}
+
+// Reserve accounts for a new file of the given size, failing with a *QuotaExceededError if
+// it does not fit in the user's remaining quota.
+func (qs *QuotaService) Reserve(ctx context.Context, ethereumAddress string, size int64) error {
+	return qs.ReserveFiles(ctx, ethereumAddress, size, 1)
+}
+
+// ReserveFiles accounts for several files totalling size bytes at once, such as the versions of
+// a file being transferred to the user.
+func (qs *QuotaService) ReserveFiles(ctx context.Context, ethereumAddress string, size, files int64) error {
+	ok, err := qs.UserRepo.ReserveStorage(ctx, ethereumAddress, size, files, qs.DefaultQuotaBytes, qs.DefaultQuotaFiles)
+	if err != nil {
+		return fmt.Errorf("failed to reserve storage: %w", err)
+	}
+	if ok {
+		return nil
+	}
+
+	usage, err := qs.GetUsage(ctx, ethereumAddress)
+	if err != nil {
+		return err
+	}
+	if usage.QuotaFiles > 0 && usage.FileCount+files > usage.QuotaFiles {
+		return &QuotaExceededError{Resource: "files", Used: usage.FileCount, Requested: files, Limit: usage.QuotaFiles}
+	}
+	return &QuotaExceededError{Resource: "bytes", Used: usage.BytesStored, Requested: size, Limit: usage.QuotaBytes}
+}
+
+// Release gives back storage accounted for by Reserve, after a failed upload or a deletion.
+// Failures are logged because usage can always be recomputed from the stored files.
+func (qs *QuotaService) Release(ctx context.Context, ethereumAddress string, size, files int64) {
+	// Releases undo work that has already happened, so they go ahead if the request is cancelled
+	ctx = context.WithoutCancel(ctx)
+	if err := qs.UserRepo.ReleaseStorage(ctx, ethereumAddress, size, files); err != nil {
+		log.Printf("Failed to release %d bytes of storage for %s: %v", size, ethereumAddress, err)
+	}
+}
+
+// GetUsage returns the user's current usage and effective quotas.
+func (qs *QuotaService) GetUsage(ctx context.Context, ethereumAddress string) (*Usage, error) {
+	user, err := qs.UserRepo.FindByEthereumAddress(ctx, ethereumAddress)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	usage := &Usage{
+		BytesStored: user.BytesStored,
+		FileCount:   user.FileCount,
+		QuotaBytes:  qs.DefaultQuotaBytes,
+		QuotaFiles:  qs.DefaultQuotaFiles,
+		Overridden:  user.QuotaBytes != nil || user.QuotaFiles != nil,
+	}
+	if user.QuotaBytes != nil {
+		usage.QuotaBytes = *user.QuotaBytes
+	}
+	if user.QuotaFiles != nil {
+		usage.QuotaFiles = *user.QuotaFiles
+	}
+	return usage, nil
+}
+
+// SetQuota overrides the quotas of a single user. A nil limit falls back to the default.
+func (qs *QuotaService) SetQuota(ctx context.Context, ethereumAddress string, quotaBytes, quotaFiles *int64) (*Usage, error) {
+	if (quotaBytes != nil && *quotaBytes < 0) || (quotaFiles != nil && *quotaFiles < 0) {
+		return nil, ErrInvalidQuota
+	}
+
+	err := qs.UserRepo.SetQuota(ctx, ethereumAddress, quotaBytes, quotaFiles)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to set quota: %w", err)
+	}
+	return qs.GetUsage(ctx, ethereumAddress)
+}
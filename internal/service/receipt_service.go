@@ -0,0 +1,54 @@
+package service
+
+import (
+	"SafeTransfer/internal/chain"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/pkg/receipt"
+	"crypto/ecdsa"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ReceiptService issues signed receipts for uploads and verifies receipts it has issued.
+type ReceiptService struct {
+	SigningKey *ecdsa.PrivateKey
+	ChainID    int64  // chain of Registry, or 0 without on-chain registration
+	Registry   string // FileRegistry address uploads are registered in, or empty
+}
+
+// NewReceiptService creates a new instance of ReceiptService. Receipts reference the registry
+// when on-chain registration is enabled.
+func NewReceiptService(signingKey *ecdsa.PrivateKey, registry *chain.Registry) *ReceiptService {
+	rs := &ReceiptService{SigningKey: signingKey}
+	if registry != nil {
+		rs.ChainID = registry.ChainID().Int64()
+		rs.Registry = registry.Address().Hex()
+	}
+	return rs
+}
+
+// Signer returns the address receipts are signed by, which verifiers need to check them offline.
+func (rs *ReceiptService) Signer() common.Address {
+	return crypto.PubkeyToAddress(rs.SigningKey.PublicKey)
+}
+
+// Issue signs a receipt for a stored file.
+func (rs *ReceiptService) Issue(fileMetadata *model.File, fileHash string) (*receipt.SignedReceipt, error) {
+	return receipt.Sign(receipt.Receipt{
+		CID:      fileMetadata.CID,
+		FileHash: fileHash,
+		Size:     fileMetadata.Size,
+		Uploader: fileMetadata.EthereumAddress,
+		IssuedAt: time.Now().Unix(),
+		Storage:  "ipfs://" + fileMetadata.CID,
+		ChainID:  rs.ChainID,
+		Registry: rs.Registry,
+	}, rs.SigningKey)
+}
+
+// Verify checks that a receipt was signed by this server.
+func (rs *ReceiptService) Verify(signed *receipt.SignedReceipt) error {
+	return receipt.Verify(signed, rs.Signer())
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"SafeTransfer/internal/chain"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// registrationTimeout bounds the whole registration of one file, including waiting for the receipt.
+const registrationTimeout = 15 * time.Minute
+
+// RegistrationService records uploads in the FileRegistry contract and tracks the resulting
+// transactions on the corresponding files.
+type RegistrationService struct {
+	Registry *chain.Registry
+	FileRepo repository.FileRepository
+}
+
+// NewRegistrationService creates a new instance of RegistrationService with dependencies injected.
+func NewRegistrationService(registry *chain.Registry, fileRepo repository.FileRepository) *RegistrationService {
+	return &RegistrationService{
+		Registry: registry,
+		FileRepo: fileRepo,
+	}
+}
+
+// RegisterAsync registers a stored file in the background so that uploads do not wait for mining.
+// The registration is part of the trace in ctx but outlives its cancellation.
+func (rs *RegistrationService) RegisterAsync(ctx context.Context, fileMetadata *model.File, fileHash string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), registrationTimeout)
+		defer cancel()
+		rs.Register(ctx, fileMetadata.CID, fileHash)
+	}()
+}
+
+// Register submits registerFile(cid, fileHash), waits for the receipt and records the
+// transaction hash and final status on the file. The contract reverts for a hash that is already
+// registered, so a file with the content of an earlier upload is recorded as a duplicate of the
+// existing registration instead. It returns the recorded status.
+func (rs *RegistrationService) Register(ctx context.Context, cid, fileHash string) string {
+	registration, err := rs.Registry.LookupFileHash(ctx, fileHash)
+	switch {
+	case err == nil:
+		existing := fmt.Sprintf("hash registered by %s at %s", registration.Owner.Hex(), registration.Timestamp.Format(time.RFC3339))
+		rs.updateStatus(ctx, cid, "", chain.StatusDuplicate, existing)
+		return chain.StatusDuplicate
+	case !errors.Is(err, chain.ErrNotRegistered):
+		log.Printf("Failed to look up the hash of file %s on chain: %v", cid, err)
+		rs.updateStatus(ctx, cid, "", chain.StatusFailed, err.Error())
+		return chain.StatusFailed
+	}
+
+	tx, err := rs.Registry.RegisterFile(ctx, cid, fileHash)
+	if err != nil {
+		log.Printf("Failed to register file %s on chain: %v", cid, err)
+		rs.updateStatus(ctx, cid, "", chain.StatusFailed, err.Error())
+		return chain.StatusFailed
+	}
+
+	txHash := tx.Hash().Hex()
+	rs.updateStatus(ctx, cid, txHash, chain.StatusPending, "")
+
+	if _, err := rs.Registry.WaitForReceipt(ctx, tx.Hash()); err != nil {
+		log.Printf("Registration of file %s in transaction %s failed: %v", cid, txHash, err)
+		rs.updateStatus(ctx, cid, txHash, chain.StatusFailed, err.Error())
+		return chain.StatusFailed
+	}
+
+	rs.updateStatus(ctx, cid, txHash, chain.StatusConfirmed, "")
+	return chain.StatusConfirmed
+}
+
+func (rs *RegistrationService) updateStatus(ctx context.Context, cid, txHash, status, chainError string) {
+	if err := rs.FileRepo.UpdateChainStatus(ctx, cid, txHash, status, chainError); err != nil {
+		log.Printf("Failed to record chain status %q for file %s: %v", status, cid, err)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrInvalidShare  = newError(ErrInvalidInput, "invalid_share", "grantee must be another user's Ethereum address")
+	ErrShareNotFound = newError(ErrNotFound, "share_not_found", "share not found")
+)
+
+// ShareService manages read access to logical files granted to other users.
+type ShareService struct {
+	ShareRepo repository.ShareRepository
+	FileRepo  repository.FileRepository
+}
+
+// NewShareService creates a new instance of ShareService with dependencies injected.
+func NewShareService(shareRepo repository.ShareRepository, fileRepo repository.FileRepository) *ShareService {
+	return &ShareService{
+		ShareRepo: shareRepo,
+		FileRepo:  fileRepo,
+	}
+}
+
+// GrantShare gives grantee read access to every version of an owned logical file.
+func (ss *ShareService) GrantShare(ctx context.Context, ethereumAddress string, logicalFileID uint, grantee string) (*model.Share, error) {
+	logicalFile, err := getOwnedLogicalFile(ctx, ss.FileRepo, ethereumAddress, logicalFileID)
+	if err != nil {
+		return nil, err
+	}
+	if !common.IsHexAddress(grantee) || sameAddress(grantee, ethereumAddress) {
+		return nil, ErrInvalidShare
+	}
+
+	share := &model.Share{
+		LogicalFileID:  logicalFile.ID,
+		GranteeAddress: model.CanonicalAddress(grantee),
+		GrantedBy:      model.CanonicalAddress(ethereumAddress),
+	}
+	if err := ss.ShareRepo.SaveShare(ctx, share); err != nil {
+		return nil, fmt.Errorf("failed to save share: %w", err)
+	}
+	return share, nil
+}
+
+// RevokeShare removes a grantee's access to an owned logical file.
+func (ss *ShareService) RevokeShare(ctx context.Context, ethereumAddress string, logicalFileID uint, grantee string) error {
+	logicalFile, err := getOwnedLogicalFile(ctx, ss.FileRepo, ethereumAddress, logicalFileID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := ss.ShareRepo.DeleteShare(ctx, logicalFile.ID, model.CanonicalAddress(grantee))
+	if err != nil {
+		return fmt.Errorf("failed to delete share: %w", err)
+	}
+	if !deleted {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// ListShares returns the users an owned logical file is shared with.
+func (ss *ShareService) ListShares(ctx context.Context, ethereumAddress string, logicalFileID uint) ([]model.Share, error) {
+	logicalFile, err := getOwnedLogicalFile(ctx, ss.FileRepo, ethereumAddress, logicalFileID)
+	if err != nil {
+		return nil, err
+	}
+
+	shares, err := ss.ShareRepo.ListShares(ctx, logicalFile.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shares: %w", err)
+	}
+	return shares, nil
+}
+
+// ListSharedWithMe returns a page of the latest versions of the files shared with a user.
+func (ss *ShareService) ListSharedWithMe(ctx context.Context, ethereumAddress string, page, pageSize int) ([]model.File, int64, error) {
+	files, total, err := ss.ShareRepo.ListSharedFiles(ctx, model.CanonicalAddress(ethereumAddress), pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list shared files: %w", err)
+	}
+	return files, total, nil
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"io"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("SafeTransfer/internal/service")
+
+// timedReader measures the time spent blocked in reads from r, so that a span streaming content
+// can tell waiting for its source apart from the work done on it.
+type timedReader struct {
+	r       io.Reader
+	elapsed time.Duration
+}
+
+func (r *timedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := r.r.Read(p)
+	r.elapsed += time.Since(start)
+	return n, err
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"SafeTransfer/internal/ethsig"
+	actiontypes "SafeTransfer/pkg/action"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Actions that require a fresh EIP-712 signature from the wallet in addition to a bearer token.
+const (
+	ActionShareGrant        = actiontypes.ShareGrant
+	ActionDeleteFile        = actiontypes.DeleteFile
+	ActionTransferOwnership = actiontypes.TransferOwnership
+)
+
+// EIP-712 domain of action authorizations. The version changes whenever an action type does.
+const (
+	TypedDataDomainName    = actiontypes.DomainName
+	TypedDataDomainVersion = actiontypes.DomainVersion
+)
+
+// maxAuthorizationLifetime bounds how far in the future an authorization's deadline may be.
+const maxAuthorizationLifetime = time.Hour
+
+var (
+	ErrInvalidAuthorization  = newError(ErrInvalidInput, "invalid_authorization", "invalid action authorization")
+	ErrAuthorizationExpired  = newError(ErrUnauthorized, "authorization_expired", "action authorization has expired")
+	ErrInvalidTypedSignature = newError(ErrUnauthorized, "invalid_typed_signature", "typed signature was not made by the caller")
+	ErrStaleNonce            = newError(ErrUnauthorized, "stale_nonce", "nonce has already been used or replaced")
+)
+
+// ActionAuthorization is a wallet's EIP-712 signature over an action. Nonce is the user's
+// current nonce from /generateNonce, and Deadline the Unix time after which the signature is
+// no longer accepted.
+type ActionAuthorization struct {
+	Nonce     string `json:"nonce"`
+	Deadline  int64  `json:"deadline"`
+	Signature string `json:"signature"`
+}
+
+// ActionTypedData returns the typed data a wallet signs to authorize action with params, which
+// hold the action's fields other than the nonce and deadline.
+func (us *UserService) ActionTypedData(action string, params map[string]interface{}, nonce string, deadline int64) apitypes.TypedData {
+	return actiontypes.TypedData(us.ChainID, action, params, nonce, deadline)
+}
+
+// VerifyTypedSignature checks that typedData was signed by ethereumAddress, or accepted by it
+// through EIP-1271 if it is a contract wallet.
+func (us *UserService) VerifyTypedSignature(ctx context.Context, ethereumAddress string, typedData apitypes.TypedData, signature string) error {
+	digest, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAuthorization, err)
+	}
+
+	signer, err := ethsig.Recover(digest, signature)
+	if err == nil && strings.EqualFold(signer.Hex(), ethereumAddress) {
+		return nil
+	}
+
+	// Contract wallets sign through EIP-1271, possibly with signatures of any length
+	if common.IsHexAddress(ethereumAddress) {
+		valid, walletErr := us.isValidContractSignature(ctx, common.HexToAddress(ethereumAddress), digest, signature)
+		if walletErr != nil {
+			return walletErr
+		}
+		if valid {
+			return nil
+		}
+	}
+
+	switch {
+	case errors.Is(err, ethsig.ErrMalformedSignature):
+		return fmt.Errorf("%w: %w", ErrInvalidAuthorization, err)
+	case err != nil:
+		return fmt.Errorf("%w: %w", ErrInvalidTypedSignature, err)
+	}
+	return ErrInvalidTypedSignature
+}
+
+// AuthorizeAction verifies a typed signature over an action and consumes the nonce it was made
+// with, so that it cannot be replayed.
+func (us *UserService) AuthorizeAction(ctx context.Context, ethereumAddress, action string, params map[string]interface{}, auth ActionAuthorization) error {
+	if !actiontypes.IsAction(action) {
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidAuthorization, action)
+	}
+	if auth.Nonce == "" || auth.Signature == "" {
+		return fmt.Errorf("%w: nonce and signature are required", ErrInvalidAuthorization)
+	}
+
+	now := time.Now()
+	if auth.Deadline < now.Unix() {
+		return ErrAuthorizationExpired
+	}
+	if auth.Deadline > now.Add(maxAuthorizationLifetime).Unix() {
+		return fmt.Errorf("%w: deadline is more than %s away", ErrInvalidAuthorization, maxAuthorizationLifetime)
+	}
+
+	typedData := us.ActionTypedData(action, params, auth.Nonce, auth.Deadline)
+	if err := us.VerifyTypedSignature(ctx, ethereumAddress, typedData, auth.Signature); err != nil {
+		return err
+	}
+
+	nextNonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+	consumed, err := us.UserRepo.ConsumeNonce(ctx, ethereumAddress, auth.Nonce, nextNonce)
+	if err != nil {
+		return fmt.Errorf("failed to consume nonce: %w", err)
+	}
+	if !consumed {
+		return ErrStaleNonce
+	}
+	return nil
+}
@@ -1,108 +1,188 @@
 package service
 
 import (
+	"SafeTransfer/internal/chain"
+	"SafeTransfer/internal/ethsig"
 	"SafeTransfer/internal/model"
 	"SafeTransfer/internal/repository"
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/crypto"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/golang-jwt/jwt"
 	"log"
-	"os"
-	"strconv"
 	"strings"
 	"time"
 )
 
+var (
+	ErrMalformedSignature = newError(ErrInvalidInput, "malformed_signature", "malformed signature")
+	ErrInvalidSignature   = newError(ErrUnauthorized, "invalid_signature", "invalid signature")
+	ErrWalletUnavailable  = newError(ErrUnavailable, "wallet_unavailable", "contract wallet could not be checked")
+	ErrAccountDisabled    = newError(ErrForbidden, "account_disabled", "account is disabled")
+)
+
 type UserService struct {
 	UserRepo     repository.UserRepository
 	JWTSecretKey string
+	ChainID      int64               // chain ID of the EIP-712 domain that actions are signed in
+	Wallets      chain.WalletBackend // nil disables EIP-1271 contract wallet signatures
 }
 
 // NewUserService function now accepts a JWTSecretKey as an argument
-func NewUserService(userRepo repository.UserRepository, JWTSecretKey string) *UserService {
+func NewUserService(userRepo repository.UserRepository, JWTSecretKey string, chainID int64, wallets chain.WalletBackend) *UserService {
 	return &UserService{
 		UserRepo:     userRepo,
 		JWTSecretKey: JWTSecretKey,
+		ChainID:      chainID,
+		Wallets:      wallets,
 	}
 }
 
-func (us *UserService) GenerateNonceForUser(ethereumAddress string) (string, error) {
-	nonceBytes := make([]byte, 16) // 128-bit nonce
-	if _, err := rand.Read(nonceBytes); err != nil {
+func (us *UserService) GenerateNonceForUser(ctx context.Context, ethereumAddress string) (string, error) {
+	nonce, err := newNonce()
+	if err != nil {
 		return "", err
 	}
-	nonce := hex.EncodeToString(nonceBytes)
 
 	user := &model.User{
-		EthereumAddress: ethereumAddress,
+		EthereumAddress: model.CanonicalAddress(ethereumAddress),
 		Nonce:           nonce,
 	}
-	err := us.UserRepo.SaveOrUpdateUser(user)
+	err = us.UserRepo.SaveOrUpdateUser(ctx, user)
 	return nonce, err
 }
 
-func (us *UserService) GetNonceForUser(ethereumAddress string) (string, error) {
-	user, err := us.UserRepo.FindByEthereumAddress(ethereumAddress)
+// newNonce generates a random 128-bit nonce as a hexadecimal string.
+func newNonce() (string, error) {
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(nonceBytes), nil
+}
+
+func (us *UserService) GetNonceForUser(ctx context.Context, ethereumAddress string) (string, error) {
+	user, err := us.UserRepo.FindByEthereumAddress(ctx, ethereumAddress)
 	if err != nil {
 		return "", err
 	}
 	return user.Nonce, nil
 }
 
-// VerifySignature verifies the signature of the nonce signed by the user.
+// VerifySignature recovers the address that signed message as an EIP-191 personal message.
 func (us *UserService) VerifySignature(message, signature string) (string, error) {
-	log.Printf("Verifying signature for message: %s", message)
-	log.Printf("Signature: %s", signature)
+	recoveredAddr, err := ethsig.RecoverMessage([]byte(message), signature)
+	if err != nil {
+		return "", signatureError(err)
+	}
+	return recoveredAddr.Hex(), nil
+}
+
+// signatureError classifies an error from ethsig as ErrMalformedSignature or ErrInvalidSignature.
+func signatureError(err error) error {
+	if errors.Is(err, ethsig.ErrMalformedSignature) {
+		return classify(ErrMalformedSignature, err)
+	}
+	return classify(ErrInvalidSignature, err)
+}
 
-	// Prepare the message for Ethereum signature verification
-	message = "\x19Ethereum Signed Message:\n" + strconv.Itoa(len(message)) + message
-	messageHash := crypto.Keccak256Hash([]byte(message))
+// VerifyWalletSignature checks that ethereumAddress signed message as an EIP-191 personal
+// message. Signatures of externally owned accounts are recovered; if that fails and code is
+// deployed at the address, as for a Safe, the wallet is asked through EIP-1271 instead. Only a
+// contract wallet that cannot be asked fails with ErrWalletUnavailable.
+func (us *UserService) VerifyWalletSignature(ctx context.Context, ethereumAddress, message, signature string) error {
+	if !common.IsHexAddress(ethereumAddress) {
+		return ErrInvalidSignature
+	}
 
-	// Remove the '0x' prefix from the signature if present
-	signature = strings.TrimPrefix(signature, "0x")
+	recoveredAddress, err := us.VerifySignature(message, signature)
+	if err == nil && strings.EqualFold(recoveredAddress, ethereumAddress) {
+		return nil
+	}
 
-	// Decode the signature from hex
-	sigBytes, err := hexutil.Decode("0x" + signature)
+	valid, walletErr := us.isValidContractSignature(ctx, common.HexToAddress(ethereumAddress), accounts.TextHash([]byte(message)), signature)
+	if walletErr != nil {
+		return walletErr
+	}
+	if valid {
+		return nil
+	}
 	if err != nil {
-		return "", err
+		return err
 	}
+	return ErrInvalidSignature
+}
 
-	// Adjust the S/V values
-	if sigBytes[64] != 27 && sigBytes[64] != 28 {
-		return "", errors.New("invalid Ethereum signature (V value is incorrect)")
+// isValidContractSignature reports whether address is a contract wallet that accepts signature
+// for digest. It is always false when contract wallets are disabled, and when the code at address
+// cannot be read, since the signature is then most likely an invalid one from an externally
+// owned account.
+func (us *UserService) isValidContractSignature(ctx context.Context, address common.Address, digest []byte, signature string) (bool, error) {
+	if us.Wallets == nil {
+		return false, nil
+	}
+	sigBytes, err := ethsig.Decode(signature)
+	if err != nil {
+		return false, nil
 	}
-	sigBytes[64] -= 27
 
-	// Recover the public key from the signature
-	publicKeyECDSA, err := crypto.SigToPub(messageHash.Bytes(), sigBytes)
+	ctx, cancel := context.WithTimeout(ctx, chainLookupTimeout)
+	defer cancel()
+
+	isContract, err := chain.IsContract(ctx, us.Wallets, address)
 	if err != nil {
-		return "", err
+		log.Printf("Failed to check for a contract wallet at %s: %v", address.Hex(), err)
+		return false, nil
+	}
+	if !isContract {
+		return false, nil
 	}
 
-	// Convert the public key to an Ethereum address
-	recoveredAddr := crypto.PubkeyToAddress(*publicKeyECDSA).Hex()
-	log.Printf("Recovered Ethereum address: %s", recoveredAddr)
+	valid, err := chain.IsValidSignature(ctx, us.Wallets, address, common.BytesToHash(digest), sigBytes)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrWalletUnavailable, err)
+	}
+	return valid, nil
+}
 
-	return recoveredAddr, nil
+// GetActiveUser returns the user with the given address, failing with ErrAccountDisabled if an
+// admin has disabled the account.
+func (us *UserService) GetActiveUser(ctx context.Context, ethereumAddress string) (*model.User, error) {
+	user, err := us.UserRepo.FindByEthereumAddress(ctx, ethereumAddress)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	if user.Role == "" {
+		user.Role = model.RoleUser
+	}
+	return user, nil
 }
 
-// GenerateJWT generates a JWT for a given user.
-func (us *UserService) GenerateJWT(ethereumAddress string) (string, error) {
-	jwtSecretKey, err := os.ReadFile("/run/secrets/jwt_secret")
+// GenerateJWT generates a JWT for a given user, carrying their canonical address in the
+// "ethereumAddress" claim and their role in the "role" claim.
+func (us *UserService) GenerateJWT(ctx context.Context, ethereumAddress string) (string, error) {
+	user, err := us.GetActiveUser(ctx, ethereumAddress)
 	if err != nil {
-		return "", errors.New("failed to read JWT secret key")
+		return "", err
 	}
 
 	claims := jwt.MapClaims{
-		"ethereumAddress": ethereumAddress,
+		"ethereumAddress": model.CanonicalAddress(ethereumAddress),
+		"role":            user.Role,
 		"exp":             time.Now().Add(24 * time.Hour).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(jwtSecretKey))
+	tokenString, err := token.SignedString([]byte(us.JWTSecretKey))
 	if err != nil {
 		return "", err
 	}
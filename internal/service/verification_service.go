@@ -0,0 +1,197 @@
+package service
+
+import (
+	"SafeTransfer/internal/chain"
+	"SafeTransfer/internal/crypto"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	maxVerificationMatches = 10
+	maxCIDSize             = 255
+)
+
+var ErrInvalidVerification = newError(ErrInvalidInput, "invalid_verification", "invalid verification request")
+
+// StoredMatch is a stored file whose content has the verified hash.
+type StoredMatch struct {
+	CID        string
+	Owner      string
+	UploadedAt time.Time
+}
+
+// RegistryMatch is the result of looking a file up in the FileRegistry contract. Status is one
+// of ChainVerified, ChainMismatch, ChainUnregistered or ChainUnavailable.
+type RegistryMatch struct {
+	Status       string
+	CID          string
+	FileHash     string
+	Owner        string
+	RegisteredAt time.Time
+}
+
+// VerificationResult reports whether a file matches what was stored and registered.
+type VerificationResult struct {
+	SHA256   string
+	CID      string
+	Stored   []StoredMatch
+	Registry *RegistryMatch // nil when on-chain registration is disabled
+}
+
+// Verified reports whether the file matched a stored record or its registration.
+func (vr *VerificationResult) Verified() bool {
+	return len(vr.Stored) > 0 || (vr.Registry != nil && vr.Registry.Status == ChainVerified)
+}
+
+// VerificationService lets anyone check a file against the registry without an account, and
+// signed in users against the stored files they can read as well.
+type VerificationService struct {
+	FileRepo  repository.FileRepository
+	ShareRepo repository.ShareRepository
+	Registry  *chain.Registry
+}
+
+// NewVerificationService creates a new instance of VerificationService with dependencies injected.
+func NewVerificationService(fileRepo repository.FileRepository, shareRepo repository.ShareRepository, registry *chain.Registry) *VerificationService {
+	return &VerificationService{
+		FileRepo:  fileRepo,
+		ShareRepo: shareRepo,
+		Registry:  registry,
+	}
+}
+
+// Verify checks a SHA-256 hash, optionally together with the CID it is claimed to have been
+// uploaded as. With a CID, only that file and its registration are considered; otherwise the
+// stored files and the registration of the hash are. Only stored files owned by or shared with
+// ethereumAddress are reported, so anonymous callers, with an empty address, only learn about
+// the public on-chain registration.
+func (vs *VerificationService) Verify(ctx context.Context, ethereumAddress, sha256Hash, cid string) (*VerificationResult, error) {
+	sha256Hash = strings.ToLower(strings.TrimSpace(sha256Hash))
+	digest, err := hex.DecodeString(sha256Hash)
+	if err != nil || len(digest) != 32 {
+		return nil, fmt.Errorf("%w: sha256 must be 64 hexadecimal characters", ErrInvalidVerification)
+	}
+	cid = strings.TrimSpace(cid)
+	if len(cid) > maxCIDSize {
+		return nil, fmt.Errorf("%w: cid exceeds %d bytes", ErrInvalidVerification, maxCIDSize)
+	}
+
+	result := &VerificationResult{SHA256: sha256Hash, CID: cid}
+
+	switch {
+	case ethereumAddress == "":
+		// Anonymous callers only learn about the registration
+	case cid != "":
+		match, err := vs.matchStoredCID(ctx, ethereumAddress, cid, sha256Hash, digest)
+		if err != nil {
+			return nil, err
+		}
+		if match != nil {
+			result.Stored = append(result.Stored, *match)
+		}
+	default:
+		files, err := vs.FileRepo.FindFilesByHash(ctx, sha256Hash, maxVerificationMatches)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find files: %w", err)
+		}
+		for _, file := range files {
+			err := checkReadableFile(ctx, vs.FileRepo, vs.ShareRepo, ethereumAddress, &file)
+			if errors.Is(err, ErrFileNotFound) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			result.Stored = append(result.Stored, newStoredMatch(&file))
+		}
+	}
+
+	if vs.Registry != nil {
+		result.Registry = vs.matchRegistry(ctx, sha256Hash, cid)
+	}
+	return result, nil
+}
+
+// matchStoredCID checks the stored file with the given CID if ethereumAddress can read it. Files
+// uploaded before hashes were recorded are checked against their signature instead.
+func (vs *VerificationService) matchStoredCID(ctx context.Context, ethereumAddress, cid, sha256Hash string, digest []byte) (*StoredMatch, error) {
+	fileMetadata, err := vs.FileRepo.GetFileMetadataByCID(ctx, cid)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get file metadata: %w", err)
+	}
+	err = checkReadableFile(ctx, vs.FileRepo, vs.ShareRepo, ethereumAddress, fileMetadata)
+	if errors.Is(err, ErrFileNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if fileMetadata.FileHash != "" {
+		if fileMetadata.FileHash != sha256Hash {
+			return nil, nil
+		}
+	} else {
+		publicKey, err := parsePublicKey(fileMetadata.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		if crypto.VerifyHash(digest, fileMetadata.Signature, publicKey) != nil {
+			return nil, nil
+		}
+	}
+
+	match := newStoredMatch(fileMetadata)
+	return &match, nil
+}
+
+// matchRegistry looks the CID up in the registry, falling back to the hash, or the hash alone
+// if no CID was given.
+func (vs *VerificationService) matchRegistry(ctx context.Context, sha256Hash, cid string) *RegistryMatch {
+	ctx, cancel := context.WithTimeout(ctx, chainLookupTimeout)
+	defer cancel()
+
+	var registration *chain.Registration
+	var err error
+	if cid != "" {
+		registration, err = lookupRegistration(ctx, vs.Registry, cid, sha256Hash)
+	} else {
+		registration, err = vs.Registry.LookupFileHash(ctx, sha256Hash)
+	}
+
+	switch {
+	case errors.Is(err, chain.ErrNotRegistered):
+		return &RegistryMatch{Status: ChainUnregistered}
+	case err != nil:
+		log.Printf("Failed to look up registration for verification: %v", err)
+		return &RegistryMatch{Status: ChainUnavailable}
+	}
+
+	match := &RegistryMatch{
+		Status:       ChainVerified,
+		CID:          registration.CID,
+		FileHash:     registration.FileHash,
+		Owner:        registration.Owner.Hex(),
+		RegisteredAt: registration.Timestamp,
+	}
+	if !strings.EqualFold(registration.FileHash, sha256Hash) {
+		match.Status = ChainMismatch
+	}
+	return match
+}
+
+func newStoredMatch(file *model.File) StoredMatch {
+	return StoredMatch{
+		CID:        file.CID,
+		Owner:      file.EthereumAddress,
+		UploadedAt: file.CreatedAt,
+	}
+}
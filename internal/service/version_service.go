@@ -0,0 +1,280 @@
+package service
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/storage"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+)
+
+var (
+	ErrVersionNotFound      = newError(ErrNotFound, "version_not_found", "file version not found")
+	ErrInvalidVersionPolicy = newError(ErrInvalidInput, "invalid_version_policy", "maxVersions must be zero or positive")
+)
+
+// VersionService manages the version history of logical files.
+type VersionService struct {
+	FileService     *FileService
+	DownloadService *DownloadService
+	FileRepo        repository.FileRepository
+	ShareRepo       repository.ShareRepository
+	IPFSStorage     *storage.IPFSStorage
+	MaxVersions     int // server-wide number of versions kept per logical file
+}
+
+// NewVersionService creates a new instance of VersionService with dependencies injected.
+func NewVersionService(fileService *FileService, downloadService *DownloadService, fileRepo repository.FileRepository, shareRepo repository.ShareRepository, ipfsStorage *storage.IPFSStorage, maxVersions int) *VersionService {
+	return &VersionService{
+		FileService:     fileService,
+		DownloadService: downloadService,
+		FileRepo:        fileRepo,
+		ShareRepo:       shareRepo,
+		IPFSStorage:     ipfsStorage,
+		MaxVersions:     maxVersions,
+	}
+}
+
+// UploadVersion stores file as the next version of a logical file. Folder, description and tags
+// are carried over from the current version unless opts overrides them.
+func (vs *VersionService) UploadVersion(ctx context.Context, ethereumAddress string, logicalFileID uint, file io.ReadSeeker, opts UploadOptions) (*model.File, string, error) {
+	logicalFile, latest, err := vs.getLatestVersion(ctx, ethereumAddress, logicalFileID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if opts.FileName == "" {
+		opts.FileName = latest.Name
+	}
+	if opts.FolderID == nil {
+		opts.FolderID = latest.FolderID
+	}
+	if opts.Description == "" {
+		opts.Description = latest.Description
+	}
+	if len(opts.Tags) == 0 {
+		opts.Tags = latest.Tags
+	}
+
+	fileMetadata, originalFileHash, err := vs.FileService.uploadWithinQuota(ctx, file, ethereumAddress, opts, func(ctx context.Context, fileMetadata *model.File) error {
+		fileMetadata.LogicalFileID = &logicalFile.ID
+		if err := vs.FileRepo.SaveFileVersion(ctx, fileMetadata); err != nil {
+			return fmt.Errorf("failed to save file version: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	vs.applyRetention(ctx, logicalFile)
+	return fileMetadata, originalFileHash, nil
+}
+
+// ListVersions returns the logical file and its retained versions, newest first. Users the file
+// is shared with may list it too.
+func (vs *VersionService) ListVersions(ctx context.Context, ethereumAddress string, logicalFileID uint) (*model.LogicalFile, []model.File, error) {
+	logicalFile, err := vs.getReadableLogicalFile(ctx, ethereumAddress, logicalFileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	versions, err := vs.FileRepo.ListFileVersions(ctx, logicalFile.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list file versions: %w", err)
+	}
+	return logicalFile, versions, nil
+}
+
+// DownloadVersion looks up one version so that it can be streamed with Download.Stream.
+// Users the file is shared with may download it too.
+func (vs *VersionService) DownloadVersion(ctx context.Context, ethereumAddress string, logicalFileID uint, version int) (*Download, error) {
+	logicalFile, err := vs.getReadableLogicalFile(ctx, ethereumAddress, logicalFileID)
+	if err != nil {
+		return nil, err
+	}
+	fileMetadata, err := vs.getVersion(ctx, logicalFile, version)
+	if err != nil {
+		return nil, err
+	}
+	return vs.DownloadService.download(ctx, fileMetadata)
+}
+
+// RestoreVersion makes an old version current again by storing its content as a new version,
+// so that the history in between is kept.
+func (vs *VersionService) RestoreVersion(ctx context.Context, ethereumAddress string, logicalFileID uint, version int) (*model.File, string, error) {
+	// Only the owner may restore, even though users the file is shared with may download it
+	if _, err := vs.getOwnedLogicalFile(ctx, ethereumAddress, logicalFileID); err != nil {
+		return nil, "", err
+	}
+
+	download, err := vs.DownloadVersion(ctx, ethereumAddress, logicalFileID, version)
+	if err != nil {
+		return nil, "", err
+	}
+	fileMetadata := download.File
+
+	var content bytes.Buffer
+	if err := download.Stream(ctx, &content); err != nil {
+		return nil, "", fmt.Errorf("failed to read file version: %w", err)
+	}
+
+	opts := UploadOptions{
+		FileName:    fileMetadata.Name,
+		Description: fileMetadata.Description,
+		Tags:        fileMetadata.Tags,
+		MimeType:    fileMetadata.MimeType,
+	}
+	return vs.UploadVersion(ctx, ethereumAddress, logicalFileID, bytes.NewReader(content.Bytes()), opts)
+}
+
+// SetRetention sets how many versions of a logical file are kept. Zero restores the server default.
+func (vs *VersionService) SetRetention(ctx context.Context, ethereumAddress string, logicalFileID uint, maxVersions int) (*model.LogicalFile, error) {
+	if maxVersions < 0 {
+		return nil, ErrInvalidVersionPolicy
+	}
+
+	logicalFile, err := vs.getOwnedLogicalFile(ctx, ethereumAddress, logicalFileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vs.FileRepo.UpdateMaxVersions(ctx, logicalFile.ID, maxVersions); err != nil {
+		return nil, fmt.Errorf("failed to update retention policy: %w", err)
+	}
+	logicalFile.MaxVersions = maxVersions
+
+	vs.applyRetention(ctx, logicalFile)
+	return logicalFile, nil
+}
+
+// RetainedVersions returns the number of versions kept for a logical file.
+func (vs *VersionService) RetainedVersions(logicalFile *model.LogicalFile) int {
+	if logicalFile.MaxVersions > 0 {
+		return logicalFile.MaxVersions
+	}
+	return vs.MaxVersions
+}
+
+// applyRetention deletes the versions that exceed the retention limit, unpins their content and
+// returns their storage to the owner's quota. Failures are logged rather than returned because
+// the new version has already been saved.
+func (vs *VersionService) applyRetention(ctx context.Context, logicalFile *model.LogicalFile) {
+	keep := vs.RetainedVersions(logicalFile)
+	if keep <= 0 {
+		return
+	}
+
+	pruned, err := vs.FileRepo.PruneFileVersions(ctx, logicalFile.ID, keep)
+	if err != nil {
+		log.Printf("Failed to prune versions of file %d: %v", logicalFile.ID, err)
+		return
+	}
+	for _, version := range pruned {
+		if err := vs.IPFSStorage.UnpinFile(ctx, version.CID); err != nil {
+			log.Printf("Failed to unpin pruned version %s: %v", version.CID, err)
+		}
+		vs.FileService.QuotaService.Release(ctx, version.EthereumAddress, version.Size, 1)
+	}
+}
+
+// getLatestVersion retrieves an owned logical file together with its current version.
+func (vs *VersionService) getLatestVersion(ctx context.Context, ethereumAddress string, logicalFileID uint) (*model.LogicalFile, *model.File, error) {
+	logicalFile, err := vs.getOwnedLogicalFile(ctx, ethereumAddress, logicalFileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	latest, err := vs.FileRepo.GetFileVersion(ctx, logicalFile.ID, logicalFile.LatestVersion)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get latest file version: %w", err)
+	}
+	return logicalFile, latest, nil
+}
+
+// getVersion retrieves one version of a logical file.
+func (vs *VersionService) getVersion(ctx context.Context, logicalFile *model.LogicalFile, version int) (*model.File, error) {
+	fileMetadata, err := vs.FileRepo.GetFileVersion(ctx, logicalFile.ID, version)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrVersionNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get file version: %w", err)
+	}
+	return fileMetadata, nil
+}
+
+// checkReadableFile reports ErrFileNotFound unless the logical file of a file version is owned by
+// or shared with ethereumAddress.
+func checkReadableFile(ctx context.Context, fileRepo repository.FileRepository, shareRepo repository.ShareRepository, ethereumAddress string, fileMetadata *model.File) error {
+	if fileMetadata.LogicalFileID == nil {
+		// Files without a logical file predate versioning and cannot be shared
+		if !sameAddress(fileMetadata.EthereumAddress, ethereumAddress) {
+			return ErrFileNotFound
+		}
+		return nil
+	}
+	_, err := getReadableLogicalFile(ctx, fileRepo, shareRepo, ethereumAddress, *fileMetadata.LogicalFileID)
+	return err
+}
+
+// getOwnedLogicalFile retrieves a logical file, reporting ErrFileNotFound if it belongs to someone else.
+func (vs *VersionService) getOwnedLogicalFile(ctx context.Context, ethereumAddress string, logicalFileID uint) (*model.LogicalFile, error) {
+	return getOwnedLogicalFile(ctx, vs.FileRepo, ethereumAddress, logicalFileID)
+}
+
+// getReadableLogicalFile retrieves a logical file that is owned by or shared with ethereumAddress.
+func (vs *VersionService) getReadableLogicalFile(ctx context.Context, ethereumAddress string, logicalFileID uint) (*model.LogicalFile, error) {
+	return getReadableLogicalFile(ctx, vs.FileRepo, vs.ShareRepo, ethereumAddress, logicalFileID)
+}
+
+// getReadableLogicalFile retrieves a logical file, reporting ErrFileNotFound unless it is owned by
+// or shared with ethereumAddress.
+func getReadableLogicalFile(ctx context.Context, fileRepo repository.FileRepository, shareRepo repository.ShareRepository, ethereumAddress string, logicalFileID uint) (*model.LogicalFile, error) {
+	logicalFile, err := getLogicalFile(ctx, fileRepo, logicalFileID)
+	if err != nil {
+		return nil, err
+	}
+	if sameAddress(logicalFile.EthereumAddress, ethereumAddress) {
+		return logicalFile, nil
+	}
+
+	shared, err := shareRepo.HasShare(ctx, logicalFile.ID, model.CanonicalAddress(ethereumAddress))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check shares: %w", err)
+	}
+	if !shared {
+		return nil, ErrFileNotFound
+	}
+	return logicalFile, nil
+}
+
+// getOwnedLogicalFile retrieves a logical file, reporting ErrFileNotFound if it belongs to someone else.
+func getOwnedLogicalFile(ctx context.Context, fileRepo repository.FileRepository, ethereumAddress string, logicalFileID uint) (*model.LogicalFile, error) {
+	logicalFile, err := getLogicalFile(ctx, fileRepo, logicalFileID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameAddress(logicalFile.EthereumAddress, ethereumAddress) {
+		return nil, ErrFileNotFound
+	}
+	return logicalFile, nil
+}
+
+// sameAddress reports whether two strings are the same Ethereum address, whatever their casing.
+func sameAddress(a, b string) bool {
+	return model.CanonicalAddress(a) == model.CanonicalAddress(b)
+}
+
+func getLogicalFile(ctx context.Context, fileRepo repository.FileRepository, logicalFileID uint) (*model.LogicalFile, error) {
+	logicalFile, err := fileRepo.GetLogicalFile(ctx, logicalFileID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrFileNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	return logicalFile, nil
+}
@@ -4,11 +4,21 @@ package storage
 
 import (
 	"SafeTransfer/internal/crypto" // Import the crypto package
+	"SafeTransfer/internal/metrics"
+	"SafeTransfer/internal/tracing"
+	"context"
 	"fmt"
 	"github.com/ipfs/go-ipfs-api"
 	"io"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("SafeTransfer/internal/storage")
+
 // IPFSStorage represents the IPFS storage service.
 type IPFSStorage struct {
 	shell *shell.Shell
@@ -20,20 +30,15 @@ func NewIPFSStorage(apiURL string) *IPFSStorage {
 	return &IPFSStorage{shell: ipfsShell}
 }
 
-// UploadFileToIPFS uploads a file to IPFS and returns the generated CID and the nonce.
-func (is *IPFSStorage) UploadFileToIPFS(file io.ReadSeeker, key []byte) (string, []byte, error) {
+// UploadFileToIPFS uploads a file to IPFS and returns the generated CID and the nonce. The
+// upload is aborted when ctx is done.
+func (is *IPFSStorage) UploadFileToIPFS(ctx context.Context, file io.ReadSeeker, key []byte) (string, []byte, error) {
 	// Encrypt the file before uploading
 	encryptedFile, nonce, err := crypto.EncryptFile(file, key)
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to encrypt file: %w", err)
 	}
-	defer func() {
-		if closer, ok := encryptedFile.(io.Closer); ok {
-			if err := closer.Close(); err != nil {
-				fmt.Println("Error closing encryptedFile:", err)
-			}
-		}
-	}()
+	encryptedFile.OnEOF = metrics.ObserveCipher("encrypt")
 
 	// Reset the file reader to the beginning
 	if _, err := file.Seek(0, io.SeekStart); err != nil {
@@ -41,7 +46,10 @@ func (is *IPFSStorage) UploadFileToIPFS(file io.ReadSeeker, key []byte) (string,
 	}
 
 	// Add the encrypted file to IPFS
-	cid, addErr := is.shell.Add(encryptedFile)
+	ctx, span, start := startRequest(ctx, "add")
+	cid, addErr := is.shell.Add(&contextReader{ctx: ctx, r: encryptedFile})
+	span.SetAttributes(attribute.String("ipfs.cid", cid), tracing.Milliseconds("safetransfer.cipher_ms", encryptedFile.Elapsed()))
+	finishRequest("add", span, start, addErr)
 	if addErr != nil {
 		return "", nil, fmt.Errorf("failed to upload encrypted file to IPFS: %w", addErr)
 	}
@@ -49,14 +57,87 @@ func (is *IPFSStorage) UploadFileToIPFS(file io.ReadSeeker, key []byte) (string,
 	return cid, nonce, nil
 }
 
-// DownloadFileFromIPFS retrieves a file from IPFS using its CID.
-func (is *IPFSStorage) DownloadFileFromIPFS(cid string) (io.ReadCloser, error) {
+// DownloadFileFromIPFS retrieves a file from IPFS using its CID. The content can be read until
+// ctx is done.
+func (is *IPFSStorage) DownloadFileFromIPFS(ctx context.Context, cid string) (io.ReadCloser, error) {
 	// Use the IPFS shell to retrieve the encrypted file
-	reader, err := is.shell.Cat(cid)
+	ctx, span, start := startRequest(ctx, "cat", attribute.String("ipfs.cid", cid))
+	resp, err := is.shell.Request("cat", cid).Send(ctx)
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+		resp.Close()
+	}
+	finishRequest("cat", span, start, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download encrypted file from IPFS: %w", err)
 	}
-	readCloser := io.NopCloser(reader)
 
-	return readCloser, nil
+	return resp.Output, nil
+}
+
+// UnpinFile removes the pin on a CID so that the IPFS node can garbage-collect its blocks.
+func (is *IPFSStorage) UnpinFile(ctx context.Context, cid string) error {
+	ctx, span, start := startRequest(ctx, "unpin", attribute.String("ipfs.cid", cid))
+	err := is.shell.Request("pin/rm", cid).Option("recursive", true).Exec(ctx, nil)
+	finishRequest("unpin", span, start, err)
+	if err != nil {
+		return fmt.Errorf("failed to unpin file from IPFS: %w", err)
+	}
+	return nil
+}
+
+// ListPins returns the CIDs pinned recursively on the IPFS node, which includes every file
+// uploaded through UploadFileToIPFS.
+func (is *IPFSStorage) ListPins(ctx context.Context) ([]string, error) {
+	ctx, span, start := startRequest(ctx, "pin_ls")
+	pins, err := is.shell.PinsOfType(ctx, shell.RecursivePin)
+	finishRequest("pin_ls", span, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pins: %w", err)
+	}
+	cids := make([]string, 0, len(pins))
+	for cid := range pins {
+		cids = append(cids, cid)
+	}
+	return cids, nil
+}
+
+// Ping checks that the IPFS HTTP API is reachable by asking the node for its version.
+func (is *IPFSStorage) Ping(ctx context.Context) error {
+	var version struct{ Version string }
+	ctx, span, start := startRequest(ctx, "version")
+	err := is.shell.Request("version").Exec(ctx, &version)
+	finishRequest("version", span, start, err)
+	if err != nil {
+		return fmt.Errorf("failed to reach IPFS: %w", err)
+	}
+	return nil
+}
+
+// startRequest starts the span of an IPFS API request.
+func startRequest(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span, time.Time) {
+	ctx, span := tracer.Start(ctx, "ipfs."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, span, time.Now()
+}
+
+// finishRequest records the duration and outcome of an IPFS API request and ends its span.
+func finishRequest(operation string, span trace.Span, start time.Time, err error) {
+	metrics.Since(metrics.IPFSRequestDuration.WithLabelValues(operation), start)
+	if err != nil {
+		metrics.IPFSErrors.WithLabelValues(operation).Inc()
+	}
+	tracing.End(span, err)
+}
+
+// contextReader fails reads once ctx is done, for requests that cannot be given a context.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
 }
@@ -0,0 +1,61 @@
+// Package tracing sets up OpenTelemetry tracing. Components create spans with tracers from the
+// global provider, which drops them until Setup installs one that exports them over OTLP.
+package tracing
+
+import (
+	"SafeTransfer/internal/config"
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup installs the W3C trace context and baggage propagators and, when an endpoint is
+// configured, a provider that exports spans to it in batches. The returned function flushes
+// pending spans and stops the exporter.
+func Setup(ctx context.Context, cfg config.TracingConfig) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !cfg.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	provider := NewProvider(cfg.ServiceName, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns a provider of tracers for serviceName that processes spans with the given
+// options, such as sdktrace.WithBatcher. Spans are sampled as configured by OTEL_TRACES_SAMPLER,
+// and always by default.
+func NewProvider(serviceName string, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	opts = append(opts, sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))))
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// End ends span, recording err as its status if it is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Milliseconds returns an attribute holding d in fractional milliseconds, for the time spent in
+// a stage that is not a span of its own, such as the cipher while content is streamed.
+func Milliseconds(key string, d time.Duration) attribute.KeyValue {
+	return attribute.Float64(key, float64(d.Microseconds())/1000)
+}
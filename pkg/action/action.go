@@ -0,0 +1,82 @@
+// Package action defines the EIP-712 typed data that wallets sign to authorize sensitive
+// actions, such as sharing or deleting a file, in addition to presenting a bearer token.
+//
+// The server verifies these signatures and clients build the same typed data to sign, so both
+// sides share this package.
+package action
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Actions that require a fresh EIP-712 signature from the wallet.
+const (
+	ShareGrant        = "ShareGrant"
+	DeleteFile        = "DeleteFile"
+	TransferOwnership = "TransferOwnership"
+)
+
+// Domain name and version of action authorizations. The version changes whenever an action
+// type does.
+const (
+	DomainName    = "SafeTransfer"
+	DomainVersion = "1"
+)
+
+// Types are the EIP-712 types of every action.
+var Types = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+	},
+	ShareGrant: {
+		{Name: "fileId", Type: "uint256"},
+		{Name: "grantee", Type: "address"},
+		{Name: "nonce", Type: "string"},
+		{Name: "deadline", Type: "uint256"},
+	},
+	DeleteFile: {
+		{Name: "fileId", Type: "uint256"},
+		{Name: "nonce", Type: "string"},
+		{Name: "deadline", Type: "uint256"},
+	},
+	TransferOwnership: {
+		{Name: "fileId", Type: "uint256"},
+		{Name: "newOwner", Type: "address"},
+		{Name: "nonce", Type: "string"},
+		{Name: "deadline", Type: "uint256"},
+	},
+}
+
+// IsAction reports whether name is one of the actions in Types.
+func IsAction(name string) bool {
+	_, ok := Types[name]
+	return ok && name != "EIP712Domain"
+}
+
+// TypedData returns the typed data a wallet signs to authorize action on chainID with params,
+// which hold the action's fields other than the nonce and deadline.
+func TypedData(chainID int64, action string, params map[string]interface{}, nonce string, deadline int64) apitypes.TypedData {
+	message := apitypes.TypedDataMessage{
+		"nonce":    nonce,
+		"deadline": big.NewInt(deadline),
+	}
+	for name, value := range params {
+		message[name] = value
+	}
+
+	return apitypes.TypedData{
+		Types:       Types,
+		PrimaryType: action,
+		Domain: apitypes.TypedDataDomain{
+			Name:    DomainName,
+			Version: DomainVersion,
+			ChainId: math.NewHexOrDecimal256(chainID),
+		},
+		Message: message,
+	}
+}
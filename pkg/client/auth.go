@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Signer signs in and authorizes actions on behalf of a wallet.
+type Signer interface {
+	Address() common.Address
+	// SignMessage returns a 65-byte [R || S || V] signature of message as an EIP-191 personal message.
+	SignMessage(message []byte) ([]byte, error)
+	// SignTypedData returns a 65-byte [R || S || V] EIP-712 signature of typedData.
+	SignTypedData(typedData apitypes.TypedData) ([]byte, error)
+}
+
+// KeySigner signs with a private key held in memory.
+type KeySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewKeySigner creates a signer for the account of key.
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{key: key}
+}
+
+// Address returns the address of the signer's account.
+func (s *KeySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+// SignMessage signs message as an EIP-191 personal message, with V of 27 or 28.
+func (s *KeySigner) SignMessage(message []byte) ([]byte, error) {
+	signature, err := crypto.Sign(accounts.TextHash(message), s.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message: %w", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+// SignTypedData signs typedData as EIP-712 typed data, with V of 27 or 28.
+func (s *KeySigner) SignTypedData(typedData apitypes.TypedData) ([]byte, error) {
+	digest, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+	signature, err := crypto.Sign(digest, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign typed data: %w", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+// GenerateNonce requests a nonce for ethereumAddress to sign in with.
+func (c *Client) GenerateNonce(ctx context.Context, ethereumAddress string) (string, error) {
+	var resp struct {
+		Nonce string `json:"nonce"`
+	}
+	req := map[string]string{"ethereumAddress": ethereumAddress}
+	if err := c.doJSON(ctx, http.MethodPost, "/generateNonce", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.Nonce, nil
+}
+
+// VerifySignature exchanges a signature of message by ethereumAddress for a JWT. It does not
+// change the client's Token.
+func (c *Client) VerifySignature(ctx context.Context, ethereumAddress, message, signature string) (string, error) {
+	var resp struct {
+		Token string `json:"token"`
+	}
+	req := map[string]string{
+		"ethereumAddress": ethereumAddress,
+		"message":         message,
+		"signature":       signature,
+	}
+	if err := c.doJSON(ctx, http.MethodPost, "/verifySignature", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.Token, nil
+}
+
+// Login signs in with signer by signing a fresh nonce, and uses the resulting JWT for later
+// calls. It returns the JWT.
+func (c *Client) Login(ctx context.Context, signer Signer) (string, error) {
+	address := signer.Address().Hex()
+	nonce, err := c.GenerateNonce(ctx, address)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := signer.SignMessage([]byte(nonce))
+	if err != nil {
+		return "", err
+	}
+
+	token, err := c.VerifySignature(ctx, address, nonce, hexutil.Encode(signature))
+	if err != nil {
+		return "", err
+	}
+	c.Token = token
+	return token, nil
+}
+
+// CheckToken checks that the client's token or API key is accepted.
+func (c *Client) CheckToken(ctx context.Context) error {
+	var resp struct {
+		Message string `json:"message"`
+	}
+	return c.doJSON(ctx, http.MethodGet, "/checkToken", nil, &resp)
+}
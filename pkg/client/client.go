@@ -0,0 +1,160 @@
+// Package client is a Go client for the SafeTransfer API described by /v1/openapi.json.
+//
+// A client signs in with a wallet through Login, or uses an API key, and then uploads and
+// downloads files:
+//
+//	c := client.New("https://safetransfer.example.com")
+//	if _, err := c.Login(ctx, client.NewKeySigner(key)); err != nil {
+//		return err
+//	}
+//	result, err := c.UploadFile(ctx, "report.pdf", client.UploadOptions{})
+//
+// Error responses are returned as *Error, carrying the problem code reported by the server.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// apiPrefix is the path of the version of the API the client speaks.
+const apiPrefix = "/v1"
+
+// Client calls a SafeTransfer server. Its fields may be changed between calls, but not while
+// a call is in progress.
+type Client struct {
+	BaseURL    string       // URL of the server, e.g. https://safetransfer.example.com, without the /v1 prefix
+	HTTPClient *http.Client // nil uses http.DefaultClient
+	Token      string       // JWT sent as a bearer token, set by Login
+	APIKey     string       // API key, sent instead of Token when set
+	ChainID    int64        // chain ID of the EIP-712 domain actions are signed in; must match the server's
+}
+
+// New creates a client for the server at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: baseURL, ChainID: 1}
+}
+
+// Error is an error response, decoded from the RFC 7807 problem details sent by the server.
+type Error struct {
+	StatusCode int
+	Type       string
+	Title      string
+	Detail     string
+	Instance   string
+	Code       string // stable identifier of the problem, such as "file_not_found"
+}
+
+func (e *Error) Error() string {
+	message := e.Detail
+	if message == "" {
+		message = e.Title
+	}
+	if e.Code == "" {
+		return fmt.Sprintf("safetransfer: %d: %s", e.StatusCode, message)
+	}
+	return fmt.Sprintf("safetransfer: %d %s: %s", e.StatusCode, e.Code, message)
+}
+
+// newRequest creates a request for path, relative to the API prefix, with the client's credentials.
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+apiPrefix+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	switch {
+	case c.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.APIKey)
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return req, nil
+}
+
+// do sends a request, returning an *Error for any status other than 2xx.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+	return resp, nil
+}
+
+// doJSON sends in, if not nil, as a JSON body and decodes the JSON response into out, if not nil.
+func (c *Client) doJSON(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// decodeError reads an error response. Responses that are not problem details, for example
+// from a proxy, are described by their status alone.
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode, Title: http.StatusText(resp.StatusCode)}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "application/problem+json" {
+		return apiErr
+	}
+
+	var problem struct {
+		Type     string `json:"type"`
+		Title    string `json:"title"`
+		Detail   string `json:"detail"`
+		Instance string `json:"instance"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&problem); err != nil {
+		return apiErr
+	}
+	apiErr.Type = problem.Type
+	apiErr.Detail = problem.Detail
+	apiErr.Instance = problem.Instance
+	apiErr.Code = problem.Code
+	if problem.Title != "" {
+		apiErr.Title = problem.Title
+	}
+	return apiErr
+}
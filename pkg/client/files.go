@@ -0,0 +1,229 @@
+package client
+
+import (
+	"SafeTransfer/pkg/receipt"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ErrHashMismatch is returned by Download.Read when the downloaded content does not match the
+// hash reported by the server.
+var ErrHashMismatch = errors.New("downloaded content does not match X-File-Hash")
+
+// ProgressFunc is called as content is transferred, with the number of bytes transferred so
+// far and the total, or -1 when the total is unknown.
+type ProgressFunc func(transferred, total int64)
+
+// UploadOptions describe a file being uploaded. All fields are optional.
+type UploadOptions struct {
+	FolderID    uint // 0 uploads to the root folder
+	Description string
+	Tags        []string
+	Size        int64        // size of the content, reported to Progress; 0 if unknown
+	Progress    ProgressFunc // called as the content is sent
+}
+
+// UploadResult describes a stored file version.
+type UploadResult struct {
+	CID              string                 `json:"cid"`
+	OriginalFileHash string                 `json:"originalFileHash"` // SHA-256 of the content as a hexadecimal string
+	Version          int                    `json:"version"`
+	FileID           uint                   `json:"fileId,omitempty"`
+	ChainStatus      string                 `json:"chainStatus,omitempty"` // empty unless on-chain registration is enabled
+	Receipt          *receipt.SignedReceipt `json:"receipt,omitempty"`     // nil unless receipts are enabled
+}
+
+// Upload streams content to the server as a file called name. The content is not buffered,
+// so the upload cannot be retried.
+func (c *Client) Upload(ctx context.Context, content io.Reader, name string, opts UploadOptions) (*UploadResult, error) {
+	total := opts.Size
+	if total <= 0 {
+		total = -1
+	}
+	if opts.Progress != nil {
+		content = &progressReader{r: content, total: total, progress: opts.Progress}
+	}
+
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeUploadForm(form, content, name, opts))
+	}()
+	// Unblock the writer if the request fails before the whole body is read
+	defer body.Close()
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/upload", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result UploadResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// UploadFile uploads the file at path under its base name. Progress, if set, is reported
+// against the size of the file.
+func (c *Client) UploadFile(ctx context.Context, path string, opts UploadOptions) (*UploadResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Size == 0 {
+		opts.Size = info.Size()
+	}
+	return c.Upload(ctx, file, filepath.Base(path), opts)
+}
+
+// writeUploadForm writes the multipart form of an upload, with the content last.
+func writeUploadForm(form *multipart.Writer, content io.Reader, name string, opts UploadOptions) error {
+	if opts.FolderID != 0 {
+		if err := form.WriteField("folderId", strconv.FormatUint(uint64(opts.FolderID), 10)); err != nil {
+			return err
+		}
+	}
+	if opts.Description != "" {
+		if err := form.WriteField("description", opts.Description); err != nil {
+			return err
+		}
+	}
+	for _, tag := range opts.Tags {
+		if err := form.WriteField("tags", tag); err != nil {
+			return err
+		}
+	}
+
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return fmt.Errorf("failed to read upload content: %w", err)
+	}
+	return form.Close()
+}
+
+// Download is the content of a downloaded file, streamed from the server. Reading it to the
+// end checks the content against Hash: the final Read returns ErrHashMismatch instead of
+// io.EOF if they differ. For files uploaded before the server recorded their hash, Hash and
+// the result of the on-chain verification arrive as trailers, and are only set at that point.
+// It must be closed.
+type Download struct {
+	CID      string
+	Filename string
+	Hash     string // SHA-256 of the content as a hexadecimal string, from X-File-Hash
+	Size     int64  // -1 if unknown
+
+	// Result of the server's on-chain verification, empty when it is disabled.
+	ChainVerification string
+	ChainOwner        string
+
+	body     io.ReadCloser
+	trailer  http.Header
+	digest   hash.Hash
+	read     int64
+	progress ProgressFunc
+}
+
+// Download starts downloading the file stored under cid. progress may be nil.
+func (c *Client) Download(ctx context.Context, cid string, progress ProgressFunc) (*Download, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/download/"+url.PathEscape(cid), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	download := &Download{
+		CID:               cid,
+		Filename:          cid,
+		Hash:              resp.Header.Get("X-File-Hash"),
+		Size:              resp.ContentLength,
+		ChainVerification: resp.Header.Get("X-Chain-Verification"),
+		ChainOwner:        resp.Header.Get("X-Chain-Owner"),
+		body:              resp.Body,
+		trailer:           resp.Trailer,
+		digest:            sha256.New(),
+		progress:          progress,
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		download.Filename = filepath.Base(params["filename"])
+	}
+	return download, nil
+}
+
+// Read reads the next part of the content.
+func (d *Download) Read(p []byte) (int, error) {
+	n, err := d.body.Read(p)
+	d.digest.Write(p[:n])
+	d.read += int64(n)
+	if d.progress != nil && n > 0 {
+		d.progress(d.read, d.Size)
+	}
+	if err != io.EOF {
+		return n, err
+	}
+	if d.Hash == "" {
+		d.Hash = d.trailer.Get("X-File-Hash")
+		d.ChainVerification = d.trailer.Get("X-Chain-Verification")
+		d.ChainOwner = d.trailer.Get("X-Chain-Owner")
+	}
+	if hex.EncodeToString(d.digest.Sum(nil)) != d.Hash {
+		return n, ErrHashMismatch
+	}
+	return n, err
+}
+
+// Close ends the download.
+func (d *Download) Close() error {
+	return d.body.Close()
+}
+
+// progressReader reports the bytes read through it.
+type progressReader struct {
+	r        io.Reader
+	read     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	if n > 0 {
+		pr.read += int64(n)
+		pr.progress(pr.read, pr.total)
+	}
+	return n, err
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// File describes a stored file version.
+type File struct {
+	CID         string    `json:"cid"`
+	FileID      *uint     `json:"fileId"`
+	Version     int       `json:"version"`
+	Name        string    `json:"name"`
+	FolderID    *uint     `json:"folderId"`
+	Description string    `json:"description"`
+	Tags        []string  `json:"tags"`
+	Size        int64     `json:"size"`
+	MimeType    string    `json:"mimeType"`
+	ChainStatus string    `json:"chainStatus,omitempty"`
+	ChainTxHash string    `json:"chainTxHash,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// FileList is a page of files.
+type FileList struct {
+	Files    []File `json:"files"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"pageSize"`
+	Total    int64  `json:"total"`
+}
+
+// Folder describes a folder.
+type Folder struct {
+	ID        uint      `json:"id"`
+	ParentID  *uint     `json:"parentId"`
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FolderContents is a page of the files in a folder, with its subfolders.
+type FolderContents struct {
+	Folder   *Folder  `json:"folder"` // nil for the root folder
+	Folders  []Folder `json:"folders"`
+	Files    []File   `json:"files"`
+	Page     int      `json:"page"`
+	PageSize int      `json:"pageSize"`
+	Total    int64    `json:"total"`
+}
+
+// SearchOptions filter a file search. All fields are optional; zero values do not filter.
+type SearchOptions struct {
+	Text          string
+	Tags          []string // all must match
+	MimeType      string   // exact, or a "type/*" wildcard
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	MinSize       int64
+	MaxSize       int64
+	Page          int // 0 for the first page
+	PageSize      int // 0 for the server's default
+}
+
+// SearchFiles lists the latest versions of the caller's files that match opts.
+func (c *Client) SearchFiles(ctx context.Context, opts SearchOptions) (*FileList, error) {
+	query := url.Values{}
+	if opts.Text != "" {
+		query.Set("q", opts.Text)
+	}
+	for _, tag := range opts.Tags {
+		query.Add("tag", tag)
+	}
+	if opts.MimeType != "" {
+		query.Set("mimeType", opts.MimeType)
+	}
+	if !opts.CreatedAfter.IsZero() {
+		query.Set("from", opts.CreatedAfter.Format(time.RFC3339))
+	}
+	if !opts.CreatedBefore.IsZero() {
+		query.Set("to", opts.CreatedBefore.Format(time.RFC3339))
+	}
+	if opts.MinSize > 0 {
+		query.Set("minSize", strconv.FormatInt(opts.MinSize, 10))
+	}
+	if opts.MaxSize > 0 {
+		query.Set("maxSize", strconv.FormatInt(opts.MaxSize, 10))
+	}
+	setPagination(query, opts.Page, opts.PageSize)
+
+	var list FileList
+	if err := c.doJSON(ctx, http.MethodGet, "/files/search"+encodeQuery(query), nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ListFolder lists a page of the contents of a folder, or of the root folder when folderID is 0.
+func (c *Client) ListFolder(ctx context.Context, folderID uint, page, pageSize int) (*FolderContents, error) {
+	id := "root"
+	if folderID != 0 {
+		id = strconv.FormatUint(uint64(folderID), 10)
+	}
+	query := url.Values{}
+	setPagination(query, page, pageSize)
+
+	var contents FolderContents
+	if err := c.doJSON(ctx, http.MethodGet, "/folders/"+id+"/contents"+encodeQuery(query), nil, &contents); err != nil {
+		return nil, err
+	}
+	return &contents, nil
+}
+
+// setPagination adds page and pageSize to query unless they are left to the server's defaults.
+func setPagination(query url.Values, page, pageSize int) {
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if pageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(pageSize))
+	}
+}
+
+// encodeQuery returns query as a string to append to a path, empty if there are no parameters.
+func encodeQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+// CreateFolder creates a folder called name in the folder parentID, or in the root folder when
+// parentID is 0.
+func (c *Client) CreateFolder(ctx context.Context, name string, parentID uint) (*Folder, error) {
+	req := map[string]interface{}{"name": name}
+	if parentID != 0 {
+		req["parentId"] = parentID
+	}
+
+	var folder Folder
+	if err := c.doJSON(ctx, http.MethodPost, "/folders", req, &folder); err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
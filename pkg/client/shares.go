@@ -0,0 +1,112 @@
+package client
+
+import (
+	"SafeTransfer/pkg/action"
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// authorizationLifetime is how long the action signatures made by the client are valid. The
+// server accepts at most an hour.
+const authorizationLifetime = 10 * time.Minute
+
+// Share is access to a file granted to another user.
+type Share struct {
+	FileID    uint      `json:"fileId"`
+	Grantee   string    `json:"grantee"`
+	GrantedBy string    `json:"grantedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GrantShare gives grantee access to every version of a file. The grant is authorized by an
+// EIP-712 ShareGrant signature from signer, which must be the file's owner, in the domain of
+// the client's ChainID. The client must be signed in with a session token, not an API key.
+func (c *Client) GrantShare(ctx context.Context, signer Signer, fileID uint, grantee string) (*Share, error) {
+	if !common.IsHexAddress(grantee) {
+		return nil, fmt.Errorf("invalid grantee address %q", grantee)
+	}
+
+	params := map[string]interface{}{
+		"fileId":  new(big.Int).SetUint64(uint64(fileID)),
+		"grantee": grantee,
+	}
+	auth, err := c.authorize(ctx, signer, action.ShareGrant, params)
+	if err != nil {
+		return nil, err
+	}
+
+	req := map[string]interface{}{
+		"grantee":   grantee,
+		"nonce":     auth.Nonce,
+		"deadline":  auth.Deadline,
+		"signature": auth.Signature,
+	}
+	var share Share
+	if err := c.doJSON(ctx, http.MethodPost, sharesPath(fileID), req, &share); err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// ListShares lists the users a file is shared with.
+func (c *Client) ListShares(ctx context.Context, fileID uint) ([]Share, error) {
+	var resp struct {
+		Shares []Share `json:"shares"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, sharesPath(fileID), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Shares, nil
+}
+
+// RevokeShare removes grantee's access to a file.
+func (c *Client) RevokeShare(ctx context.Context, fileID uint, grantee string) error {
+	return c.doJSON(ctx, http.MethodDelete, sharesPath(fileID)+"/"+url.PathEscape(grantee), nil, nil)
+}
+
+// ListSharedWithMe lists a page of the files other users have shared with the caller.
+func (c *Client) ListSharedWithMe(ctx context.Context, page, pageSize int) (*FileList, error) {
+	query := url.Values{}
+	setPagination(query, page, pageSize)
+
+	var list FileList
+	if err := c.doJSON(ctx, http.MethodGet, "/shared"+encodeQuery(query), nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// authorization is a signed action, as sent in the body of the request it authorizes.
+type authorization struct {
+	Nonce     string
+	Deadline  int64
+	Signature string
+}
+
+// authorize signs an action with a fresh nonce. Requesting the nonce replaces any earlier one,
+// so only the latest authorization made for a user can be used.
+func (c *Client) authorize(ctx context.Context, signer Signer, name string, params map[string]interface{}) (*authorization, error) {
+	nonce, err := c.GenerateNonce(ctx, signer.Address().Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(authorizationLifetime).Unix()
+	signature, err := signer.SignTypedData(action.TypedData(c.ChainID, name, params, nonce, deadline))
+	if err != nil {
+		return nil, err
+	}
+	return &authorization{Nonce: nonce, Deadline: deadline, Signature: hexutil.Encode(signature)}, nil
+}
+
+func sharesPath(fileID uint) string {
+	return "/files/" + strconv.FormatUint(uint64(fileID), 10) + "/shares"
+}
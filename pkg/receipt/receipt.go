@@ -0,0 +1,147 @@
+// Package receipt issues and verifies SafeTransfer upload receipts.
+//
+// A receipt is EIP-712 typed data signed by the server's receipt key, so that anyone holding a
+// receipt and the server's signer address can check it offline with Verify, or in a wallet that
+// supports eth_signTypedData_v4.
+package receipt
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Domain name and version of receipts. The version changes whenever the Receipt type does.
+const (
+	DomainName    = "SafeTransfer Receipt"
+	DomainVersion = "1"
+)
+
+var ErrInvalidSignature = errors.New("invalid receipt signature")
+
+// Receipt describes a stored upload.
+type Receipt struct {
+	CID      string `json:"cid"`
+	FileHash string `json:"fileHash"` // SHA-256 of the plaintext as a hexadecimal string
+	Size     int64  `json:"size"`
+	Uploader string `json:"uploader"` // Ethereum address of the uploader
+	IssuedAt int64  `json:"issuedAt"` // Unix time
+	Storage  string `json:"storage"`  // where the encrypted content is stored, e.g. ipfs://<cid>
+	ChainID  int64  `json:"chainId"`  // chain of Registry, or 0 without on-chain registration
+	Registry string `json:"registry"` // FileRegistry contract the upload is registered in, or the zero address
+}
+
+// SignedReceipt is a receipt together with the server's signature over its typed data.
+type SignedReceipt struct {
+	Receipt   Receipt `json:"receipt"`
+	Signature string  `json:"signature"` // 65-byte [R || S || V] signature with V of 27 or 28, hex-encoded
+}
+
+var receiptTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	},
+	"Receipt": {
+		{Name: "cid", Type: "string"},
+		{Name: "fileHash", Type: "string"},
+		{Name: "size", Type: "uint256"},
+		{Name: "uploader", Type: "address"},
+		{Name: "issuedAt", Type: "uint256"},
+		{Name: "storage", Type: "string"},
+	},
+}
+
+// TypedData returns the EIP-712 typed data that is signed for a receipt. The chain ID and
+// registry form the domain, binding the receipt to the chain the upload is registered on.
+func TypedData(r Receipt) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types:       receiptTypes,
+		PrimaryType: "Receipt",
+		Domain: apitypes.TypedDataDomain{
+			Name:              DomainName,
+			Version:           DomainVersion,
+			ChainId:           math.NewHexOrDecimal256(r.ChainID),
+			VerifyingContract: common.HexToAddress(r.Registry).Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"cid":      r.CID,
+			"fileHash": r.FileHash,
+			"size":     big.NewInt(r.Size),
+			"uploader": common.HexToAddress(r.Uploader).Hex(),
+			"issuedAt": big.NewInt(r.IssuedAt),
+			"storage":  r.Storage,
+		},
+	}
+}
+
+// Hash returns the EIP-712 digest of a receipt.
+func Hash(r Receipt) ([]byte, error) {
+	if !common.IsHexAddress(r.Uploader) {
+		return nil, fmt.Errorf("invalid uploader address %q", r.Uploader)
+	}
+	if r.Registry != "" && !common.IsHexAddress(r.Registry) {
+		return nil, fmt.Errorf("invalid registry address %q", r.Registry)
+	}
+	digest, _, err := apitypes.TypedDataAndHash(TypedData(r))
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash receipt: %w", err)
+	}
+	return digest, nil
+}
+
+// Sign signs a receipt with key.
+func Sign(r Receipt, key *ecdsa.PrivateKey) (*SignedReceipt, error) {
+	digest, err := Hash(r)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := crypto.Sign(digest, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign receipt: %w", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return &SignedReceipt{Receipt: r, Signature: hexutil.Encode(signature)}, nil
+}
+
+// Recover returns the address that signed a receipt.
+func Recover(sr *SignedReceipt) (common.Address, error) {
+	signature, err := hexutil.Decode(sr.Signature)
+	if err != nil || len(signature) != crypto.SignatureLength {
+		return common.Address{}, ErrInvalidSignature
+	}
+	if v := signature[crypto.RecoveryIDOffset]; v == 27 || v == 28 {
+		signature[crypto.RecoveryIDOffset] -= 27
+	}
+
+	digest, err := Hash(sr.Receipt)
+	if err != nil {
+		return common.Address{}, err
+	}
+	publicKey, err := crypto.SigToPub(digest, signature)
+	if err != nil {
+		return common.Address{}, ErrInvalidSignature
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
+
+// Verify checks offline that a receipt was signed by signer.
+func Verify(sr *SignedReceipt, signer common.Address) error {
+	recovered, err := Recover(sr)
+	if err != nil {
+		return err
+	}
+	if recovered != signer {
+		return fmt.Errorf("%w: signed by %s", ErrInvalidSignature, recovered.Hex())
+	}
+	return nil
+}
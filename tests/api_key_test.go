@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/service"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// memoryAPIKeyRepository keeps API keys in memory.
+type memoryAPIKeyRepository struct {
+	repository.APIKeyRepository
+	keys []*model.APIKey
+}
+
+func (repo *memoryAPIKeyRepository) SaveAPIKey(ctx context.Context, key *model.APIKey) error {
+	key.ID = uint(len(repo.keys) + 1)
+	key.CreatedAt = time.Now()
+	repo.keys = append(repo.keys, key)
+	return nil
+}
+
+func (repo *memoryAPIKeyRepository) FindAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	for _, key := range repo.keys {
+		if key.Prefix == prefix && !key.DeletedAt.Valid {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (repo *memoryAPIKeyRepository) CountAPIKeys(ctx context.Context, ethereumAddress string) (int64, error) {
+	var count int64
+	for _, key := range repo.keys {
+		if key.EthereumAddress == ethereumAddress && !key.DeletedAt.Valid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (repo *memoryAPIKeyRepository) DeleteAPIKey(ctx context.Context, ethereumAddress string, id uint) (bool, error) {
+	for _, key := range repo.keys {
+		if key.ID == id && key.EthereumAddress == ethereumAddress && !key.DeletedAt.Valid {
+			key.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (repo *memoryAPIKeyRepository) UpdateLastUsed(ctx context.Context, id uint, lastUsedAt time.Time) error {
+	repo.keys[id-1].LastUsedAt = &lastUsedAt
+	return nil
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryAPIKeyRepository{}
+	apiKeyService := service.NewAPIKeyService(repo)
+
+	apiKey, key, err := apiKeyService.CreateAPIKey(ctx, userAddress, "CI", []string{"Upload", "upload", "download"}, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, apiKey.Prefix+"_"))
+	assert.Equal(t, model.Tags{service.ScopeUpload, service.ScopeDownload}, apiKey.Scopes)
+	assert.NotContains(t, apiKey.KeyHash, key)
+
+	authenticated, err := apiKeyService.Authenticate(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, userAddress, authenticated.EthereumAddress)
+	assert.NotNil(t, repo.keys[0].LastUsedAt)
+
+	// A key with the right prefix but the wrong secret is rejected
+	forged := key[:len(key)-1] + "0"
+	if forged == key {
+		forged = key[:len(key)-1] + "1"
+	}
+	_, err = apiKeyService.Authenticate(ctx, forged)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+	_, err = apiKeyService.Authenticate(ctx, "st_not-a-key")
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+
+	require.NoError(t, apiKeyService.RevokeAPIKey(ctx, userAddress, apiKey.ID))
+	_, err = apiKeyService.Authenticate(ctx, key)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+	assert.ErrorIs(t, apiKeyService.RevokeAPIKey(ctx, userAddress, apiKey.ID), service.ErrAPIKeyNotFound)
+}
+
+func TestAPIKeyValidation(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryAPIKeyRepository{}
+	apiKeyService := service.NewAPIKeyService(repo)
+	past := time.Now().Add(-time.Minute)
+
+	_, _, err := apiKeyService.CreateAPIKey(ctx, userAddress, "", []string{service.ScopeUpload}, nil)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKeyRequest)
+	_, _, err = apiKeyService.CreateAPIKey(ctx, userAddress, "CI", nil, nil)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKeyRequest)
+	_, _, err = apiKeyService.CreateAPIKey(ctx, userAddress, "CI", []string{"admin"}, nil)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKeyRequest)
+	_, _, err = apiKeyService.CreateAPIKey(ctx, userAddress, "CI", []string{service.ScopeUpload}, &past)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKeyRequest)
+
+	// Keys stop working once they expire
+	soon := time.Now().Add(time.Hour)
+	apiKey, key, err := apiKeyService.CreateAPIKey(ctx, userAddress, "CI", []string{service.ScopeUpload}, &soon)
+	require.NoError(t, err)
+	repo.keys[apiKey.ID-1].ExpiresAt = &past
+	_, err = apiKeyService.Authenticate(ctx, key)
+	assert.ErrorIs(t, err, service.ErrAPIKeyExpired)
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	apiKeyService := service.NewAPIKeyService(&memoryAPIKeyRepository{})
+	_, key, err := apiKeyService.CreateAPIKey(context.Background(), adminAddress, "CI", []string{service.ScopeUpload}, nil)
+	require.NoError(t, err)
+
+	serve := func(authorization string, middleware ...func(http.Handler) http.Handler) (int, http.Header) {
+		var seen http.Header
+		var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = r.Header.Clone()
+			w.WriteHeader(http.StatusOK)
+		})
+		for i := len(middleware) - 1; i >= 0; i-- {
+			handler = middleware[i](handler)
+		}
+		handler = api.APIKeyMiddleware(apiKeyService, service.NewUserService(nil, "secret", 1, nil))(handler)
+
+		request := httptest.NewRequest(http.MethodPost, "/upload", nil)
+		request.Header.Set("Authorization", authorization)
+		request.Header.Set("Role", model.RoleAdmin)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code, seen
+	}
+
+	code, headers := serve("ApiKey "+key, api.ScopeMiddleware(service.ScopeUpload))
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, adminAddress, headers.Get("EthereumAddress"))
+	// API keys never carry their owner's elevated role
+	assert.Equal(t, model.RoleUser, headers.Get("Role"))
+
+	code, _ = serve("ApiKey "+key, api.ScopeMiddleware(service.ScopeDownload))
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = serve("ApiKey "+key, api.SessionMiddleware)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = serve("ApiKey "+key, api.RoleMiddleware(model.RoleAdmin))
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = serve("ApiKey st_0000")
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestAPIKeyFolderCreation(t *testing.T) {
+	ctx := context.Background()
+	userRepo := &memoryUserRepository{users: map[string]*model.User{
+		userAddress: {EthereumAddress: userAddress, Role: model.RoleUser},
+	}}
+	apiKeyService := service.NewAPIKeyService(&memoryAPIKeyRepository{})
+	router := chi.NewRouter()
+	(&api.Handler{
+		FolderService: service.NewFolderService(&memoryFolderRepository{}, nil),
+		UserService:   service.NewUserService(userRepo, "secret", 1337, nil),
+		APIKeyService: apiKeyService,
+	}).RegisterRoutes(router)
+	_, key, err := apiKeyService.CreateAPIKey(ctx, userAddress, "CI", []string{service.ScopeUpload}, nil)
+	require.NoError(t, err)
+	serve := func(method, path, body string) int {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request.Header.Set("Authorization", "ApiKey "+key)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// Uploading a directory creates its folders, so the upload scope covers creating them
+	assert.Equal(t, http.StatusCreated, serve(http.MethodPost, "/v1/folders", `{"name": "photos"}`))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPatch, "/v1/folders/1", `{"name": "pictures"}`))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodDelete, "/v1/folders/1", ""))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/v1/folders/root/contents", ""))
+}
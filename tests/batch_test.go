@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/service"
+	"SafeTransfer/internal/storage"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type batchUploadResponse struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Results   []struct {
+		FileName         string `json:"fileName"`
+		Status           int    `json:"status"`
+		CID              string `json:"cid"`
+		OriginalFileHash string `json:"originalFileHash"`
+		FileID           *uint  `json:"fileId"`
+		Code             string `json:"code"`
+		Error            string `json:"error"`
+	} `json:"results"`
+}
+
+func TestBatchUploadAndArchive(t *testing.T) {
+	ctx := context.Background()
+	ipfs := newMemoryIPFS(t)
+	defer ipfs.Close()
+	ipfsStorage := storage.NewIPFSStorage(ipfs.URL)
+	fileRepo := &memoryFileRepository{}
+	userRepo := &memoryUserRepository{users: map[string]*model.User{
+		ownerAddress:    {EthereumAddress: ownerAddress, Role: model.RoleUser},
+		strangerAddress: {EthereumAddress: strangerAddress, Role: model.RoleUser},
+	}}
+	quotaService := service.NewQuotaService(userRepo, 40, 0)
+	userService := service.NewUserService(userRepo, "secret", 1337, nil)
+	router := chi.NewRouter()
+	(&api.Handler{
+		FileService:     service.NewFileService(ipfsStorage, fileRepo, nil, quotaService, nil, nil),
+		DownloadService: service.NewDownloadService(ipfsStorage, fileRepo, &memoryShareRepository{}, nil, service.ChainVerifyOff, nil),
+		QuotaService:    quotaService,
+		UserService:     userService,
+	}).RegisterRoutes(router)
+	serve := func(ethereumAddress string, request *http.Request) *httptest.ResponseRecorder {
+		token, err := userService.GenerateJWT(ctx, ethereumAddress)
+		require.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// Every file gets its own result, and failures do not affect the other files
+	recorder := serve(ownerAddress, multipartRequest(t, "/v1/upload/batch", "files",
+		formFile{"report.txt", "quarterly report"},
+		formFile{"huge.bin", strings.Repeat("x", 50)},
+		formFile{"report.txt", "meeting notes"},
+		formFile{"more.txt", "twelve bytes"},
+	))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var response batchUploadResponse
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Equal(t, 2, response.Succeeded)
+	assert.Equal(t, 2, response.Failed)
+	require.Len(t, response.Results, 4)
+
+	expected := []struct {
+		fileName string
+		status   int
+		code     string
+	}{
+		{"report.txt", http.StatusOK, ""},
+		{"huge.bin", http.StatusRequestEntityTooLarge, "quota_exceeded"},
+		{"report.txt", http.StatusOK, ""},
+		{"more.txt", http.StatusInsufficientStorage, "quota_exceeded"},
+	}
+	for i, result := range response.Results {
+		assert.Equal(t, expected[i].fileName, result.FileName, i)
+		assert.Equal(t, expected[i].status, result.Status, i)
+		assert.Equal(t, expected[i].code, result.Code, i)
+		if result.Status == http.StatusOK {
+			assert.NotEmpty(t, result.CID, i)
+			assert.NotEmpty(t, result.OriginalFileHash, i)
+			assert.NotNil(t, result.FileID, i)
+			assert.Empty(t, result.Error, i)
+		} else {
+			assert.Empty(t, result.CID, i)
+			assert.NotEmpty(t, result.Error, i)
+		}
+	}
+	assert.Equal(t, int64(16+13), userRepo.users[ownerAddress].BytesStored)
+	assert.Equal(t, int64(2), userRepo.users[ownerAddress].FileCount)
+
+	// A batch without files is rejected as a whole
+	recorder = serve(ownerAddress, multipartRequest(t, "/v1/upload/batch", "files"))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// The archive holds the decrypted content of every file, with repeated names numbered
+	archive := func(ethereumAddress string, cids ...string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(map[string][]string{"cids": cids})
+		require.NoError(t, err)
+		return serve(ethereumAddress, httptest.NewRequest(http.MethodPost, "/v1/files/archive", bytes.NewReader(body)))
+	}
+	first, second := response.Results[0].CID, response.Results[2].CID
+	recorder = archive(ownerAddress, first, second, first)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/zip", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Header().Get("Content-Disposition"), "attachment; filename=safetransfer-")
+
+	reader, err := zip.NewReader(bytes.NewReader(recorder.Body.Bytes()), int64(recorder.Body.Len()))
+	require.NoError(t, err)
+	contents := make(map[string]string)
+	var names []string
+	for _, entry := range reader.File {
+		names = append(names, entry.Name)
+		file, err := entry.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(file)
+		require.NoError(t, err)
+		file.Close()
+		contents[entry.Name] = string(content)
+	}
+	assert.Equal(t, []string{"report.txt", "report (2).txt"}, names, "duplicate CIDs are archived once")
+	assert.Equal(t, "quarterly report", contents["report.txt"])
+	assert.Equal(t, "meeting notes", contents["report (2).txt"])
+
+	// Nothing is streamed when any of the files cannot be read by the caller
+	recorder = archive(strangerAddress, first)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	recorder = archive(ownerAddress)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestUploadFormErrors(t *testing.T) {
+	ctx := context.Background()
+	userRepo := &memoryUserRepository{users: map[string]*model.User{
+		ownerAddress: {EthereumAddress: ownerAddress, Role: model.RoleUser},
+	}}
+	userService := service.NewUserService(userRepo, "secret", 1337, nil)
+	router := chi.NewRouter()
+	(&api.Handler{FileService: service.NewFileService(nil, &memoryFileRepository{}, nil, nil, nil, nil), UserService: userService}).RegisterRoutes(router)
+	token, err := userService.GenerateJWT(ctx, ownerAddress)
+	require.NoError(t, err)
+	serve := func(request *http.Request) (int, api.Problem) {
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		var problem api.Problem
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+		return recorder.Code, problem
+	}
+
+	for _, path := range []string{"/v1/upload", "/v1/upload/batch"} {
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"file": "report.txt"}`))
+		request.Header.Set("Content-Type", "application/json")
+		status, problem := serve(request)
+		assert.Equal(t, http.StatusBadRequest, status, path)
+		assert.Equal(t, "invalid_form", problem.Code, path)
+
+		// Go allows 10 MB of form fields on top of the in-memory limit, beyond which they are rejected
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		require.NoError(t, writer.WriteField("description", strings.Repeat("x", service.MaxMultipartFormSize+10<<20+1)))
+		require.NoError(t, writer.Close())
+		request = httptest.NewRequest(http.MethodPost, path, &body)
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		status, problem = serve(request)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, status, path)
+		assert.Equal(t, "form_too_large", problem.Code, path)
+	}
+
+	status, problem := serve(httptest.NewRequest(http.MethodGet, "/v1/files/search?pageSize=1000", nil))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_pagination", problem.Code)
+	assert.Contains(t, problem.Detail, "pageSize must be between 1 and")
+}
@@ -0,0 +1,253 @@
+package tests
+
+import (
+	"SafeTransfer/internal/chain"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryRegisterFile(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	backend, registry := newSimulatedRegistry(t, key)
+	ctx := context.Background()
+
+	tx, err := registry.RegisterFile(ctx, "QmTestCID", "abc123")
+	require.NoError(t, err)
+	backend.Commit()
+
+	receipt, err := registry.WaitForReceipt(ctx, tx.Hash())
+	require.NoError(t, err)
+	require.Len(t, receipt.Logs, 1)
+
+	event, err := registry.Contract().ParseFileRegistered(*receipt.Logs[0])
+	require.NoError(t, err)
+	assert.Equal(t, registry.Signer(), event.Owner)
+	assert.Equal(t, "QmTestCID", event.Cid)
+	assert.Equal(t, "abc123", event.FileHash)
+
+	// The registry refuses to register the same file twice
+	_, err = registry.RegisterFile(ctx, "QmTestCID", "abc123")
+	assert.Error(t, err)
+}
+
+func TestRegistryWaitForReceiptTimeout(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	_, registry := newSimulatedRegistry(t, key)
+
+	// Without a commit the transaction is never mined
+	tx, err := registry.RegisterFile(context.Background(), "QmPendingCID", "def456")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	receipt, err := registry.WaitForReceipt(ctx, tx.Hash())
+	assert.Error(t, err)
+	assert.Nil(t, receipt)
+}
+
+func TestRegistryLookupFile(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	details := chain.FileRegistryFileDetails{Owner: owner, FileHash: "abc123", Timestamp: big.NewInt(1700000000)}
+	_, registry := newSimulatedContract(t, key, lookupStubRuntime(t, "abc123", details))
+
+	registration, err := registry.LookupFile(context.Background(), "QmTestCID")
+	require.NoError(t, err)
+	assert.Equal(t, "QmTestCID", registration.CID)
+	assert.Equal(t, "abc123", registration.FileHash)
+	assert.Equal(t, owner, registration.Owner)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), registration.Timestamp)
+}
+
+func TestRegistryLookupUnregisteredFile(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	_, registry := newSimulatedContract(t, key, revertStubRuntime("CID not registered"))
+
+	_, err = registry.LookupFile(context.Background(), "QmUnknownCID")
+	assert.ErrorIs(t, err, chain.ErrNotRegistered)
+}
+
+// registryStubRuntime is a hand-assembled stand-in for the FileRegistry contract, used until the
+// binding is generated with its bytecode by solc 0.8.18. getFileDetails reverts as for an unknown
+// hash. Any other call stores keccak(calldata[4:]), reverting if it was already stored, and emits
+// FileRegistered(msg.sender) with calldata[4:] as data, which is exactly the ABI encoding of
+// registerFile's (cid, fileHash) arguments.
+func registryStubRuntime() []byte {
+	topic := crypto.Keccak256([]byte("FileRegistered(address,string,string)"))
+	getFileDetails := crypto.Keccak256([]byte("getFileDetails(string)"))[:4]
+
+	register := func(offset int) []byte {
+		code := common.FromHex(
+			"6004" + "36" + "03" + // len = calldatasize - 4
+				"80" + "6004" + "6000" + "37" + // calldatacopy(0, 4, len)
+				"80" + "6000" + "20" + // key = keccak256(0, len)
+				"80" + "54" + "15" + fmt.Sprintf("60%02x", offset+0x18) + "57" + // if sload(key) == 0 jump to store
+				"6000" + "80" + "fd" + // revert(0, 0)
+				"5b" + "6001" + "90" + "55" + // store: sstore(key, 1)
+				"33" + "7f") // caller, push32 topic0
+		code = append(code, topic...)
+		return append(code, common.FromHex(
+			"82"+"6000"+"a2"+ // log2(0, len, topic0, caller)
+				"00")...) // stop
+	}
+	return selectorStubRuntime(getFileDetails, revertAt("File not registered"), register)
+}
+
+// selectorStubRuntime runs matched for calls to the function with selector, and otherwise for
+// any other call. Both are given the offset their code is placed at.
+func selectorStubRuntime(selector []byte, matched, otherwise func(offset int) []byte) []byte {
+	// if calldataload(0) >> 224 == selector jump to matched
+	code := []byte{0x60, 0x00, 0x35, 0x60, 0xe0, 0x1c, 0x63}
+	code = append(code, selector...)
+	code = append(code, 0x14, 0x61, 0x00, 0x00, 0x57)
+	code = append(code, otherwise(len(code))...)
+	matchedAt := len(code)
+	code[13], code[14] = byte(matchedAt>>8), byte(matchedAt)
+	code = append(code, 0x5b)
+	return append(code, matched(len(code))...)
+}
+
+// deployCode returns creation code that deploys runtime.
+func deployCode(runtime []byte) []byte {
+	init := []byte{
+		0x61, byte(len(runtime) >> 8), byte(len(runtime)), // push2 len
+		0x80,             // dup1
+		0x61, 0x00, 0x0d, // push2 offset of runtime
+		0x60, 0x00, // push1 0
+		0x39,       // codecopy(0, 13, len)
+		0x60, 0x00, // push1 0
+		0xf3, // return(0, len)
+	}
+	return append(init, runtime...)
+}
+
+// returnDataAt returns code, to be placed at offset, that copies data appended after it into
+// memory and ends execution with op (RETURN or REVERT).
+func returnDataAt(offset int, data []byte, op byte) []byte {
+	dataOffset := offset + 13
+	code := []byte{
+		0x61, byte(len(data) >> 8), byte(len(data)), // push2 len
+		0x80,                                          // dup1
+		0x61, byte(dataOffset >> 8), byte(dataOffset), // push2 offset of data
+		0x60, 0x00, // push1 0
+		0x39,       // codecopy(0, offset, len)
+		0x60, 0x00, // push1 0
+		op,
+	}
+	return append(code, data...)
+}
+
+// lookupStubRuntime answers getFileHash with fileHash and any other call with details, which
+// is enough to exercise Registry.LookupFile.
+func lookupStubRuntime(t *testing.T, fileHash string, details chain.FileRegistryFileDetails) []byte {
+	parsed, err := chain.FileRegistryMetaData.GetAbi()
+	require.NoError(t, err)
+	hashData, err := parsed.Methods["getFileHash"].Outputs.Pack(fileHash)
+	require.NoError(t, err)
+	return selectorStubRuntime(parsed.Methods["getFileHash"].ID, returnAt(hashData), detailsAt(t, details))
+}
+
+// hashLookupStubRuntime reverts getFileHash as for an unknown CID and answers any other call
+// with details, like the contract for content registered under another CID.
+func hashLookupStubRuntime(t *testing.T, details chain.FileRegistryFileDetails) []byte {
+	parsed, err := chain.FileRegistryMetaData.GetAbi()
+	require.NoError(t, err)
+	return selectorStubRuntime(parsed.Methods["getFileHash"].ID, revertAt("CID not registered"), detailsAt(t, details))
+}
+
+// detailsAt returns code that answers with the ABI encoding of details.
+func detailsAt(t *testing.T, details chain.FileRegistryFileDetails) func(offset int) []byte {
+	parsed, err := chain.FileRegistryMetaData.GetAbi()
+	require.NoError(t, err)
+	data, err := parsed.Methods["getFileDetails"].Outputs.Pack(details)
+	require.NoError(t, err)
+	return returnAt(data)
+}
+
+// returnAt returns code that ends execution returning data.
+func returnAt(data []byte) func(offset int) []byte {
+	return func(offset int) []byte {
+		return returnDataAt(offset, data, 0xf3)
+	}
+}
+
+// revertAt returns code that reverts with Error(reason), like the contract does for unknown files.
+func revertAt(reason string) func(offset int) []byte {
+	data, err := abi.Arguments{{Type: abi.Type{T: abi.StringTy}}}.Pack(reason)
+	if err != nil {
+		panic(err)
+	}
+	return func(offset int) []byte {
+		return returnDataAt(offset, append(common.FromHex("08c379a0"), data...), 0xfd)
+	}
+}
+
+// revertStubRuntime reverts every call with Error(reason).
+func revertStubRuntime(reason string) []byte {
+	return revertAt(reason)(0)
+}
+
+// newSimulatedRegistry deploys the registration stub on a simulated chain funded for key.
+func newSimulatedRegistry(t *testing.T, key *ecdsa.PrivateKey) (*simulated.Backend, *chain.Registry) {
+	// The compiled contract is deployed once go generate has added its bytecode to the binding
+	if chain.FileRegistryMetaData.Bin != "" {
+		return deploySimulatedContract(t, key, common.FromHex(chain.FileRegistryMetaData.Bin))
+	}
+	return newSimulatedContract(t, key, registryStubRuntime())
+}
+
+// newSimulatedContract deploys runtime on a simulated chain funded for key and binds a Registry to it.
+func newSimulatedContract(t *testing.T, key *ecdsa.PrivateKey, runtime []byte) (*simulated.Backend, *chain.Registry) {
+	return deploySimulatedContract(t, key, deployCode(runtime))
+}
+
+// deploySimulatedContract deploys a contract with the given creation code on a simulated chain
+// funded for key and binds a Registry to it.
+func deploySimulatedContract(t *testing.T, key *ecdsa.PrivateKey, code []byte) (*simulated.Backend, *chain.Registry) {
+	t.Helper()
+
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		owner: {Balance: new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))},
+	})
+	t.Cleanup(func() { backend.Close() })
+	client := backend.Client()
+
+	ctx := context.Background()
+	chainID, err := client.ChainID(ctx)
+	require.NoError(t, err)
+	opts, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+	require.NoError(t, err)
+
+	parsed, err := chain.FileRegistryMetaData.GetAbi()
+	require.NoError(t, err)
+	address, _, _, err := bind.DeployContract(opts, *parsed, code, client)
+	require.NoError(t, err)
+	backend.Commit()
+
+	registry, err := chain.NewRegistry(ctx, client, chain.Config{
+		ContractAddress: address,
+		SignerKey:       key,
+		PollInterval:    10 * time.Millisecond,
+		ReceiptTimeout:  5 * time.Second,
+	})
+	require.NoError(t, err)
+	return backend, registry
+}
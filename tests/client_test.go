@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/service"
+	"SafeTransfer/pkg/client"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientGrantShare(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := client.NewKeySigner(key)
+	owner := signer.Address().Hex()
+	grantee := "0x00000000000000000000000000000000000000b0"
+
+	repo := &memoryUserRepository{nonces: map[string]string{}, users: map[string]*model.User{}}
+	userService := &service.UserService{UserRepo: repo, ChainID: 5}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/generateNonce", func(w http.ResponseWriter, r *http.Request) {
+		nonce := "nonce-" + time.Now().Format(time.RFC3339Nano)
+		repo.nonces[strings.ToLower(owner)] = nonce
+		api.RespondWithJSON(w, http.StatusOK, map[string]string{"nonce": nonce})
+	})
+	mux.HandleFunc("/v1/files/42/shares", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Grantee string `json:"grantee"`
+			service.ActionAuthorization
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		params := map[string]interface{}{"fileId": big.NewInt(42), "grantee": req.Grantee}
+		if err := userService.AuthorizeAction(context.Background(), owner, service.ActionShareGrant, params, req.ActionAuthorization); err != nil {
+			api.RespondWithProblem(w, r, err)
+			return
+		}
+		api.RespondWithJSON(w, http.StatusCreated, map[string]interface{}{"fileId": 42, "grantee": req.Grantee, "grantedBy": owner})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	c := client.New(server.URL)
+	ctx := context.Background()
+
+	// The signature is bound to the chain of the server's domain
+	_, err = c.GrantShare(ctx, signer, 42, grantee)
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "invalid_typed_signature", apiErr.Code)
+
+	c.ChainID = 5
+	share, err := c.GrantShare(ctx, signer, 42, grantee)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), share.FileID)
+	assert.Equal(t, grantee, share.Grantee)
+	assert.Equal(t, owner, share.GrantedBy)
+
+	_, err = c.GrantShare(ctx, signer, 42, "bob")
+	assert.Error(t, err)
+}
+
+func TestClientListing(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/files/search", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "report", query.Get("q"))
+		assert.Equal(t, []string{"finance", "q3"}, query["tag"])
+		assert.Equal(t, "2", query.Get("page"))
+		assert.Empty(t, query.Get("pageSize"))
+		api.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"files":    []map[string]interface{}{{"cid": "QmReport", "name": "report.pdf", "version": 2, "size": 1024}},
+			"page":     2,
+			"pageSize": 50,
+			"total":    51,
+		})
+	})
+	mux.HandleFunc("/v1/folders/root/contents", func(w http.ResponseWriter, r *http.Request) {
+		api.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"folder":  nil,
+			"folders": []map[string]interface{}{{"id": 7, "name": "photos", "path": "/photos"}},
+			"files":   []map[string]interface{}{},
+			"total":   1,
+		})
+	})
+	mux.HandleFunc("/v1/files/42/shares/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		assert.Equal(t, "/v1/files/42/shares/0x00000000000000000000000000000000000000b0", r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	c := client.New(server.URL)
+	ctx := context.Background()
+
+	list, err := c.SearchFiles(ctx, client.SearchOptions{Text: "report", Tags: []string{"finance", "q3"}, Page: 2})
+	require.NoError(t, err)
+	require.Len(t, list.Files, 1)
+	assert.Equal(t, "QmReport", list.Files[0].CID)
+	assert.Equal(t, int64(51), list.Total)
+
+	contents, err := c.ListFolder(ctx, 0, 0, 0)
+	require.NoError(t, err)
+	assert.Nil(t, contents.Folder)
+	require.Len(t, contents.Folders, 1)
+	assert.Equal(t, uint(7), contents.Folders[0].ID)
+
+	assert.NoError(t, c.RevokeShare(ctx, 42, "0x00000000000000000000000000000000000000b0"))
+}
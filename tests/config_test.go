@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"SafeTransfer/internal/config"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+// loadConfig loads the configuration with the given command-line flags.
+func loadConfig(t *testing.T, args ...string) (*config.Config, error) {
+	t.Helper()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	config.RegisterFlags(flags)
+	require.NoError(t, flags.Parse(args))
+	return config.Load(flags)
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestConfigDefaults(t *testing.T) {
+	t.Setenv(config.FileEnv, "")
+	t.Setenv("DB_HOST", "")
+	t.Setenv("PORT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+
+	cfg, err := loadConfig(t)
+	require.NoError(t, err)
+	assert.Equal(t, 8083, cfg.Server.Port)
+	assert.Equal(t, "localhost", cfg.Database.Host)
+	assert.Equal(t, 5432, cfg.Database.Port)
+	assert.Equal(t, int64(1073741824), cfg.Storage.DefaultQuotaBytes)
+	assert.Equal(t, 15*time.Second, cfg.Chain.IndexerPollInterval)
+	assert.Equal(t, "off", cfg.Chain.VerifyMode)
+	assert.False(t, cfg.Chain.Enabled())
+	assert.Nil(t, cfg.Chain.GasFeeCap)
+	assert.False(t, cfg.Tracing.Enabled())
+	assert.Equal(t, "safetransfer", cfg.Tracing.ServiceName)
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  port: 9000
+database:
+  host: db.internal
+  name: files
+auth:
+  admin_addresses:
+    - "0x00000000000000000000000000000000000000a1"
+    - "0x00000000000000000000000000000000000000a2"
+chain:
+  receipt_timeout: 10m
+`)
+	t.Setenv(config.FileEnv, path)
+	t.Setenv("DB_HOST", "db.env")
+	t.Setenv("DB_NAME", "")
+	t.Setenv("PORT", "9100")
+
+	cfg, err := loadConfig(t, "-port", "9200")
+	require.NoError(t, err)
+	assert.Equal(t, 9200, cfg.Server.Port, "flags override the environment")
+	assert.Equal(t, "db.env", cfg.Database.Host, "the environment overrides the file")
+	assert.Equal(t, "files", cfg.Database.Name, "empty variables are unset")
+	assert.Equal(t, []string{"0x00000000000000000000000000000000000000a1", "0x00000000000000000000000000000000000000a2"}, cfg.Auth.AdminAddresses)
+	assert.Equal(t, 10*time.Minute, cfg.Chain.ReceiptTimeout)
+	assert.Equal(t, "postgres", cfg.Database.User, "defaults fill the rest")
+}
+
+func TestConfigTOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[database]
+port = 6432
+
+[storage]
+default_quota_files = 50
+`)
+	t.Setenv(config.FileEnv, "")
+
+	cfg, err := loadConfig(t, "-config", path)
+	require.NoError(t, err)
+	assert.Equal(t, 6432, cfg.Database.Port)
+	assert.Equal(t, int64(50), cfg.Storage.DefaultQuotaFiles)
+}
+
+func TestConfigSecretFiles(t *testing.T) {
+	t.Setenv(config.FileEnv, "")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_SECRET_FILE", writeFile(t, "jwt_secret", "from-env-file\n"))
+	t.Setenv("DB_PASSWORD", "from-env")
+	t.Setenv("DB_PASSWORD_FILE", "")
+
+	cfg, err := loadConfig(t, "-db-password-file", writeFile(t, "db_password", "from-flag-file"))
+	require.NoError(t, err)
+	assert.Equal(t, "from-env-file", cfg.Auth.JWTSecret, "the trailing line break is dropped")
+	assert.Equal(t, "from-flag-file", cfg.Database.Password)
+
+	// A secret cannot be given both directly and as a file in the same place.
+	t.Setenv("JWT_SECRET", "direct")
+	_, err = loadConfig(t)
+	var configErr *config.Error
+	require.ErrorAs(t, err, &configErr)
+	assert.Equal(t, []string{"JWT_SECRET and JWT_SECRET_FILE are both set in the environment"}, configErr.Problems)
+}
+
+func TestConfigValidation(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+database:
+  hots: db.internal
+chain:
+  verify_mode: strict
+  signer_key: "`+testKey+`"
+`)
+	t.Setenv(config.FileEnv, path)
+	t.Setenv("PORT", "http")
+	t.Setenv("CHAIN_RECEIPT_TIMEOUT", "soon")
+	t.Setenv("ENCRYPTION_KEK", "")
+	t.Setenv("ENCRYPTION_KEK_FILE", writeFile(t, "kek", "not hex"))
+	t.Setenv("ETH_RPC_URL", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "collector:4318")
+
+	_, err := loadConfig(t, "-admin-addresses", "0xnope")
+	var configErr *config.Error
+	require.ErrorAs(t, err, &configErr)
+	assert.Equal(t, []string{
+		`PORT "http" (from environment) must be an integer`,
+		`CHAIN_RECEIPT_TIMEOUT "soon" (from environment) must be a duration such as 30s`,
+		`unknown setting "database.hots" in ` + path,
+		`ADMIN_ADDRESSES: "0xnope" is not an Ethereum address`,
+		"ENCRYPTION_KEK must be a hex-encoded 32-byte key",
+		"CHAIN_VERIFY_MODE strict requires ETH_RPC_URL",
+		"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT must be an http or https URL",
+	}, configErr.Problems)
+	assert.NotContains(t, err.Error(), testKey)
+	assert.NotContains(t, err.Error(), "not hex")
+}
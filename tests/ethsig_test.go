@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"SafeTransfer/internal/ethsig"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const signedMessage = "Sign in to SafeTransfer: nonce-1"
+
+// rawSignature signs message with key and returns the [R || S || V] signature with V as the
+// recovery ID.
+func rawSignature(t testing.TB, key *ecdsa.PrivateKey, message string) []byte {
+	signature, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	require.NoError(t, err)
+	return signature
+}
+
+func withV(signature []byte, v byte) []byte {
+	modified := append([]byte(nil), signature...)
+	modified[crypto.RecoveryIDOffset] = v
+	return modified
+}
+
+func TestRecoverMessage(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	signature := rawSignature(t, key, signedMessage)
+	recoveryID := signature[crypto.RecoveryIDOffset]
+
+	// The same signature with S replaced by N - S and the other recovery ID
+	s := new(big.Int).SetBytes(signature[32:64])
+	highS := append([]byte(nil), signature...)
+	new(big.Int).Sub(crypto.S256().Params().N, s).FillBytes(highS[32:64])
+	highS[crypto.RecoveryIDOffset] = 1 - recoveryID
+
+	zeroR := append([]byte(nil), signature...)
+	copy(zeroR[:32], make([]byte, 32))
+
+	tests := []struct {
+		name      string
+		signature string
+		err       error
+	}{
+		{"recovery ID", hexutil.Encode(signature), nil},
+		{"V of 27 or 28", hexutil.Encode(withV(signature, recoveryID+27)), nil},
+		{"EIP-155 V on mainnet", hexutil.Encode(withV(signature, recoveryID+37)), nil},
+		{"EIP-155 V on chain 100", hexutil.Encode(withV(signature, recoveryID+235)), nil},
+		{"without 0x prefix", strings.TrimPrefix(hexutil.Encode(signature), "0x"), nil},
+		{"empty", "", ethsig.ErrSignatureEncoding},
+		{"not hex", "0xzz", ethsig.ErrSignatureEncoding},
+		{"too short", hexutil.Encode(signature[:64]), ethsig.ErrSignatureLength},
+		{"too long", hexutil.Encode(append(signature, 0)), ethsig.ErrSignatureLength},
+		{"V of 2", hexutil.Encode(withV(signature, 2)), ethsig.ErrRecoveryID},
+		{"V of 29", hexutil.Encode(withV(signature, 29)), ethsig.ErrRecoveryID},
+		{"V of 34", hexutil.Encode(withV(signature, 34)), ethsig.ErrRecoveryID},
+		{"high S", hexutil.Encode(highS), ethsig.ErrMalleableSignature},
+		{"zero R", hexutil.Encode(zeroR), ethsig.ErrUnrecoverableSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recovered, err := ethsig.RecoverMessage([]byte(signedMessage), tt.signature)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, signer, recovered)
+		})
+	}
+}
+
+func TestRecoverMessageErrorClasses(t *testing.T) {
+	for _, err := range []error{ethsig.ErrSignatureEncoding, ethsig.ErrSignatureLength, ethsig.ErrRecoveryID} {
+		assert.ErrorIs(t, err, ethsig.ErrMalformedSignature)
+		assert.NotErrorIs(t, err, ethsig.ErrInvalidSignature)
+	}
+	for _, err := range []error{ethsig.ErrMalleableSignature, ethsig.ErrUnrecoverableSignature} {
+		assert.ErrorIs(t, err, ethsig.ErrInvalidSignature)
+		assert.NotErrorIs(t, err, ethsig.ErrMalformedSignature)
+	}
+}
+
+func TestRecoverMessageOtherMessage(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signature := hexutil.Encode(rawSignature(t, key, signedMessage))
+
+	// A signature over another message recovers to an unrelated address
+	recovered, err := ethsig.RecoverMessage([]byte("another message"), signature)
+	if err == nil {
+		assert.NotEqual(t, crypto.PubkeyToAddress(key.PublicKey), recovered)
+	} else {
+		assert.ErrorIs(t, err, ethsig.ErrInvalidSignature)
+	}
+}
+
+func FuzzRecoverMessage(f *testing.F) {
+	key, err := crypto.GenerateKey()
+	require.NoError(f, err)
+	signature := rawSignature(f, key, signedMessage)
+
+	f.Add(signedMessage, signature)
+	f.Add(signedMessage, withV(signature, 27))
+	f.Add(signedMessage, withV(signature, 38))
+	f.Add("", signature[:64])
+	f.Add("message", []byte{})
+	f.Add("message", make([]byte, 65))
+
+	f.Fuzz(func(t *testing.T, message string, signature []byte) {
+		recovered, err := ethsig.RecoverMessage([]byte(message), hexutil.Encode(signature))
+		if err != nil {
+			if !errors.Is(err, ethsig.ErrMalformedSignature) && !errors.Is(err, ethsig.ErrInvalidSignature) {
+				t.Fatalf("unclassified error: %v", err)
+			}
+			return
+		}
+
+		// Only canonical 65-byte signatures are accepted
+		if len(signature) != crypto.SignatureLength {
+			t.Fatalf("accepted a %d-byte signature", len(signature))
+		}
+		if new(big.Int).SetBytes(signature[32:64]).Cmp(new(big.Int).Rsh(crypto.S256().Params().N, 1)) > 0 {
+			t.Fatal("accepted a high S value")
+		}
+		if recovered == (common.Address{}) {
+			t.Fatal("recovered the zero address")
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newMemoryIPFS starts a fake IPFS API that stores added content in memory under a CID derived
+// from its hash, serves it back from cat and forgets it when it is unpinned.
+func newMemoryIPFS(t *testing.T) *httptest.Server {
+	var (
+		mu    sync.Mutex
+		blobs = make(map[string][]byte)
+	)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v0/version":
+			w.Write([]byte(`{"Version":"0.26.0"}`))
+		case "/api/v0/add":
+			reader, err := r.MultipartReader()
+			if !assert.NoError(t, err) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			part, err := reader.NextPart()
+			if !assert.NoError(t, err) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			content, err := io.ReadAll(part)
+			assert.NoError(t, err)
+			digest := sha256.Sum256(content)
+			cid := "Qm" + hex.EncodeToString(digest[:16])
+			blobs[cid] = content
+			fmt.Fprintf(w, `{"Name":"file","Hash":%q,"Size":"%d"}`, cid, len(content))
+		case "/api/v0/cat":
+			content, ok := blobs[r.URL.Query().Get("arg")]
+			if !ok {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"Message":"block not found","Code":0,"Type":"error"}`))
+				return
+			}
+			w.Write(content)
+		case "/api/v0/pin/rm":
+			cid := r.URL.Query().Get("arg")
+			delete(blobs, cid)
+			fmt.Fprintf(w, `{"Pins":[%q]}`, cid)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"Message":"unsupported","Code":0,"Type":"error"}`))
+		}
+	}))
+}
+
+// memoryFile is an uploaded file held in memory.
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error { return nil }
+
+// unlimitedUserRepository grants every storage reservation.
+type unlimitedUserRepository struct {
+	repository.UserRepository
+}
+
+func (unlimitedUserRepository) ReserveStorage(ctx context.Context, ethereumAddress string, bytes, files, defaultQuotaBytes, defaultQuotaFiles int64) (bool, error) {
+	return true, nil
+}
+
+func (unlimitedUserRepository) ReleaseStorage(ctx context.Context, ethereumAddress string, bytes, files int64) error {
+	return nil
+}
+
+// memoryShareRepository keeps shares in memory.
+type memoryShareRepository struct {
+	repository.ShareRepository
+	shares []model.Share
+}
+
+func (repo *memoryShareRepository) SaveShare(ctx context.Context, share *model.Share) error {
+	repo.shares = append(repo.shares, *share)
+	return nil
+}
+
+func (repo *memoryShareRepository) DeleteShare(ctx context.Context, logicalFileID uint, granteeAddress string) (bool, error) {
+	for i, share := range repo.shares {
+		if share.LogicalFileID == logicalFileID && share.GranteeAddress == granteeAddress {
+			repo.shares = append(repo.shares[:i], repo.shares[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (repo *memoryShareRepository) HasShare(ctx context.Context, logicalFileID uint, granteeAddress string) (bool, error) {
+	for _, share := range repo.shares {
+		if share.LogicalFileID == logicalFileID && share.GranteeAddress == granteeAddress {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/service"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryFolderRepository keeps folders in memory. Unlike the database it does not rewrite the
+// paths of subfolders.
+type memoryFolderRepository struct {
+	repository.FolderRepository
+	folders []model.Folder
+}
+
+func (repo *memoryFolderRepository) CreateFolder(ctx context.Context, folder *model.Folder) error {
+	folder.ID = uint(len(repo.folders) + 1)
+	repo.folders = append(repo.folders, *folder)
+	return nil
+}
+
+func (repo *memoryFolderRepository) GetFolderByID(ctx context.Context, id uint) (*model.Folder, error) {
+	for _, folder := range repo.folders {
+		if folder.ID == id {
+			return &folder, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (repo *memoryFolderRepository) UpdateFolder(ctx context.Context, folder *model.Folder, oldPath string) error {
+	repo.folders[folder.ID-1] = *folder
+	return nil
+}
+
+func TestMoveFolderIntoItself(t *testing.T) {
+	ctx := context.Background()
+	folderService := service.NewFolderService(&memoryFolderRepository{}, nil)
+	docs, err := folderService.CreateFolder(ctx, ownerAddress, "docs", nil)
+	require.NoError(t, err)
+	reports, err := folderService.CreateFolder(ctx, ownerAddress, "reports", &docs.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "/docs/reports", reports.Path)
+
+	_, err = folderService.MoveFolder(ctx, ownerAddress, docs.ID, &docs.ID)
+	assert.ErrorIs(t, err, service.ErrInvalidFolderMove)
+	_, err = folderService.MoveFolder(ctx, ownerAddress, docs.ID, &reports.ID)
+	assert.ErrorIs(t, err, service.ErrInvalidFolderMove)
+
+	// Folders of other owners cannot be moved or moved into
+	_, err = folderService.MoveFolder(ctx, strangerAddress, reports.ID, nil)
+	assert.ErrorIs(t, err, service.ErrFolderNotFound)
+	other, err := folderService.CreateFolder(ctx, strangerAddress, "other", nil)
+	require.NoError(t, err)
+	_, err = folderService.MoveFolder(ctx, ownerAddress, reports.ID, &other.ID)
+	assert.ErrorIs(t, err, service.ErrFolderNotFound)
+
+	moved, err := folderService.MoveFolder(ctx, ownerAddress, reports.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/reports", moved.Path)
+	assert.Nil(t, moved.ParentID)
+
+	renamed, err := folderService.RenameFolder(ctx, ownerAddress, docs.ID, "documents")
+	require.NoError(t, err)
+	assert.Equal(t, "/documents", renamed.Path)
+	_, err = folderService.RenameFolder(ctx, ownerAddress, docs.ID, "a/b")
+	assert.ErrorIs(t, err, service.ErrInvalidFolderName)
+}
+
+func TestFolderPathRewriting(t *testing.T) {
+	testDB := setupTestDatabase(t)
+	defer testDB.Close()
+	folderRepo := repository.NewFolderRepository(testDB)
+	folderService := service.NewFolderService(folderRepo, repository.NewFileRepository(testDB))
+	ctx := context.Background()
+	owner := fmt.Sprintf("0x%040x", time.Now().UnixNano())
+
+	create := func(name string, parentID *uint) *model.Folder {
+		folder, err := folderService.CreateFolder(ctx, owner, name, parentID)
+		require.NoError(t, err)
+		return folder
+	}
+	path := func(folder *model.Folder) string {
+		stored, err := folderRepo.GetFolderByID(ctx, folder.ID)
+		require.NoError(t, err)
+		return stored.Path
+	}
+	docs := create("docs", nil)
+	archive := create("archive", nil)
+	reports := create("reports", &docs.ID)
+	year := create("2024", &reports.ID)
+	month := create("01", &year.ID)
+	// Only descendants are rewritten, not folders whose names merely share the prefix
+	similar := create("reports-old", &docs.ID)
+
+	_, err := folderService.RenameFolder(ctx, owner, reports.ID, "statements")
+	require.NoError(t, err)
+	assert.Equal(t, "/docs/statements", path(reports))
+	assert.Equal(t, "/docs/statements/2024", path(year))
+	assert.Equal(t, "/docs/statements/2024/01", path(month))
+	assert.Equal(t, "/docs/reports-old", path(similar))
+
+	_, err = folderService.MoveFolder(ctx, owner, reports.ID, &archive.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "/archive/statements", path(reports))
+	assert.Equal(t, "/archive/statements/2024", path(year))
+	assert.Equal(t, "/archive/statements/2024/01", path(month))
+	assert.Equal(t, "/docs/reports-old", path(similar))
+
+	_, err = folderService.MoveFolder(ctx, owner, archive.ID, &month.ID)
+	assert.ErrorIs(t, err, service.ErrInvalidFolderMove)
+	assert.Equal(t, "/archive/statements/2024/01", path(month))
+
+	// Paths stay unique among siblings
+	create("statements", &docs.ID)
+	_, err = folderService.MoveFolder(ctx, owner, reports.ID, &docs.ID)
+	assert.ErrorIs(t, err, service.ErrFolderExists)
+}
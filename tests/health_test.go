@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/service"
+	"SafeTransfer/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	var ipfsCalls atomic.Int32
+	ipfs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ipfsCalls.Add(1)
+		assert.Equal(t, "/api/v0/version", r.URL.Path)
+		w.Write([]byte(`{"Version":"0.26.0"}`))
+	}))
+	defer ipfs.Close()
+
+	chainErr := errors.New("dial tcp 10.0.0.7:8545: connection refused")
+	healthService := service.NewHealthService(
+		service.HealthCheck{Name: "ipfs", Check: storage.NewIPFSStorage(ipfs.URL).Ping, Critical: true},
+		service.HealthCheck{Name: "ethereum", Check: func(ctx context.Context) error { return chainErr }},
+	)
+	router := chi.NewRouter()
+	(&api.Handler{HealthService: healthService}).RegisterRoutes(router)
+
+	readyz := func() (int, service.HealthReport) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var report service.HealthReport
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&report))
+		return recorder.Code, report
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Zero(t, ipfsCalls.Load(), "liveness checks no dependency")
+
+	// A failing non-critical dependency degrades the server without making it unready
+	code, report := readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, service.HealthDegraded, report.Status)
+	require.Len(t, report.Dependencies, 2)
+	assert.Equal(t, service.HealthOK, report.Dependencies[0].Status)
+	assert.Equal(t, service.HealthUnavailable, report.Dependencies[1].Status)
+	assert.Equal(t, "unreachable", report.Dependencies[1].Error, "errors are not exposed")
+
+	// The report is cached
+	readyz()
+	assert.Equal(t, int32(1), ipfsCalls.Load())
+
+	// Once it expires, a failing critical dependency makes the server unready
+	healthService.CacheTTL = 0
+	ipfs.Close()
+	code, report = readyz()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, service.HealthUnavailable, report.Status)
+}
+
+func TestHealthCheckTimeout(t *testing.T) {
+	healthService := service.NewHealthService(service.HealthCheck{
+		Name: "slow",
+		Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		Critical: true,
+	})
+	healthService.Timeout = 10 * time.Millisecond
+
+	report := healthService.Readiness()
+	assert.False(t, report.Ready())
+	assert.Equal(t, "timed out", report.Dependencies[0].Error)
+	assert.GreaterOrEqual(t, report.Dependencies[0].LatencyMs, 10.0)
+}
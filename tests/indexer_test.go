@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/service"
+	"context"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryEventRepository keeps indexed events in memory with the semantics of ChainEventRepository.
+type memoryEventRepository struct {
+	events    []model.ChainEvent
+	lastBlock uint64
+	scanned   bool
+}
+
+func (repo *memoryEventRepository) GetCursor(context.Context, string) (uint64, bool, error) {
+	return repo.lastBlock, repo.scanned, nil
+}
+
+func (repo *memoryEventRepository) SaveEvents(_ context.Context, _ string, fromBlock uint64, events []model.ChainEvent, confirmedThrough, lastBlock uint64) error {
+	kept := events
+	for _, event := range repo.events {
+		if event.Confirmed || event.BlockNumber < fromBlock {
+			kept = append(kept, event)
+		}
+	}
+	for i := range kept {
+		if kept[i].BlockNumber <= confirmedThrough {
+			kept[i].Confirmed = true
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].BlockNumber < kept[j].BlockNumber })
+
+	repo.events, repo.lastBlock, repo.scanned = kept, lastBlock, true
+	return nil
+}
+
+func (repo *memoryEventRepository) ConfirmFileRegistrations(context.Context, string) (int64, error) {
+	return 0, nil
+}
+
+func (repo *memoryEventRepository) ListUnmatchedEvents(context.Context, string, int) ([]model.ChainEvent, error) {
+	return nil, nil
+}
+
+func (repo *memoryEventRepository) ListUnregisteredFiles(context.Context, string, int) ([]model.File, error) {
+	return nil, nil
+}
+
+func (repo *memoryEventRepository) find(cid string) []model.ChainEvent {
+	var found []model.ChainEvent
+	for _, event := range repo.events {
+		if event.CID == cid {
+			found = append(found, event)
+		}
+	}
+	return found
+}
+
+func TestChainIndexerConfirmationsAndReorgs(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	backend, registry := newSimulatedRegistry(t, key)
+	repo := &memoryEventRepository{}
+	indexer := service.NewChainIndexer(registry, repo, 0, 2, time.Second)
+	ctx := context.Background()
+
+	_, err = registry.RegisterFile(ctx, "QmFirstCID", "hash-1")
+	require.NoError(t, err)
+	backend.Commit()
+
+	// Indexed as soon as it is mined, but not confirmed until the next block
+	require.NoError(t, indexer.Poll(ctx))
+	events := repo.find("QmFirstCID")
+	require.Len(t, events, 1)
+	assert.False(t, events[0].Confirmed)
+	assert.Equal(t, registry.Signer().Hex(), events[0].Owner)
+	assert.Equal(t, "hash-1", events[0].FileHash)
+
+	backend.Commit()
+	require.NoError(t, indexer.Poll(ctx))
+	events = repo.find("QmFirstCID")
+	require.Len(t, events, 1)
+	assert.True(t, events[0].Confirmed)
+
+	// Register a second file, then replace its block with a longer fork
+	forkPoint, err := backend.Client().HeaderByNumber(ctx, nil)
+	require.NoError(t, err)
+	_, err = registry.RegisterFile(ctx, "QmSecondCID", "hash-2")
+	require.NoError(t, err)
+	backend.Commit()
+	require.NoError(t, indexer.Poll(ctx))
+	require.Len(t, repo.find("QmSecondCID"), 1)
+
+	require.NoError(t, backend.Fork(forkPoint.Hash()))
+	backend.Commit()
+	backend.Commit()
+	require.NoError(t, indexer.Poll(ctx))
+
+	// Every indexed event belongs to the canonical chain; the second registration is either gone
+	// or was re-included in the fork
+	assertCanonical(t, backend, repo.events)
+	assert.LessOrEqual(t, len(repo.find("QmSecondCID")), 1)
+	assert.Len(t, repo.find("QmFirstCID"), 1)
+}
+
+func assertCanonical(t *testing.T, backend *simulated.Backend, events []model.ChainEvent) {
+	t.Helper()
+	for _, event := range events {
+		header, err := backend.Client().HeaderByNumber(context.Background(), new(big.Int).SetUint64(event.BlockNumber))
+		require.NoError(t, err)
+		assert.Equal(t, header.Hash().Hex(), event.BlockHash, "event %s is from a replaced block", event.CID)
+	}
+}
@@ -1,16 +1,15 @@
 package tests
 
 import (
+	"SafeTransfer/internal/config"
 	"SafeTransfer/internal/db"
 	"SafeTransfer/internal/model"
-	"SafeTransfer/utils"
 	"fmt"
-	"log"
-	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 func TestDatabaseMigration(t *testing.T) {
@@ -21,31 +20,51 @@ func TestDatabaseMigration(t *testing.T) {
 	// Check if the migration was successful
 	assertTableExists(t, testDB, "files")
 	assertTableExists(t, testDB, "users")
+	assertTableExists(t, testDB, "folders")
+	assertTableExists(t, testDB, "logical_files")
+	assertTableExists(t, testDB, "chain_events")
+	assertTableExists(t, testDB, "chain_cursors")
+	assertTableExists(t, testDB, "shares")
+	assertTableExists(t, testDB, "api_keys")
+	assertTableExists(t, testDB, "schema_migrations")
+	assert.NoError(t, testDB.CheckSchema())
+
+	// Every model field must have a column, so that model changes come with a migration
+	assertModelColumns(t, testDB)
 }
 
 func setupTestDatabase(t *testing.T) *db.Database {
-	// Set up a test database connection to PostgreSQL
-	host := utils.GetEnvOrDefault("DB_HOST", "localhost")
-	port := utils.GetEnvOrDefault("DB_PORT", "5432")
-	dbname := utils.GetEnvOrDefault("DB_NAME", "postgres")
-	user := utils.GetEnvOrDefault("DB_USER", "postgres")
-	sslmode := utils.GetEnvOrDefault("SSL_MODE", "disable")
-	password, err := os.ReadFile("/run/secrets/db_password")
-	if err != nil {
-		log.Fatalf("Failed to read database password: %v", err)
-	}
-
-	dataSourceName := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s sslmode=%s", host, port, dbname, user, password, sslmode)
-
-	testDB, err := db.NewDatabase(dataSourceName)
+	// Set up a test database connection to PostgreSQL, configured like the server
+	testDB, err := db.NewDatabase(testDataSourceName(t))
 	require.NoError(t, err, "failed to create test database")
 
-	err = testDB.AutoMigrate(&model.File{}, &model.User{})
+	_, err = testDB.Migrate()
 	require.NoError(t, err, "failed to migrate test database")
 
 	return testDB
 }
 
+// testDataSourceName returns the connection string of the configured database.
+func testDataSourceName(t *testing.T) string {
+	cfg, err := config.Load(nil)
+	require.NoError(t, err, "failed to load configuration")
+	database := cfg.Database
+	return fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=%s", database.Host, database.Port, database.Name, database.User, database.Password, database.SSLMode)
+}
+
+// assertModelColumns checks that every field of every model has a column.
+func assertModelColumns(t *testing.T, testDB *db.Database) {
+	for _, m := range []interface{}{&model.File{}, &model.User{}, &model.Folder{}, &model.LogicalFile{}, &model.ChainEvent{}, &model.ChainCursor{}, &model.Share{}, &model.APIKey{}} {
+		statement := &gorm.Statement{DB: testDB.DB}
+		require.NoError(t, statement.Parse(m))
+		for _, field := range statement.Schema.Fields {
+			if field.DBName != "" {
+				assert.True(t, testDB.Migrator().HasColumn(m, field.DBName), "column %s.%s does not exist", statement.Schema.Table, field.DBName)
+			}
+		}
+	}
+}
+
 func assertTableExists(t *testing.T, db *db.Database, tableName string) {
 	// Check if the specified table exists in the database
 	exists := db.Migrator().HasTable(tableName)
@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"SafeTransfer/internal/crypto"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/service"
+	"SafeTransfer/internal/storage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// keyedFileRepository lists and updates the encryption keys of a fixed set of files.
+type keyedFileRepository struct {
+	repository.FileRepository
+	files []model.File
+}
+
+func (repo *keyedFileRepository) ListFilesAfter(ctx context.Context, ethereumAddress string, afterID uint, limit int) ([]model.File, error) {
+	var files []model.File
+	for _, file := range repo.files {
+		if file.ID > afterID && len(files) < limit {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func (repo *keyedFileRepository) UpdateEncryptionKey(ctx context.Context, id uint, key string) error {
+	for i := range repo.files {
+		if repo.files[i].ID == id {
+			repo.files[i].EncryptionKey = key
+		}
+	}
+	return nil
+}
+
+func (repo *keyedFileRepository) GetFileMetadataByCID(ctx context.Context, cid string) (*model.File, error) {
+	for i := range repo.files {
+		if repo.files[i].CID == cid {
+			return &repo.files[i], nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func TestKeyRingWrapAndUnwrap(t *testing.T) {
+	oldKEK := bytes.Repeat([]byte{1}, crypto.KEKSize)
+	newKEK := bytes.Repeat([]byte{2}, crypto.KEKSize)
+	key := bytes.Repeat([]byte{7}, 32)
+
+	oldRing, err := crypto.NewKeyRing(oldKEK)
+	require.NoError(t, err)
+	wrapped, err := oldRing.Wrap(key)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(wrapped, "kek:"+oldRing.CurrentKEKID()+":"))
+	assert.NotContains(t, wrapped, string(key))
+
+	unwrapped, err := oldRing.Unwrap(wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, key, unwrapped)
+	assert.False(t, oldRing.NeedsRewrap(wrapped))
+
+	// After rotation the old KEK still unwraps existing keys, which now need rewrapping.
+	newRing, err := crypto.NewKeyRing(newKEK, oldKEK)
+	require.NoError(t, err)
+	unwrapped, err = newRing.Unwrap(wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, key, unwrapped)
+	assert.True(t, newRing.NeedsRewrap(wrapped))
+
+	// Without the old KEK the key cannot be unwrapped.
+	onlyNew, err := crypto.NewKeyRing(newKEK)
+	require.NoError(t, err)
+	_, err = onlyNew.Unwrap(wrapped)
+	assert.ErrorIs(t, err, crypto.ErrUnknownKEK)
+
+	// Keys stored before a KEK was configured are plain base64.
+	var noRing *crypto.KeyRing
+	legacy, err := noRing.Wrap(key)
+	require.NoError(t, err)
+	unwrapped, err = newRing.Unwrap(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, key, unwrapped)
+	assert.True(t, newRing.NeedsRewrap(legacy))
+
+	_, err = crypto.NewKeyRing([]byte("short"))
+	assert.Error(t, err)
+}
+
+func TestRotateKeys(t *testing.T) {
+	ctx := context.Background()
+	oldKEK := bytes.Repeat([]byte{1}, crypto.KEKSize)
+	newKEK := bytes.Repeat([]byte{2}, crypto.KEKSize)
+	key := bytes.Repeat([]byte{7}, 32)
+
+	oldRing, err := crypto.NewKeyRing(oldKEK)
+	require.NoError(t, err)
+	wrappedOld, err := oldRing.Wrap(key)
+	require.NoError(t, err)
+	var noRing *crypto.KeyRing
+	legacy, err := noRing.Wrap(key)
+	require.NoError(t, err)
+
+	newRing, err := crypto.NewKeyRing(newKEK, oldKEK)
+	require.NoError(t, err)
+	wrappedNew, err := newRing.Wrap(key)
+	require.NoError(t, err)
+
+	repo := &keyedFileRepository{files: []model.File{
+		{Model: gorm.Model{ID: 1}, CID: "QmLegacy", EncryptionKey: legacy},
+		{Model: gorm.Model{ID: 2}, CID: "QmOld", EncryptionKey: wrappedOld},
+		{Model: gorm.Model{ID: 3}, CID: "QmNew", EncryptionKey: wrappedNew},
+	}}
+	maintenanceService := service.NewMaintenanceService(nil, repo, nil, newRing)
+
+	rewrapped, err := maintenanceService.RotateKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rewrapped)
+	assert.Equal(t, wrappedNew, repo.files[2].EncryptionKey)
+	for _, file := range repo.files {
+		assert.False(t, newRing.NeedsRewrap(file.EncryptionKey), file.CID)
+		unwrapped, err := newRing.Unwrap(file.EncryptionKey)
+		require.NoError(t, err)
+		assert.Equal(t, key, unwrapped)
+	}
+
+	rewrapped, err = maintenanceService.RotateKeys(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, rewrapped)
+
+	_, err = service.NewMaintenanceService(nil, repo, nil, nil).RotateKeys(ctx)
+	assert.ErrorIs(t, err, service.ErrNoKEK)
+}
+
+func TestUnpinOrphans(t *testing.T) {
+	pinned := map[string]bool{"QmStored": true, "QmOrphan": true, "QmUploading": true}
+	ipfs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/pin/ls":
+			keys := make(map[string]map[string]string)
+			for cid := range pinned {
+				keys[cid] = map[string]string{"Type": "recursive"}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"Keys": keys})
+		case "/api/v0/pin/rm":
+			cid := r.URL.Query().Get("arg")
+			delete(pinned, cid)
+			json.NewEncoder(w).Encode(map[string][]string{"Pins": {cid}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ipfs.Close()
+
+	repo := &keyedFileRepository{files: []model.File{{Model: gorm.Model{ID: 1}, CID: "QmStored"}}}
+	maintenanceService := service.NewMaintenanceService(storage.NewIPFSStorage(ipfs.URL), repo, nil, nil)
+
+	orphans, err := maintenanceService.FindOrphanedPins(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"QmOrphan", "QmUploading"}, orphans)
+
+	// An upload that completes during the grace period keeps its content.
+	repo.files = append(repo.files, model.File{Model: gorm.Model{ID: 2}, CID: "QmUploading"})
+
+	unpinned, err := maintenanceService.UnpinOrphans(context.Background(), orphans)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"QmOrphan"}, unpinned)
+	assert.Equal(t, map[string]bool{"QmStored": true, "QmUploading": true}, pinned)
+}
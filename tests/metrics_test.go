@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/metrics"
+	"SafeTransfer/internal/storage"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleCount returns the number of observations of a histogram.
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	t.Helper()
+	var metric dto.Metric
+	require.NoError(t, observer.(prometheus.Metric).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestHTTPMetrics(t *testing.T) {
+	router := chi.NewRouter()
+	(&api.Handler{}).RegisterRoutes(router)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+
+	healthz := metrics.HTTPRequests.WithLabelValues("/healthz", http.MethodGet, "200")
+	download := metrics.HTTPRequests.WithLabelValues("/v1/download/{cid}", http.MethodGet, "401")
+	unmatched := metrics.HTTPRequests.WithLabelValues("unmatched", http.MethodGet, "404")
+	missingToken := metrics.AuthAttempts.WithLabelValues(metrics.AuthJWT, "failure", "missing_token")
+	otherMethod := metrics.HTTPRequests.WithLabelValues("unmatched", "other", "405")
+	before := []float64{testutil.ToFloat64(healthz), testutil.ToFloat64(download), testutil.ToFloat64(unmatched), testutil.ToFloat64(missingToken), testutil.ToFloat64(otherMethod)}
+
+	serve(http.MethodGet, "/healthz")
+	serve(http.MethodGet, "/v1/download/QmFirst")
+	serve(http.MethodGet, "/v1/download/QmSecond")
+	serve(http.MethodGet, "/nowhere")
+	serve("PURGE", "/nowhere")
+	serve("X-RANDOM-1", "/nowhere")
+
+	assert.Equal(t, before[0]+1, testutil.ToFloat64(healthz))
+	assert.Equal(t, before[1]+2, testutil.ToFloat64(download), "requests are counted by route pattern")
+	assert.Equal(t, before[2]+1, testutil.ToFloat64(unmatched))
+	assert.Equal(t, before[3]+2, testutil.ToFloat64(missingToken))
+	assert.Equal(t, before[4]+2, testutil.ToFloat64(otherMethod), "non-standard methods share a series")
+
+	recorder := serve(http.MethodGet, "/metrics")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `safetransfer_http_requests_total{method="GET",route="/v1/download/{cid}",status="401"}`)
+	assert.Contains(t, string(body), "go_goroutines")
+}
+
+func TestMetricsToken(t *testing.T) {
+	router := chi.NewRouter()
+	(&api.Handler{MetricsToken: "scrape-secret"}).RegisterRoutes(router)
+	scrape := func(authorization string) int {
+		request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, scrape(""))
+	assert.Equal(t, http.StatusUnauthorized, scrape("Bearer wrong-secret"))
+	assert.Equal(t, http.StatusUnauthorized, scrape("scrape-secret"))
+	assert.Equal(t, http.StatusOK, scrape("Bearer scrape-secret"))
+}
+
+func TestIPFSMetrics(t *testing.T) {
+	ipfs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/version":
+			w.Write([]byte(`{"Version":"0.26.0"}`))
+		case "/api/v0/add":
+			_, err := io.Copy(io.Discard, r.Body)
+			assert.NoError(t, err)
+			w.Write([]byte(`{"Name":"file","Hash":"QmTest","Size":"12"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"Message":"not found","Code":0,"Type":"error"}`))
+		}
+	}))
+	defer ipfs.Close()
+	ipfsStorage := storage.NewIPFSStorage(ipfs.URL)
+
+	add := metrics.IPFSRequestDuration.WithLabelValues("add")
+	encrypt := metrics.CipherDuration.WithLabelValues("encrypt")
+	catErrors := metrics.IPFSErrors.WithLabelValues("cat")
+	addCount, encryptCount, catErrorCount := sampleCount(t, add), sampleCount(t, encrypt), testutil.ToFloat64(catErrors)
+
+	cid, _, err := ipfsStorage.UploadFileToIPFS(context.Background(), bytes.NewReader([]byte("hello, world")), make([]byte, 32))
+	require.NoError(t, err)
+	assert.Equal(t, "QmTest", cid)
+	assert.Equal(t, addCount+1, sampleCount(t, add))
+	assert.Equal(t, encryptCount+1, sampleCount(t, encrypt), "encryption is timed once the content is read")
+
+	_, err = ipfsStorage.DownloadFileFromIPFS(context.Background(), "QmMissing")
+	require.Error(t, err)
+	assert.Equal(t, catErrorCount+1, testutil.ToFloat64(catErrors))
+}
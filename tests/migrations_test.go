@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"SafeTransfer/internal/db"
+	"SafeTransfer/internal/model"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// baselineUser and baselineFile are the models of the first release, whose schema was created by AutoMigrate.
+type baselineUser struct {
+	gorm.Model
+	EthereumAddress string `gorm:"uniqueIndex"`
+	Nonce           string
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineFile struct {
+	gorm.Model
+	CID             string `gorm:"column:cid;type:varchar(255);uniqueIndex"`
+	EthereumAddress string `gorm:"column:ethereum_address;type:varchar(255);index"`
+	EncryptionKey   string `gorm:"column:encryption_key;type:varchar(255)"`
+	Nonce           string `gorm:"column:nonce;type:varchar(255)"`
+	Signature       string `gorm:"column:signature;type:text"`
+	PublicKey       string `gorm:"column:public_key;type:text"`
+}
+
+func (baselineFile) TableName() string { return "files" }
+
+func TestMigrations(t *testing.T) {
+	migrations, err := db.Migrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "initial_schema", migrations[0].Name)
+
+	latest, err := db.LatestVersion()
+	require.NoError(t, err)
+	assert.Equal(t, migrations[len(migrations)-1].Version, latest)
+
+	// Each migration's down file drops the tables its up file creates
+	createTable := regexp.MustCompile(`(?i)CREATE TABLE IF NOT EXISTS (\w+)`)
+	for _, migration := range migrations {
+		for _, match := range createTable.FindAllStringSubmatch(migration.Up, -1) {
+			assert.Regexp(t, `(?i)DROP TABLE IF EXISTS `+match[1]+`;`, migration.Down, "migration %d", migration.Version)
+		}
+	}
+}
+
+func TestMigrateFromBaseline(t *testing.T) {
+	// The baseline schema is created in a schema of its own, so that the shared tables are left alone
+	schema := fmt.Sprintf("baseline_%d", time.Now().UnixNano())
+	adminDB, err := db.NewDatabase(testDataSourceName(t))
+	require.NoError(t, err)
+	defer adminDB.Close()
+	require.NoError(t, adminDB.Exec("CREATE SCHEMA "+schema).Error)
+	defer adminDB.Exec("DROP SCHEMA " + schema + " CASCADE")
+
+	testDB, err := db.NewDatabase(testDataSourceName(t) + " search_path=" + schema)
+	require.NoError(t, err)
+	defer testDB.Close()
+	require.NoError(t, testDB.AutoMigrate(&baselineUser{}, &baselineFile{}))
+	require.NoError(t, testDB.Create(&baselineUser{EthereumAddress: ownerAddress, Nonce: "1"}).Error)
+	for _, cid := range []string{"QmFirst", "QmSecond"} {
+		require.NoError(t, testDB.Create(&baselineFile{CID: cid, EthereumAddress: ownerAddress, EncryptionKey: "key", Nonce: "nonce"}).Error)
+	}
+	// The same wallet signed in with its address in two casings, storing a file under each
+	checksummed := "0x00000000000000000000000000000000000000AA"
+	for i, address := range []string{strings.ToLower(checksummed), checksummed} {
+		require.NoError(t, testDB.Create(&baselineUser{EthereumAddress: address, Nonce: "1"}).Error)
+		require.NoError(t, testDB.Create(&baselineFile{CID: fmt.Sprintf("QmCasing%d", i), EthereumAddress: address, EncryptionKey: "key", Nonce: "nonce"}).Error)
+	}
+
+	applied, err := testDB.Migrate()
+	require.NoError(t, err)
+	require.NotEmpty(t, applied)
+	assert.NoError(t, testDB.CheckSchema())
+	assertModelColumns(t, testDB)
+
+	// Every existing file became the first version of a logical file of its own
+	var files []model.File
+	require.NoError(t, testDB.Where("ethereum_address = ?", ownerAddress).Order("id").Find(&files).Error)
+	require.Len(t, files, 2)
+	logicalFiles := make(map[uint]bool)
+	for _, file := range files {
+		require.NotNil(t, file.LogicalFileID, file.CID)
+		assert.Equal(t, 1, file.Version, file.CID)
+		assert.True(t, file.IsLatest, file.CID)
+		logicalFiles[*file.LogicalFileID] = true
+
+		var logicalFile model.LogicalFile
+		require.NoError(t, testDB.First(&logicalFile, *file.LogicalFileID).Error)
+		assert.Equal(t, ownerAddress, logicalFile.EthereumAddress)
+		assert.Equal(t, 1, logicalFile.LatestVersion)
+	}
+	assert.Len(t, logicalFiles, 2)
+
+	// Existing users keep their account, with the files they stored counted against their quota
+	var user model.User
+	require.NoError(t, testDB.Where("ethereum_address = ?", ownerAddress).First(&user).Error)
+	assert.Equal(t, model.RoleUser, user.Role)
+	assert.Equal(t, int64(2), user.FileCount)
+
+	// Addresses are stored checksummed, and the accounts of both casings were merged
+	var users []model.User
+	require.NoError(t, testDB.Where("lower(ethereum_address) = lower(?)", checksummed).Find(&users).Error)
+	require.Len(t, users, 1)
+	assert.Equal(t, checksummed, users[0].EthereumAddress)
+	assert.Equal(t, int64(2), users[0].FileCount)
+	var count int64
+	require.NoError(t, testDB.Model(&model.LogicalFile{}).Where("ethereum_address = ?", checksummed).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
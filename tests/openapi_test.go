@@ -0,0 +1,312 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/ethsig"
+	"SafeTransfer/internal/service"
+	"SafeTransfer/pkg/client"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	legacyrouter "github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAPIRouter mounts the routes of a handler without services, for requests that never reach one.
+func newAPIRouter() chi.Router {
+	r := chi.NewRouter()
+	(&api.Handler{}).RegisterRoutes(r)
+	return r
+}
+
+// loadOpenAPISpec fetches and validates the document served at /v1/openapi.json.
+func loadOpenAPISpec(t *testing.T) *openapi3.T {
+	recorder := httptest.NewRecorder()
+	newAPIRouter().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(recorder.Body.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(loader.Context))
+	return doc
+}
+
+// newSpecServer serves handler, checking every request and response against the OpenAPI
+// document. Requests that do not match it fail the test and are rejected.
+func newSpecServer(t *testing.T, handler http.Handler) *httptest.Server {
+	router, err := legacyrouter.NewRouter(loadOpenAPISpec(t))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		input, err := validateRequest(router, r)
+		if err != nil {
+			t.Errorf("request %s %s does not match the OpenAPI document: %v", r.Method, r.URL.Path, err)
+			api.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		response := recorder.Result()
+
+		err = openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 response.StatusCode,
+			Header:                 response.Header,
+			Body:                   io.NopCloser(bytes.NewReader(recorder.Body.Bytes())),
+		})
+		if err != nil {
+			t.Errorf("response to %s %s does not match the OpenAPI document: %v", r.Method, r.URL.Path, err)
+		}
+
+		for name, values := range response.Header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(response.StatusCode)
+		w.Write(recorder.Body.Bytes())
+		for name, values := range response.Trailer {
+			w.Header()[name] = values
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// validateRequest checks a request against the route it matches, leaving its body to be read again.
+func validateRequest(router routers.Router, r *http.Request) (*openapi3filter.RequestValidationInput, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	route, pathParams, err := router.FindRoute(r)
+	if err != nil {
+		return nil, err
+	}
+	input := &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+	}
+	err = openapi3filter.ValidateRequest(r.Context(), input)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return input, err
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc := loadOpenAPISpec(t)
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+	require.Len(t, doc.Servers, 1)
+	assert.Equal(t, "/v1", doc.Servers[0].URL)
+
+	for _, path := range []string{"/upload", "/download/{cid}", "/generateNonce", "/verifySignature", "/checkToken"} {
+		assert.NotNil(t, doc.Paths.Find(path), "%s is not described", path)
+	}
+
+	// Every operation in the document is served
+	routes := make(map[string]bool)
+	err := chi.Walk(newAPIRouter(), func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		routes[method+" "+route] = true
+		return nil
+	})
+	require.NoError(t, err)
+	for path, item := range doc.Paths {
+		for method := range item.Operations() {
+			assert.True(t, routes[method+" /v1"+path], "%s /v1%s is not a route", method, path)
+		}
+	}
+}
+
+func TestOpenAPIRejectsInvalidRequests(t *testing.T) {
+	router, err := legacyrouter.NewRouter(loadOpenAPISpec(t))
+	require.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/generateNonce", strings.NewReader(`{"ethereumAddress": "alice"}`))
+	request.Header.Set("Content-Type", "application/json")
+	_, err = validateRequest(router, request)
+	assert.Error(t, err)
+
+	request = httptest.NewRequest(http.MethodPost, "/v1/upload", strings.NewReader("description=no+file"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = validateRequest(router, request)
+	assert.Error(t, err)
+}
+
+func TestClientLogin(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := client.NewKeySigner(key)
+
+	const nonce = "0123456789abcdef0123456789abcdef"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/generateNonce", func(w http.ResponseWriter, r *http.Request) {
+		api.RespondWithJSON(w, http.StatusOK, map[string]string{"nonce": nonce})
+	})
+	mux.HandleFunc("/v1/verifySignature", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			EthereumAddress string `json:"ethereumAddress"`
+			Signature       string `json:"signature"`
+			Message         string `json:"message"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		recovered, err := ethsig.RecoverMessage([]byte(req.Message), req.Signature)
+		if err != nil || req.Message != nonce || recovered.Hex() != req.EthereumAddress {
+			api.RespondWithProblem(w, r, service.ErrInvalidSignature)
+			return
+		}
+		api.RespondWithJSON(w, http.StatusOK, map[string]string{"token": "token-for-" + req.EthereumAddress})
+	})
+	mux.HandleFunc("/v1/checkToken", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-for-"+signer.Address().Hex() {
+			api.RespondWithError(w, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+		api.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "ok"})
+	})
+	c := client.New(newSpecServer(t, mux).URL)
+
+	ctx := context.Background()
+	require.Error(t, c.CheckToken(ctx))
+	token, err := c.Login(ctx, signer)
+	require.NoError(t, err)
+	assert.Equal(t, "token-for-"+signer.Address().Hex(), token)
+	assert.NoError(t, c.CheckToken(ctx))
+}
+
+func TestClientUpload(t *testing.T) {
+	content := bytes.Repeat([]byte("safetransfer "), 100000)
+	sum := sha256.Sum256(content)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/upload", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ApiKey st_test", r.Header.Get("Authorization"))
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "7", r.FormValue("folderId"))
+		assert.Equal(t, "quarterly report", r.FormValue("description"))
+		assert.Equal(t, []string{"finance", "q3"}, r.Form["tags"])
+
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		defer file.Close()
+		uploaded, err := io.ReadAll(file)
+		require.NoError(t, err)
+		uploadedSum := sha256.Sum256(uploaded)
+
+		api.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"cid":              "Qm" + header.Filename,
+			"originalFileHash": hex.EncodeToString(uploadedSum[:]),
+			"version":          1,
+			"fileId":           3,
+		})
+	})
+	c := client.New(newSpecServer(t, mux).URL)
+	c.APIKey = "st_test"
+
+	var calls int
+	var transferred, total int64
+	result, err := c.Upload(context.Background(), bytes.NewReader(content), "report.txt", client.UploadOptions{
+		FolderID:    7,
+		Description: "quarterly report",
+		Tags:        []string{"finance", "q3"},
+		Size:        int64(len(content)),
+		Progress: func(n, size int64) {
+			assert.GreaterOrEqual(t, n, transferred)
+			calls++
+			transferred, total = n, size
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Qmreport.txt", result.CID)
+	assert.Equal(t, hex.EncodeToString(sum[:]), result.OriginalFileHash)
+	assert.Equal(t, uint(3), result.FileID)
+	assert.Greater(t, calls, 1)
+	assert.Equal(t, int64(len(content)), transferred)
+	assert.Equal(t, int64(len(content)), total)
+}
+
+func TestClientDownload(t *testing.T) {
+	content := []byte("the decrypted content")
+	sum := sha256.Sum256(content)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/download/", func(w http.ResponseWriter, r *http.Request) {
+		hash := hex.EncodeToString(sum[:])
+		switch strings.TrimPrefix(r.URL.Path, "/v1/download/") {
+		case "QmGood":
+		case "QmTampered":
+			hash = strings.Repeat("0", 64)
+		case "QmLegacy":
+			// Files without a recorded hash send it once the content has been streamed
+			w.Header().Set("Content-Disposition", "attachment; filename=report.txt")
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Trailer", "X-File-Hash, X-Chain-Verification")
+			w.Write(content)
+			w.Header().Set("X-File-Hash", hash)
+			w.Header().Set("X-Chain-Verification", service.ChainVerified)
+			return
+		default:
+			api.RespondWithProblem(w, r, service.ErrFileNotFound)
+			return
+		}
+		w.Header().Set("Content-Disposition", "attachment; filename=report.txt")
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("X-File-Hash", hash)
+		w.Header().Set("X-Chain-Verification", service.ChainVerified)
+		w.Write(content)
+	})
+	c := client.New(newSpecServer(t, mux).URL)
+	ctx := context.Background()
+
+	var transferred int64
+	download, err := c.Download(ctx, "QmGood", func(n, total int64) { transferred = n })
+	require.NoError(t, err)
+	received, err := io.ReadAll(download)
+	require.NoError(t, err)
+	require.NoError(t, download.Close())
+	assert.Equal(t, content, received)
+	assert.Equal(t, "report.txt", download.Filename)
+	assert.Equal(t, service.ChainVerified, download.ChainVerification)
+	assert.Equal(t, int64(len(content)), transferred)
+
+	download, err = c.Download(ctx, "QmTampered", nil)
+	require.NoError(t, err)
+	_, err = io.ReadAll(download)
+	assert.ErrorIs(t, err, client.ErrHashMismatch)
+	download.Close()
+
+	download, err = c.Download(ctx, "QmLegacy", nil)
+	require.NoError(t, err)
+	assert.Empty(t, download.Hash)
+	received, err = io.ReadAll(download)
+	require.NoError(t, err)
+	download.Close()
+	assert.Equal(t, content, received)
+	assert.Equal(t, hex.EncodeToString(sum[:]), download.Hash)
+	assert.Equal(t, service.ChainVerified, download.ChainVerification)
+
+	_, err = c.Download(ctx, "QmMissing", nil)
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr), fmt.Sprint(err))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "file_not_found", apiErr.Code)
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// missingFileRepository knows no files. Its other methods are not implemented.
+type missingFileRepository struct {
+	repository.FileRepository
+}
+
+func (missingFileRepository) GetFileMetadataByCID(ctx context.Context, cid string) (*model.File, error) {
+	return nil, repository.ErrNotFound
+}
+
+// respondWithProblem reports err for a request to path and decodes the problem it was reported as.
+func respondWithProblem(t *testing.T, path string, err error) (int, api.Problem) {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	recorder := httptest.NewRecorder()
+	api.RespondWithProblem(recorder, request, err)
+
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	var problem api.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.Equal(t, recorder.Code, problem.Status)
+	assert.Equal(t, path, problem.Instance)
+	assert.Equal(t, "urn:safetransfer:problem:"+problem.Code, problem.Type)
+	return recorder.Code, problem
+}
+
+func TestRespondWithProblem(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"not found", fmt.Errorf("failed to move file: %w", service.ErrFileNotFound), http.StatusNotFound, "file_not_found"},
+		{"invalid input", fmt.Errorf("%w: name is too long", service.ErrInvalidFolderName), http.StatusBadRequest, "invalid_folder_name"},
+		{"unauthorized", service.ErrStaleNonce, http.StatusUnauthorized, "stale_nonce"},
+		{"forbidden", service.ErrAccountDisabled, http.StatusForbidden, "account_disabled"},
+		{"conflict", service.ErrFolderNotEmpty, http.StatusConflict, "folder_not_empty"},
+		{"too many API keys", service.ErrTooManyAPIKeys, http.StatusConflict, "too_many_api_keys"},
+		{"quota", &service.QuotaExceededError{Resource: "bytes", Used: 90, Requested: 20, Limit: 100}, http.StatusInsufficientStorage, "quota_exceeded"},
+		{"too large", &service.QuotaExceededError{Resource: "bytes", Requested: 200, Limit: 100}, http.StatusRequestEntityTooLarge, "quota_exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, problem := respondWithProblem(t, "/files/1", tt.err)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, tt.err.Error(), problem.Detail)
+		})
+	}
+}
+
+func TestRespondWithProblemHidesInternalErrors(t *testing.T) {
+	status, problem := respondWithProblem(t, "/download/cid", fmt.Errorf("failed to get file metadata: %w", errors.New("connection refused")))
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, "internal_server_error", problem.Code)
+	assert.NotContains(t, problem.Detail, "connection refused")
+
+	// Failures of upstream services are reported by kind only
+	status, problem = respondWithProblem(t, "/verifySignature", fmt.Errorf("%w: dial tcp 10.0.0.1:8545: i/o timeout", service.ErrWalletUnavailable))
+	assert.Equal(t, http.StatusBadGateway, status)
+	assert.Equal(t, "wallet_unavailable", problem.Code)
+	assert.Equal(t, service.ErrWalletUnavailable.Error(), problem.Detail)
+}
+
+func TestDownloadMissingFile(t *testing.T) {
+	downloadService := service.NewDownloadService(nil, missingFileRepository{}, nil, nil, service.ChainVerifyOff, nil)
+	_, err := downloadService.DownloadFile(context.Background(), "0xowner", "QmMissing")
+	assert.ErrorIs(t, err, service.ErrFileNotFound)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	status, problem := respondWithProblem(t, "/download/QmMissing", err)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "file_not_found", problem.Code)
+	assert.NotContains(t, problem.Detail, "record not found")
+}
+
+func TestSignatureErrorKinds(t *testing.T) {
+	userService := service.NewUserService(&memoryUserRepository{}, "secret", 1, nil)
+
+	err := userService.VerifyWalletSignature(context.Background(), userAddress, "message", "0x1234")
+	assert.ErrorIs(t, err, service.ErrMalformedSignature)
+	assert.ErrorIs(t, err, service.ErrInvalidInput)
+	status, problem := respondWithProblem(t, "/verifySignature", err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "malformed_signature", problem.Code)
+
+	err = userService.VerifyWalletSignature(context.Background(), "not an address", "message", "0x1234")
+	assert.ErrorIs(t, err, service.ErrInvalidSignature)
+	assert.ErrorIs(t, err, service.ErrUnauthorized)
+}
+
+func TestMiddlewareProblems(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/upload", nil)
+	request.Header.Set("ApiKeyScopes", service.ScopeDownload)
+	recorder := httptest.NewRecorder()
+	api.ScopeMiddleware(service.ScopeUpload)(http.NotFoundHandler()).ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	var problem api.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.Equal(t, "insufficient_scope", problem.Code)
+	assert.Equal(t, "/upload", problem.Instance)
+}
@@ -0,0 +1,259 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/service"
+	"SafeTransfer/internal/storage"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// formFile is a file part of a multipart form.
+type formFile struct {
+	name    string
+	content string
+}
+
+// multipartRequest builds a form posting every file under field, in order.
+func multipartRequest(t *testing.T, path, field string, files ...formFile) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, file := range files {
+		part, err := writer.CreateFormFile(field, file.name)
+		require.NoError(t, err)
+		_, err = part.Write([]byte(file.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	request := httptest.NewRequest(http.MethodPost, path, &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+func TestReserveAndReleaseStorage(t *testing.T) {
+	ctx := context.Background()
+	quotaFiles := int64(3)
+	userRepo := &memoryUserRepository{users: map[string]*model.User{
+		ownerAddress:   {EthereumAddress: ownerAddress},
+		granteeAddress: {EthereumAddress: granteeAddress, QuotaFiles: &quotaFiles},
+	}}
+	quotaService := service.NewQuotaService(userRepo, 100, 10)
+
+	require.NoError(t, quotaService.Reserve(ctx, ownerAddress, 60))
+	require.NoError(t, quotaService.ReserveFiles(ctx, ownerAddress, 30, 2))
+	usage, err := quotaService.GetUsage(ctx, ownerAddress)
+	require.NoError(t, err)
+	assert.Equal(t, service.Usage{BytesStored: 90, FileCount: 3, QuotaBytes: 100, QuotaFiles: 10}, *usage)
+
+	// A rejected reservation reports the exceeded quota and leaves the usage unchanged
+	var quotaErr *service.QuotaExceededError
+	err = quotaService.Reserve(ctx, ownerAddress, 20)
+	require.ErrorAs(t, err, &quotaErr)
+	assert.ErrorIs(t, err, service.ErrQuotaExceeded)
+	assert.Equal(t, service.QuotaExceededError{Resource: "bytes", Used: 90, Requested: 20, Limit: 100}, *quotaErr)
+	assert.False(t, quotaErr.TooLarge())
+	err = quotaService.Reserve(ctx, ownerAddress, 101)
+	require.ErrorAs(t, err, &quotaErr)
+	assert.True(t, quotaErr.TooLarge())
+	assert.Equal(t, int64(90), userRepo.users[ownerAddress].BytesStored)
+	assert.Equal(t, int64(3), userRepo.users[ownerAddress].FileCount)
+
+	// Released storage can be reserved again, and usage never drops below zero
+	quotaService.Release(ctx, ownerAddress, 60, 1)
+	require.NoError(t, quotaService.Reserve(ctx, ownerAddress, 70))
+	quotaService.Release(ctx, ownerAddress, 1000, 10)
+	assert.Zero(t, userRepo.users[ownerAddress].BytesStored)
+	assert.Zero(t, userRepo.users[ownerAddress].FileCount)
+
+	// Overrides replace the defaults, and the file count is checked as well as the size
+	require.NoError(t, quotaService.ReserveFiles(ctx, granteeAddress, 10, 3))
+	err = quotaService.Reserve(ctx, granteeAddress, 1)
+	require.ErrorAs(t, err, &quotaErr)
+	assert.Equal(t, service.QuotaExceededError{Resource: "files", Used: 3, Requested: 1, Limit: 3}, *quotaErr)
+
+	err = quotaService.Reserve(ctx, strangerAddress, 1)
+	assert.ErrorIs(t, err, service.ErrUserNotFound)
+}
+
+func TestUploadQuotaResponses(t *testing.T) {
+	ctx := context.Background()
+	ipfs := newMemoryIPFS(t)
+	defer ipfs.Close()
+	userRepo := &memoryUserRepository{users: map[string]*model.User{
+		ownerAddress: {EthereumAddress: ownerAddress, Role: model.RoleUser},
+	}}
+	quotaService := service.NewQuotaService(userRepo, 20, 0)
+	fileService := service.NewFileService(storage.NewIPFSStorage(ipfs.URL), &memoryFileRepository{}, nil, quotaService, nil, nil)
+	userService := service.NewUserService(userRepo, "secret", 1337, nil)
+	router := chi.NewRouter()
+	(&api.Handler{FileService: fileService, QuotaService: quotaService, UserService: userService}).RegisterRoutes(router)
+	token, err := userService.GenerateJWT(ctx, ownerAddress)
+	require.NoError(t, err)
+	upload := func(content string) (int, api.Problem) {
+		request := multipartRequest(t, "/v1/upload", "file", formFile{"report.txt", content})
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		var problem api.Problem
+		if recorder.Code != http.StatusOK {
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+		}
+		return recorder.Code, problem
+	}
+
+	status, _ := upload("quarterly report")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(16), userRepo.users[ownerAddress].BytesStored)
+
+	// The file fits the quota, but not next to the files already stored
+	status, problem := upload("meeting notes")
+	assert.Equal(t, http.StatusInsufficientStorage, status)
+	assert.Equal(t, "quota_exceeded", problem.Code)
+
+	// The file is larger than the whole quota
+	status, problem = upload("a file larger than the quota")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+	assert.Equal(t, "quota_exceeded", problem.Code)
+
+	assert.Equal(t, int64(16), userRepo.users[ownerAddress].BytesStored)
+	assert.Equal(t, int64(1), userRepo.users[ownerAddress].FileCount)
+}
+
+func TestQuotaSharedAcrossAddressCasings(t *testing.T) {
+	ipfs := newMemoryIPFS(t)
+	defer ipfs.Close()
+	userRepo := &memoryUserRepository{users: map[string]*model.User{}}
+	quotaService := service.NewQuotaService(userRepo, 20, 0)
+	userService := service.NewUserService(userRepo, "secret", 1337, nil)
+	router := chi.NewRouter()
+	(&api.Handler{
+		FileService:  service.NewFileService(storage.NewIPFSStorage(ipfs.URL), &memoryFileRepository{}, nil, quotaService, nil, nil),
+		QuotaService: quotaService,
+		UserService:  userService,
+	}).RegisterRoutes(router)
+	post := func(path string, body interface{}) map[string]string {
+		encoded, err := json.Marshal(body)
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(encoded)))
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		var response map[string]string
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		return response
+	}
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	// The wallet signs in once with its address in lower case and once in upper case
+	upload := func(ethereumAddress, content string) int {
+		nonce := post("/v1/generateNonce", map[string]string{"ethereumAddress": ethereumAddress})["nonce"]
+		token := post("/v1/verifySignature", map[string]string{
+			"ethereumAddress": ethereumAddress,
+			"message":         nonce,
+			"signature":       signPersonalMessage(t, key, nonce),
+		})["token"]
+		request := multipartRequest(t, "/v1/upload", "file", formFile{"report.txt", content})
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	require.Equal(t, http.StatusOK, upload(strings.ToLower(address), "quarterly report"))
+	assert.Equal(t, http.StatusInsufficientStorage, upload("0x"+strings.ToUpper(address[2:]), "meeting notes"))
+
+	require.Len(t, userRepo.users, 1)
+	assert.Equal(t, int64(16), userRepo.users[address].BytesStored)
+	assert.Equal(t, int64(1), userRepo.users[address].FileCount)
+}
+
+func TestUploadReleasesStorageOnFailure(t *testing.T) {
+	ctx := context.Background()
+	userRepo := &memoryUserRepository{users: map[string]*model.User{
+		ownerAddress: {EthereumAddress: ownerAddress},
+	}}
+	// Without an IPFS node the upload fails after its storage was reserved
+	ipfs := httptest.NewServer(http.NotFoundHandler())
+	defer ipfs.Close()
+	fileService := service.NewFileService(storage.NewIPFSStorage(ipfs.URL), &memoryFileRepository{}, nil, service.NewQuotaService(userRepo, 100, 0), nil, nil)
+
+	_, _, err := fileService.UploadFile(ctx, memoryFile{bytes.NewReader([]byte("quarterly report"))}, ownerAddress, service.UploadOptions{FileName: "report.txt"})
+	require.Error(t, err)
+	assert.Zero(t, userRepo.users[ownerAddress].BytesStored)
+	assert.Zero(t, userRepo.users[ownerAddress].FileCount)
+}
+
+func TestReserveStorage(t *testing.T) {
+	testDB := setupTestDatabase(t)
+	defer testDB.Close()
+	userRepo := repository.NewUserRepository(testDB)
+	ctx := context.Background()
+	owner := fmt.Sprintf("0x%040x", time.Now().UnixNano())
+
+	require.NoError(t, userRepo.SaveOrUpdateUser(ctx, &model.User{EthereumAddress: owner, Role: model.RoleUser}))
+	usage := func() (int64, int64) {
+		user, err := userRepo.FindByEthereumAddress(ctx, owner)
+		require.NoError(t, err)
+		return user.BytesStored, user.FileCount
+	}
+
+	ok, err := userRepo.ReserveStorage(ctx, owner, 60, 1, 100, 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = userRepo.ReserveStorage(ctx, owner, 50, 1, 100, 2)
+	require.NoError(t, err)
+	assert.False(t, ok, "the default byte quota is exceeded")
+	ok, err = userRepo.ReserveStorage(ctx, owner, 40, 1, 100, 2)
+	require.NoError(t, err)
+	assert.True(t, ok, "the quota may be filled exactly")
+	ok, err = userRepo.ReserveStorage(ctx, owner, 0, 1, 100, 2)
+	require.NoError(t, err)
+	assert.False(t, ok, "the default file quota is exceeded")
+	bytesStored, fileCount := usage()
+	assert.Equal(t, int64(100), bytesStored)
+	assert.Equal(t, int64(2), fileCount)
+
+	// Overrides replace the defaults, and zero means unlimited
+	unlimited := int64(0)
+	require.NoError(t, userRepo.SetQuota(ctx, owner, &unlimited, nil))
+	ok, err = userRepo.ReserveStorage(ctx, owner, 1000, 0, 100, 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = userRepo.ReserveStorage(ctx, owner, 0, 1, 100, 0)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, userRepo.ReleaseStorage(ctx, owner, 100, 1))
+	bytesStored, fileCount = usage()
+	assert.Equal(t, int64(1000), bytesStored)
+	assert.Equal(t, int64(2), fileCount)
+	require.NoError(t, userRepo.ReleaseStorage(ctx, owner, 5000, 5))
+	bytesStored, fileCount = usage()
+	assert.Zero(t, bytesStored)
+	assert.Zero(t, fileCount)
+
+	// Addresses are found whatever their casing
+	_, err = userRepo.FindByEthereumAddress(ctx, strings.ToUpper(owner))
+	assert.NoError(t, err)
+
+	ok, err = userRepo.ReserveStorage(ctx, fmt.Sprintf("0x%040x", time.Now().UnixNano()), 1, 1, 0, 0)
+	require.NoError(t, err)
+	assert.False(t, ok, "unknown users have nothing to reserve")
+}
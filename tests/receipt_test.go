@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"SafeTransfer/pkg/receipt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiptSignAndVerify(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	signed, err := receipt.Sign(receipt.Receipt{
+		CID:      "QmReceiptCID",
+		FileHash: verifiedHash,
+		Size:     5,
+		Uploader: "0x00000000000000000000000000000000000000aa",
+		IssuedAt: 1700000000,
+		Storage:  "ipfs://QmReceiptCID",
+		ChainID:  1337,
+		Registry: "0x00000000000000000000000000000000000000bb",
+	}, key)
+	require.NoError(t, err)
+	require.NoError(t, receipt.Verify(signed, signer))
+
+	// Any change to the receipt invalidates the signature
+	tampered := *signed
+	tampered.Receipt.Size = 6
+	assert.ErrorIs(t, receipt.Verify(&tampered, signer), receipt.ErrInvalidSignature)
+
+	tampered = *signed
+	tampered.Receipt.ChainID = 1
+	assert.ErrorIs(t, receipt.Verify(&tampered, signer), receipt.ErrInvalidSignature)
+
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	assert.ErrorIs(t, receipt.Verify(signed, crypto.PubkeyToAddress(other.PublicKey)), receipt.ErrInvalidSignature)
+
+	tampered = *signed
+	tampered.Signature = "0x1234"
+	assert.ErrorIs(t, receipt.Verify(&tampered, signer), receipt.ErrInvalidSignature)
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"SafeTransfer/internal/chain"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/service"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chainStatusRepository records the chain status of files by CID.
+type chainStatusRepository struct {
+	repository.FileRepository
+	statuses map[string]string
+	errors   map[string]string
+}
+
+func newChainStatusRepository() *chainStatusRepository {
+	return &chainStatusRepository{statuses: map[string]string{}, errors: map[string]string{}}
+}
+
+func (repo *chainStatusRepository) UpdateChainStatus(ctx context.Context, cid, txHash, status, chainError string) error {
+	repo.statuses[cid] = status
+	repo.errors[cid] = chainError
+	return nil
+}
+
+func TestRegisterFile(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	backend, registry := newSimulatedRegistry(t, key)
+	repo := newChainStatusRepository()
+	registration := service.NewRegistrationService(registry, repo)
+
+	status := make(chan string)
+	go func() { status <- registration.Register(context.Background(), "QmNewCID", "abc123") }()
+	for {
+		select {
+		case s := <-status:
+			assert.Equal(t, chain.StatusConfirmed, s)
+			assert.Equal(t, chain.StatusConfirmed, repo.statuses["QmNewCID"])
+			return
+		case <-time.After(10 * time.Millisecond):
+			backend.Commit()
+		}
+	}
+}
+
+func TestRegisterDuplicateHash(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	details := chain.FileRegistryFileDetails{Owner: owner, FileHash: "abc123", Timestamp: big.NewInt(1700000000)}
+	backend, registry := newSimulatedContract(t, key, hashLookupStubRuntime(t, details))
+	repo := newChainStatusRepository()
+	ctx := context.Background()
+
+	nonce, err := backend.Client().PendingNonceAt(ctx, owner)
+	require.NoError(t, err)
+
+	status := service.NewRegistrationService(registry, repo).Register(ctx, "QmSecondCID", "abc123")
+	assert.Equal(t, chain.StatusDuplicate, status)
+	assert.Equal(t, chain.StatusDuplicate, repo.statuses["QmSecondCID"])
+	assert.Contains(t, repo.errors["QmSecondCID"], owner.Hex())
+
+	// No transaction was sent, since the contract would revert it
+	after, err := backend.Client().PendingNonceAt(ctx, owner)
+	require.NoError(t, err)
+	assert.Equal(t, nonce, after)
+}
+
+func TestVerifyDownloadByHash(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	content := "quarterly report"
+	details := chain.FileRegistryFileDetails{
+		Owner:     crypto.PubkeyToAddress(key.PublicKey),
+		FileHash:  fmt.Sprintf("%x", sha256.Sum256([]byte(content))),
+		Timestamp: big.NewInt(1700000000),
+	}
+	_, registry := newSimulatedContract(t, key, hashLookupStubRuntime(t, details))
+
+	s := newTransferServices(t)
+	s.downloadService.Registry = registry
+	s.downloadService.ChainVerifyMode = service.ChainVerifyStrict
+	cid, _ := s.upload(t, ownerAddress, "report.txt", content)
+
+	// The CID is not registered, but the content was registered by an earlier upload
+	download, err := s.downloadService.DownloadFile(context.Background(), ownerAddress, cid)
+	require.NoError(t, err)
+	assert.Equal(t, service.ChainVerified, download.Chain.Status)
+	assert.Equal(t, int64(1700000000), download.Chain.RegisteredAt.Unix())
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/service"
+	"SafeTransfer/internal/storage"
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (repo *memoryFileRepository) SaveFileVersion(ctx context.Context, fileMetadata *model.File) error {
+	logicalFile, err := repo.GetLogicalFile(ctx, *fileMetadata.LogicalFileID)
+	if err != nil {
+		return err
+	}
+	for i := range repo.files {
+		if repo.files[i].LogicalFileID != nil && *repo.files[i].LogicalFileID == logicalFile.ID {
+			repo.files[i].IsLatest = false
+		}
+	}
+	logicalFile.LatestVersion++
+	fileMetadata.ID = uint(len(repo.files) + 1)
+	fileMetadata.Version = logicalFile.LatestVersion
+	fileMetadata.IsLatest = true
+	repo.files = append(repo.files, *fileMetadata)
+	return nil
+}
+
+func (repo *memoryFileRepository) GetFileVersion(ctx context.Context, logicalFileID uint, version int) (*model.File, error) {
+	for i := range repo.files {
+		if repo.files[i].LogicalFileID != nil && *repo.files[i].LogicalFileID == logicalFileID && repo.files[i].Version == version {
+			return &repo.files[i], nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (repo *memoryFileRepository) ListFileVersions(ctx context.Context, logicalFileID uint) ([]model.File, error) {
+	var versions []model.File
+	for _, file := range repo.files {
+		if file.LogicalFileID != nil && *file.LogicalFileID == logicalFileID {
+			versions = append(versions, file)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version > versions[j].Version })
+	return versions, nil
+}
+
+func (repo *memoryFileRepository) UpdateMaxVersions(ctx context.Context, logicalFileID uint, maxVersions int) error {
+	logicalFile, err := repo.GetLogicalFile(ctx, logicalFileID)
+	if err != nil {
+		return err
+	}
+	logicalFile.MaxVersions = maxVersions
+	return nil
+}
+
+func (repo *memoryFileRepository) PruneFileVersions(ctx context.Context, logicalFileID uint, keep int) ([]model.File, error) {
+	// Like the database, the latest version is always kept
+	keep = max(keep, 1)
+	versions, err := repo.ListFileVersions(ctx, logicalFileID)
+	if err != nil || len(versions) <= keep {
+		return nil, err
+	}
+	pruned := versions[keep:]
+	retained := repo.files[:0]
+	for _, file := range repo.files {
+		if file.LogicalFileID == nil || *file.LogicalFileID != logicalFileID || file.Version > pruned[0].Version {
+			retained = append(retained, file)
+		}
+	}
+	repo.files = retained
+	return pruned, nil
+}
+
+func TestVersionRetentionAndRestore(t *testing.T) {
+	ctx := context.Background()
+	ipfs := newMemoryIPFS(t)
+	defer ipfs.Close()
+	ipfsStorage := storage.NewIPFSStorage(ipfs.URL)
+	fileRepo := &memoryFileRepository{}
+	shareRepo := &memoryShareRepository{}
+	userRepo := &memoryUserRepository{users: map[string]*model.User{
+		ownerAddress: {EthereumAddress: ownerAddress, Role: model.RoleUser},
+	}}
+	fileService := service.NewFileService(ipfsStorage, fileRepo, nil, service.NewQuotaService(userRepo, 0, 0), nil, nil)
+	downloadService := service.NewDownloadService(ipfsStorage, fileRepo, shareRepo, nil, service.ChainVerifyOff, nil)
+	versionService := service.NewVersionService(fileService, downloadService, fileRepo, shareRepo, ipfsStorage, 3)
+
+	first, _, err := fileService.UploadFile(ctx, memoryFile{bytes.NewReader([]byte("v1"))}, ownerAddress, service.UploadOptions{FileName: "notes.txt"})
+	require.NoError(t, err)
+	logicalFileID := *first.LogicalFileID
+	for _, content := range []string{"v2 ", "v3  ", "v4   "} {
+		_, _, err := versionService.UploadVersion(ctx, ownerAddress, logicalFileID, bytes.NewReader([]byte(content)), service.UploadOptions{})
+		require.NoError(t, err)
+	}
+	versionNumbers := func() []int {
+		_, versions, err := versionService.ListVersions(ctx, ownerAddress, logicalFileID)
+		require.NoError(t, err)
+		numbers := make([]int, 0, len(versions))
+		for _, version := range versions {
+			numbers = append(numbers, version.Version)
+		}
+		return numbers
+	}
+	usage := func() (int64, int64) {
+		user := userRepo.users[ownerAddress]
+		return user.BytesStored, user.FileCount
+	}
+
+	// Uploading a fourth version pruned the first, unpinned it and released its storage
+	assert.Equal(t, []int{4, 3, 2}, versionNumbers())
+	_, err = versionService.DownloadVersion(ctx, ownerAddress, logicalFileID, 1)
+	assert.ErrorIs(t, err, service.ErrVersionNotFound)
+	_, err = ipfsStorage.DownloadFileFromIPFS(ctx, first.CID)
+	assert.Error(t, err, "the pruned version is unpinned")
+	bytesStored, fileCount := usage()
+	assert.Equal(t, int64(3+4+5), bytesStored)
+	assert.Equal(t, int64(3), fileCount)
+
+	// Lowering the retention prunes right away
+	logicalFile, err := versionService.SetRetention(ctx, ownerAddress, logicalFileID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, versionService.RetainedVersions(logicalFile))
+	assert.Equal(t, []int{4, 3}, versionNumbers())
+	bytesStored, fileCount = usage()
+	assert.Equal(t, int64(4+5), bytesStored)
+	assert.Equal(t, int64(2), fileCount)
+	_, err = versionService.SetRetention(ctx, ownerAddress, logicalFileID, -1)
+	assert.ErrorIs(t, err, service.ErrInvalidVersionPolicy)
+
+	// Restoring stores the old content as a new version, keeping the history in between
+	restored, _, err := versionService.RestoreVersion(ctx, ownerAddress, logicalFileID, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 5, restored.Version)
+	assert.True(t, restored.IsLatest)
+	assert.Equal(t, "notes.txt", restored.Name)
+	assert.Equal(t, []int{5, 4}, versionNumbers())
+	download, err := versionService.DownloadVersion(ctx, ownerAddress, logicalFileID, 5)
+	require.NoError(t, err)
+	var content bytes.Buffer
+	require.NoError(t, download.Stream(ctx, &content))
+	assert.Equal(t, "v3  ", content.String())
+
+	_, _, err = versionService.RestoreVersion(ctx, ownerAddress, logicalFileID, 3)
+	assert.ErrorIs(t, err, service.ErrVersionNotFound, "version 3 has been pruned since")
+	_, _, err = versionService.RestoreVersion(ctx, strangerAddress, logicalFileID, 4)
+	assert.ErrorIs(t, err, service.ErrFileNotFound)
+}
+
+func TestPruneFileVersions(t *testing.T) {
+	testDB := setupTestDatabase(t)
+	defer testDB.Close()
+	fileRepo := repository.NewFileRepository(testDB)
+	ctx := context.Background()
+	suffix := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	first := &model.File{CID: "QmVersion1-" + suffix, EthereumAddress: ownerAddress, Size: 1}
+	require.NoError(t, fileRepo.SaveFileMetadata(ctx, first))
+	for version := 2; version <= 4; version++ {
+		file := &model.File{CID: fmt.Sprintf("QmVersion%d-%s", version, suffix), EthereumAddress: ownerAddress, Size: int64(version), LogicalFileID: first.LogicalFileID}
+		require.NoError(t, fileRepo.SaveFileVersion(ctx, file))
+		assert.Equal(t, version, file.Version)
+	}
+
+	pruned, err := fileRepo.PruneFileVersions(ctx, *first.LogicalFileID, 2)
+	require.NoError(t, err)
+	require.Len(t, pruned, 2)
+	assert.Equal(t, 2, pruned[0].Version)
+	assert.Equal(t, 1, pruned[1].Version)
+
+	versions, err := fileRepo.ListFileVersions(ctx, *first.LogicalFileID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 4, versions[0].Version)
+	assert.True(t, versions[0].IsLatest)
+	assert.Equal(t, 3, versions[1].Version)
+
+	// The latest version is always kept
+	pruned, err = fileRepo.PruneFileVersions(ctx, *first.LogicalFileID, 0)
+	require.NoError(t, err)
+	require.Len(t, pruned, 1)
+	assert.Equal(t, 3, pruned[0].Version)
+	_, err = fileRepo.GetFileVersion(ctx, *first.LogicalFileID, 4)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/service"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	adminAddress   = "0x00000000000000000000000000000000000000A1"
+	auditorAddress = "0x00000000000000000000000000000000000000A2"
+	userAddress    = "0x00000000000000000000000000000000000000A3"
+)
+
+func newRoleUserRepository() *memoryUserRepository {
+	disabledAt := time.Now()
+	return &memoryUserRepository{users: map[string]*model.User{
+		adminAddress:   {EthereumAddress: adminAddress, Role: model.RoleAdmin},
+		auditorAddress: {EthereumAddress: auditorAddress, Role: model.RoleAuditor},
+		userAddress:    {EthereumAddress: userAddress, Role: model.RoleUser},
+		"0x00000000000000000000000000000000000000a4": {
+			EthereumAddress: "0x00000000000000000000000000000000000000a4",
+			Role:            model.RoleUser,
+			DisabledAt:      &disabledAt,
+		},
+	}}
+}
+
+// serveAs sends a request through middleware as if JWTMiddleware had authenticated it.
+func serveAs(middleware func(http.Handler) http.Handler, ethereumAddress, role string) int {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	request := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+	request.Header.Set("EthereumAddress", ethereumAddress)
+	request.Header.Set("Role", role)
+	recorder := httptest.NewRecorder()
+	middleware(next).ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+func TestRoleMiddleware(t *testing.T) {
+	readOnly := api.RoleMiddleware(model.RoleAuditor, model.RoleAdmin)
+	assert.Equal(t, http.StatusOK, serveAs(readOnly, adminAddress, model.RoleAdmin))
+	assert.Equal(t, http.StatusOK, serveAs(readOnly, auditorAddress, model.RoleAuditor))
+	assert.Equal(t, http.StatusForbidden, serveAs(readOnly, userAddress, model.RoleUser))
+	assert.Equal(t, http.StatusForbidden, serveAs(readOnly, userAddress, ""))
+
+	adminOnly := api.RoleMiddleware(model.RoleAdmin)
+	assert.Equal(t, http.StatusOK, serveAs(adminOnly, adminAddress, model.RoleAdmin))
+	assert.Equal(t, http.StatusForbidden, serveAs(adminOnly, auditorAddress, model.RoleAuditor))
+}
+
+func TestAccountMiddleware(t *testing.T) {
+	userService := service.NewUserService(newRoleUserRepository(), "secret", 1337, nil)
+	middleware := api.AccountMiddleware(userService)
+
+	assert.Equal(t, http.StatusOK, serveAs(middleware, adminAddress, model.RoleAdmin))
+	assert.Equal(t, http.StatusOK, serveAs(middleware, userAddress, model.RoleUser))
+
+	// A token issued before the role changed is rejected rather than trusted
+	assert.Equal(t, http.StatusUnauthorized, serveAs(middleware, userAddress, model.RoleAdmin))
+	assert.Equal(t, http.StatusUnauthorized, serveAs(middleware, "0x00000000000000000000000000000000000000ff", model.RoleUser))
+	assert.Equal(t, http.StatusForbidden, serveAs(middleware, "0x00000000000000000000000000000000000000a4", model.RoleUser))
+}
+
+func TestAdminServiceAccounts(t *testing.T) {
+	ctx := context.Background()
+	userRepo := newRoleUserRepository()
+	adminService := service.NewAdminService(userRepo, nil, nil)
+
+	user, err := adminService.SetRole(ctx, adminAddress, userAddress, model.RoleAuditor)
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleAuditor, user.Role)
+
+	_, err = adminService.SetRole(ctx, adminAddress, userAddress, "superuser")
+	assert.ErrorIs(t, err, service.ErrInvalidRole)
+	_, err = adminService.SetRole(ctx, adminAddress, adminAddress, model.RoleUser)
+	assert.ErrorIs(t, err, service.ErrOwnAccount)
+	_, err = adminService.SetRole(ctx, adminAddress, strings.ToLower(adminAddress[2:]), model.RoleUser)
+	assert.ErrorIs(t, err, service.ErrOwnAccount, "the address is recognized in any form")
+	_, err = adminService.SetRole(ctx, adminAddress, "0x00000000000000000000000000000000000000ff", model.RoleUser)
+	assert.ErrorIs(t, err, service.ErrUserNotFound)
+
+	user, err = adminService.SetDisabled(ctx, adminAddress, userAddress, true)
+	require.NoError(t, err)
+	assert.NotNil(t, user.DisabledAt)
+	_, err = adminService.SetDisabled(ctx, adminAddress, adminAddress, true)
+	assert.ErrorIs(t, err, service.ErrOwnAccount)
+
+	// Disabled users cannot sign in
+	userService := service.NewUserService(userRepo, "secret", 1337, nil)
+	_, err = userService.GenerateJWT(ctx, userAddress)
+	assert.ErrorIs(t, err, service.ErrAccountDisabled)
+
+	user, err = adminService.SetDisabled(ctx, adminAddress, userAddress, false)
+	require.NoError(t, err)
+	assert.Nil(t, user.DisabledAt)
+	_, err = userService.GetActiveUser(ctx, userAddress)
+	assert.NoError(t, err)
+}
+
+func TestAdminServiceBootstrapAdmins(t *testing.T) {
+	userRepo := newRoleUserRepository()
+	adminService := service.NewAdminService(userRepo, nil, nil)
+
+	newAdmin := "0x00000000000000000000000000000000000000B1"
+	require.NoError(t, adminService.BootstrapAdmins(context.Background(), []string{strings.ToLower(userAddress), strings.ToLower(newAdmin)}))
+
+	assert.Equal(t, model.RoleAdmin, userRepo.users[userAddress].Role)
+	require.Contains(t, userRepo.users, newAdmin)
+	assert.Equal(t, model.RoleAdmin, userRepo.users[newAdmin].Role)
+	assert.NotEmpty(t, userRepo.users[newAdmin].Nonce)
+	assert.Len(t, userRepo.users, 5)
+
+	user, err := adminService.GetUser(context.Background(), strings.ToLower(newAdmin))
+	require.NoError(t, err)
+	assert.Equal(t, newAdmin, user.EthereumAddress)
+}
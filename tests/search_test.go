@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/service"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// searchRecorder records the last search query and answers it with no files.
+type searchRecorder struct {
+	repository.FileRepository
+	query repository.FileSearchQuery
+}
+
+func (repo *searchRecorder) SearchFiles(ctx context.Context, query repository.FileSearchQuery) ([]model.File, int64, error) {
+	repo.query = query
+	return nil, 42, nil
+}
+
+func TestTagsValue(t *testing.T) {
+	value, err := model.Tags{"plain", `with "quotes"`, `back\slash`, "a,b", ""}.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{"plain","with \"quotes\"","back\\slash","a,b",""}`, value)
+
+	value, err = model.Tags(nil).Value()
+	require.NoError(t, err)
+	assert.Equal(t, "{}", value)
+}
+
+func TestTagsScan(t *testing.T) {
+	for literal, expected := range map[string]model.Tags{
+		`{}`:                                {},
+		`{plain,other}`:                     {"plain", "other"},
+		`{"with \"quotes\"","back\\slash"}`: {`with "quotes"`, `back\slash`},
+		`{"a,b",""}`:                        {"a,b", ""},
+		`{""}`:                              {""},
+	} {
+		var tags model.Tags
+		require.NoError(t, tags.Scan(literal), literal)
+		assert.Equal(t, expected, tags, literal)
+	}
+
+	// Values round-trip through their encoding
+	original := model.Tags{`"`, `\`, "{braces}", "spaced out"}
+	value, err := original.Value()
+	require.NoError(t, err)
+	var tags model.Tags
+	require.NoError(t, tags.Scan([]byte(value.(string))))
+	assert.Equal(t, original, tags)
+
+	require.NoError(t, tags.Scan(nil))
+	assert.Nil(t, tags)
+	assert.Error(t, tags.Scan("plain"))
+	assert.Error(t, tags.Scan(42))
+}
+
+func TestSearchFilesQuery(t *testing.T) {
+	ctx := context.Background()
+	repo := &searchRecorder{}
+	userRepo := &memoryUserRepository{users: map[string]*model.User{
+		ownerAddress: {EthereumAddress: ownerAddress, Role: model.RoleUser},
+	}}
+	userService := service.NewUserService(userRepo, "secret", 1337, nil)
+	router := chi.NewRouter()
+	(&api.Handler{FileService: service.NewFileService(nil, repo, nil, nil, nil, nil), UserService: userService}).RegisterRoutes(router)
+	token, err := userService.GenerateJWT(ctx, ownerAddress)
+	require.NoError(t, err)
+	search := func(query string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/v1/files/search?"+query, nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := search("q=+quarterly+report+&tag=Finance,q1&tag=finance&from=2024-01-01&to=2024-01-31&minSize=10&maxSize=100&mimeType=Image/*&page=3&pageSize=5")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, repository.FileSearchQuery{
+		EthereumAddress: ownerAddress,
+		Text:            "quarterly report",
+		Tags:            model.Tags{"finance", "q1"},
+		CreatedAfter:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedBefore:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		MinSize:         10,
+		MaxSize:         100,
+		MimeType:        "image/*",
+		Limit:           5,
+		Offset:          10,
+	}, repo.query)
+	var response struct {
+		Files    []json.RawMessage `json:"files"`
+		Page     int               `json:"page"`
+		PageSize int               `json:"pageSize"`
+		Total    int64             `json:"total"`
+	}
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.Empty(t, response.Files)
+	assert.Equal(t, 3, response.Page)
+	assert.Equal(t, 5, response.PageSize)
+	assert.Equal(t, int64(42), response.Total)
+
+	// Without parameters the first page of every file is returned
+	require.Equal(t, http.StatusOK, search("").Code)
+	assert.Equal(t, repository.FileSearchQuery{EthereumAddress: ownerAddress, Tags: model.Tags{}, Limit: service.DefaultPageSize}, repo.query)
+
+	for _, query := range []string{"page=0", "pageSize=201", "from=yesterday", "minSize=-1", "maxSize=big"} {
+		assert.Equal(t, http.StatusBadRequest, search(query).Code, query)
+	}
+}
+
+func TestSearchFiles(t *testing.T) {
+	testDB := setupTestDatabase(t)
+	defer testDB.Close()
+	fileRepo := repository.NewFileRepository(testDB)
+	ctx := context.Background()
+	owner := fmt.Sprintf("0x%040x", time.Now().UnixNano())
+
+	save := func(cid, name, description, mimeType string, size int64, tags model.Tags) {
+		file := &model.File{CID: cid, EthereumAddress: owner, Name: name, Description: description, MimeType: mimeType, Size: size, Tags: tags}
+		require.NoError(t, fileRepo.SaveFileMetadata(ctx, file))
+	}
+	suffix := owner[len(owner)-8:]
+	save("QmReport"+suffix, "quarterly report.pdf", "Revenue for the first quarter", "application/pdf", 2000, model.Tags{"finance", "q1"})
+	save("QmPhoto"+suffix, "team.png", "Offsite photo", "image/png", 500, model.Tags{"team"})
+	save("QmScan"+suffix, "receipt.jpg", "Scanned quarterly receipt", "image/jpeg", 100, model.Tags{"finance"})
+	save("QmNotes"+suffix, "notes.txt", "", "text/plain", 10, nil)
+	// Files of other owners are never found
+	require.NoError(t, fileRepo.SaveFileMetadata(ctx, &model.File{CID: "QmOther" + suffix, EthereumAddress: strangerAddress, Name: "quarterly report.pdf"}))
+
+	search := func(query repository.FileSearchQuery) ([]string, int64) {
+		query.EthereumAddress = owner
+		if query.Limit == 0 {
+			query.Limit = 10
+		}
+		files, total, err := fileRepo.SearchFiles(ctx, query)
+		require.NoError(t, err)
+		names := make([]string, 0, len(files))
+		for _, file := range files {
+			names = append(names, file.Name)
+		}
+		return names, total
+	}
+
+	names, total := search(repository.FileSearchQuery{Text: "quarterly"})
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, []string{"quarterly report.pdf", "receipt.jpg"}, names, "name matches rank above description matches")
+
+	names, _ = search(repository.FileSearchQuery{Tags: []string{"finance", "q1"}})
+	assert.Equal(t, []string{"quarterly report.pdf"}, names)
+	names, _ = search(repository.FileSearchQuery{MimeType: "image/*"})
+	assert.ElementsMatch(t, []string{"team.png", "receipt.jpg"}, names)
+	names, _ = search(repository.FileSearchQuery{MimeType: "image/png"})
+	assert.Equal(t, []string{"team.png"}, names)
+	names, _ = search(repository.FileSearchQuery{MinSize: 100, MaxSize: 500})
+	assert.ElementsMatch(t, []string{"team.png", "receipt.jpg"}, names)
+	names, _ = search(repository.FileSearchQuery{CreatedAfter: time.Now().Add(time.Hour)})
+	assert.Empty(t, names)
+	names, _ = search(repository.FileSearchQuery{CreatedBefore: time.Now().Add(time.Hour)})
+	assert.Len(t, names, 4)
+
+	// Pages are ordered newest first and report the total
+	names, total = search(repository.FileSearchQuery{Limit: 3})
+	assert.Equal(t, int64(4), total)
+	assert.Equal(t, []string{"notes.txt", "receipt.jpg", "team.png"}, names)
+	names, total = search(repository.FileSearchQuery{Limit: 3, Offset: 3})
+	assert.Equal(t, int64(4), total)
+	assert.Equal(t, []string{"quarterly report.pdf"}, names)
+
+	// Tags are stored and read back as a text array
+	stored, err := fileRepo.GetFileMetadataByCID(ctx, "QmReport"+suffix)
+	require.NoError(t, err)
+	assert.Equal(t, model.Tags{"finance", "q1"}, stored.Tags)
+}
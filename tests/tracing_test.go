@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/config"
+	"SafeTransfer/internal/service"
+	"SafeTransfer/internal/storage"
+	"SafeTransfer/internal/tracing"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	spanExporter    = tracetest.NewInMemoryExporter()
+	installProvider sync.Once
+)
+
+// tracedSpans returns the spans of a trace that have ended, by name.
+func tracedSpans(t *testing.T, traceID trace.TraceID) map[string]tracetest.SpanStub {
+	t.Helper()
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range spanExporter.GetSpans() {
+		if span.SpanContext.TraceID() == traceID {
+			spans[span.Name] = span
+		}
+	}
+	return spans
+}
+
+// setupTracing exports spans to spanExporter. The provider is installed once for all tests,
+// since tracers obtained before a provider is set keep delegating to the first one.
+func setupTracing(t *testing.T) {
+	t.Helper()
+	installProvider.Do(func() {
+		_, err := tracing.Setup(context.Background(), config.TracingConfig{})
+		require.NoError(t, err)
+		otel.SetTracerProvider(tracing.NewProvider("safetransfer-test", sdktrace.WithSyncer(spanExporter)))
+	})
+}
+
+func attributeValue(attributes []attribute.KeyValue, key attribute.Key) attribute.Value {
+	for _, kv := range attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestRequestTracing(t *testing.T) {
+	setupTracing(t)
+	router := chi.NewRouter()
+	(&api.Handler{}).RegisterRoutes(router)
+
+	// The request continues the trace of the client
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	request := httptest.NewRequest(http.MethodGet, "/v1/download/QmFirst", nil)
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	spans := tracedSpans(t, traceID)
+	span, ok := spans["GET /v1/download/{cid}"]
+	require.True(t, ok, "the span is named after the route pattern")
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	assert.True(t, span.Parent.IsRemote())
+	assert.Equal(t, "/v1/download/{cid}", attributeValue(span.Attributes, "http.route").AsString())
+	assert.Equal(t, int64(http.StatusUnauthorized), attributeValue(span.Attributes, "http.response.status_code").AsInt64())
+	assert.Equal(t, "safetransfer-test", attributeValue(span.Resource.Attributes(), "service.name").AsString())
+}
+
+func TestTransferTracing(t *testing.T) {
+	setupTracing(t)
+	ipfs := newMemoryIPFS(t)
+	defer ipfs.Close()
+
+	ipfsStorage := storage.NewIPFSStorage(ipfs.URL)
+	fileRepo := &memoryFileRepository{}
+	quotaService := service.NewQuotaService(unlimitedUserRepository{}, 0, 0)
+	fileService := service.NewFileService(ipfsStorage, fileRepo, nil, quotaService, nil, nil)
+	downloadService := service.NewDownloadService(ipfsStorage, fileRepo, nil, nil, service.ChainVerifyOff, nil)
+	content := []byte("quarterly report")
+
+	// Upload
+	ctx, request := otel.Tracer("test").Start(context.Background(), "upload request")
+	fileMetadata, _, err := fileService.UploadFile(ctx, memoryFile{bytes.NewReader(content)}, "0xowner", service.UploadOptions{FileName: "report.txt"})
+	request.End()
+	require.NoError(t, err)
+
+	spans := tracedSpans(t, request.SpanContext().TraceID())
+	upload, ok := spans["file.upload"]
+	require.True(t, ok)
+	assert.Equal(t, request.SpanContext().SpanID(), upload.Parent.SpanID())
+	assert.Equal(t, fileMetadata.CID, attributeValue(upload.Attributes, "ipfs.cid").AsString())
+	for _, name := range []string{"file.sign", "file.verify_signature", "ipfs.add"} {
+		span, ok := spans[name]
+		if assert.True(t, ok, name) {
+			assert.Equal(t, upload.SpanContext.SpanID(), span.Parent.SpanID(), name)
+		}
+	}
+
+	// Download
+	ctx, request = otel.Tracer("test").Start(context.Background(), "download request")
+	download, err := downloadService.DownloadFile(ctx, "0xowner", fileMetadata.CID)
+	require.NoError(t, err)
+	var downloaded bytes.Buffer
+	err = download.Stream(ctx, &downloaded)
+	request.End()
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded.Bytes())
+
+	spans = tracedSpans(t, request.SpanContext().TraceID())
+	stream, ok := spans["file.download"]
+	require.True(t, ok)
+	for _, name := range []string{"ipfs.cat", "file.decrypt", "file.verify_signature"} {
+		span, ok := spans[name]
+		if assert.True(t, ok, name) {
+			assert.Equal(t, stream.SpanContext.SpanID(), span.Parent.SpanID(), name)
+		}
+	}
+	decrypt := spans["file.decrypt"]
+	assert.Equal(t, int64(len(content)), attributeValue(decrypt.Attributes, "safetransfer.bytes").AsInt64())
+	assert.Equal(t, attribute.FLOAT64, attributeValue(decrypt.Attributes, "safetransfer.ipfs_read_ms").Type())
+	assert.Equal(t, attribute.FLOAT64, attributeValue(decrypt.Attributes, "safetransfer.cipher_ms").Type())
+}
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/service"
+	"SafeTransfer/internal/storage"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ownerAddress    = "0x1111111111111111111111111111111111111111"
+	granteeAddress  = "0x2222222222222222222222222222222222222222"
+	strangerAddress = "0x3333333333333333333333333333333333333333"
+)
+
+// transferServices uploads and downloads files through an in-memory IPFS node and repositories.
+type transferServices struct {
+	fileRepo        *memoryFileRepository
+	shareRepo       *memoryShareRepository
+	fileService     *service.FileService
+	downloadService *service.DownloadService
+	shareService    *service.ShareService
+}
+
+func newTransferServices(t *testing.T) *transferServices {
+	ipfs := newMemoryIPFS(t)
+	t.Cleanup(ipfs.Close)
+	ipfsStorage := storage.NewIPFSStorage(ipfs.URL)
+
+	fileRepo := &memoryFileRepository{}
+	shareRepo := &memoryShareRepository{}
+	quotaService := service.NewQuotaService(unlimitedUserRepository{}, 0, 0)
+	return &transferServices{
+		fileRepo:        fileRepo,
+		shareRepo:       shareRepo,
+		fileService:     service.NewFileService(ipfsStorage, fileRepo, nil, quotaService, nil, nil),
+		downloadService: service.NewDownloadService(ipfsStorage, fileRepo, shareRepo, nil, service.ChainVerifyOff, nil),
+		shareService:    service.NewShareService(shareRepo, fileRepo),
+	}
+}
+
+// upload stores content for ethereumAddress and returns its CID and logical file ID.
+func (s *transferServices) upload(t *testing.T, ethereumAddress, name, content string) (string, uint) {
+	t.Helper()
+	fileMetadata, _, err := s.fileService.UploadFile(context.Background(), memoryFile{bytes.NewReader([]byte(content))}, ethereumAddress, service.UploadOptions{FileName: name})
+	require.NoError(t, err)
+	return fileMetadata.CID, *fileMetadata.LogicalFileID
+}
+
+func TestArchiveAccess(t *testing.T) {
+	ctx := context.Background()
+	s := newTransferServices(t)
+	report, reportID := s.upload(t, ownerAddress, "report.txt", "quarterly report")
+	notes, _ := s.upload(t, ownerAddress, "notes.txt", "meeting notes")
+	_, err := s.shareService.GrantShare(ctx, ownerAddress, reportID, granteeAddress)
+	require.NoError(t, err)
+
+	files, err := s.downloadService.ResolveArchive(ctx, ownerAddress, []string{report, notes})
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	// Files shared with the caller can be archived, others are reported as missing
+	files, err = s.downloadService.ResolveArchive(ctx, granteeAddress, []string{report})
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+	_, err = s.downloadService.ResolveArchive(ctx, granteeAddress, []string{report, notes})
+	assert.ErrorIs(t, err, service.ErrFileNotFound)
+	assert.ErrorContains(t, err, notes)
+
+	_, err = s.downloadService.ResolveArchive(ctx, strangerAddress, []string{report})
+	assert.ErrorIs(t, err, service.ErrFileNotFound)
+}
+
+func TestDownloadAccess(t *testing.T) {
+	ctx := context.Background()
+	s := newTransferServices(t)
+	cid, logicalFileID := s.upload(t, ownerAddress, "report.txt", "quarterly report")
+	_, err := s.shareService.GrantShare(ctx, ownerAddress, logicalFileID, granteeAddress)
+	require.NoError(t, err)
+
+	userRepo := &memoryUserRepository{users: map[string]*model.User{}}
+	for _, address := range []string{ownerAddress, granteeAddress, strangerAddress} {
+		userRepo.users[address] = &model.User{EthereumAddress: address, Role: model.RoleUser}
+	}
+	userService := service.NewUserService(userRepo, "secret", 1337, nil)
+	router := chi.NewRouter()
+	(&api.Handler{DownloadService: s.downloadService, UserService: userService}).RegisterRoutes(router)
+	download := func(ethereumAddress string) *httptest.ResponseRecorder {
+		token, err := userService.GenerateJWT(ctx, ethereumAddress)
+		require.NoError(t, err)
+		request := httptest.NewRequest(http.MethodGet, "/v1/download/"+cid, nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := download(ownerAddress)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "quarterly report", recorder.Body.String())
+	assert.Equal(t, http.StatusOK, download(granteeAddress).Code)
+	assert.Equal(t, http.StatusNotFound, download(strangerAddress).Code)
+
+	// Revoking the share revokes access to the CID
+	require.NoError(t, s.shareService.RevokeShare(ctx, ownerAddress, logicalFileID, granteeAddress))
+	assert.Equal(t, http.StatusNotFound, download(granteeAddress).Code)
+	assert.Equal(t, http.StatusOK, download(ownerAddress).Code)
+}
+
+func TestStreamedDownload(t *testing.T) {
+	ctx := context.Background()
+	s := newTransferServices(t)
+	cid, _ := s.upload(t, ownerAddress, "report.txt", "quarterly report")
+	sum := sha256.Sum256([]byte("quarterly report"))
+
+	userRepo := &memoryUserRepository{users: map[string]*model.User{
+		ownerAddress: {EthereumAddress: ownerAddress, Role: model.RoleUser},
+	}}
+	userService := service.NewUserService(userRepo, "secret", 1337, nil)
+	router := chi.NewRouter()
+	(&api.Handler{DownloadService: s.downloadService, UserService: userService}).RegisterRoutes(router)
+	token, err := userService.GenerateJWT(ctx, ownerAddress)
+	require.NoError(t, err)
+	download := func() *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/v1/download/"+cid, nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// The recorded hash is sent before the content
+	recorder := download()
+	assert.Equal(t, hex.EncodeToString(sum[:]), recorder.Header().Get("X-File-Hash"))
+	assert.Empty(t, recorder.Header().Get("Trailer"))
+	assert.Equal(t, "quarterly report", recorder.Body.String())
+
+	// Files uploaded before their hash was recorded send it as a trailer
+	fileMetadata, err := s.fileRepo.GetFileMetadataByCID(ctx, cid)
+	require.NoError(t, err)
+	fileMetadata.FileHash = ""
+	response := download().Result()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "quarterly report", string(body))
+	assert.Empty(t, response.Header.Get("X-File-Hash"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), response.Trailer.Get("X-File-Hash"))
+
+	// Content that does not match the recorded hash aborts the response once streamed
+	fileMetadata.FileHash = strings.Repeat("0", 64)
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { download() })
+}
+
+func TestAccessWhateverTheAddressCasing(t *testing.T) {
+	ctx := context.Background()
+	s := newTransferServices(t)
+	owner, grantee := "0x00000000000000000000000000000000000000AA", "0x00000000000000000000000000000000000000B0"
+	// Files stored before addresses were canonicalized carry the address as the client sent it
+	report, reportID := s.upload(t, strings.ToLower(owner), "report.txt", "quarterly report")
+
+	files, err := s.downloadService.ResolveArchive(ctx, owner, []string{report})
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+	_, err = s.shareService.GrantShare(ctx, owner, reportID, strings.ToLower(grantee))
+	require.NoError(t, err)
+	_, err = s.shareService.GrantShare(ctx, owner, reportID, strings.ToLower(owner))
+	assert.ErrorIs(t, err, service.ErrInvalidShare, "files cannot be shared with their owner")
+
+	files, err = s.downloadService.ResolveArchive(ctx, grantee, []string{report})
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+	require.NoError(t, s.shareService.RevokeShare(ctx, strings.ToLower(owner), reportID, grantee))
+	_, err = s.downloadService.ResolveArchive(ctx, grantee, []string{report})
+	assert.ErrorIs(t, err, service.ErrFileNotFound)
+}
@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/service"
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// memoryUserRepository keeps users and their nonces in memory. Like the database, it looks users
+// up by their canonical address.
+type memoryUserRepository struct {
+	repository.UserRepository
+	nonces map[string]string
+	users  map[string]*model.User
+}
+
+func (repo *memoryUserRepository) FindByEthereumAddress(ctx context.Context, ethereumAddress string) (*model.User, error) {
+	user, ok := repo.users[model.CanonicalAddress(ethereumAddress)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+func (repo *memoryUserRepository) SaveOrUpdateUser(ctx context.Context, user *model.User) error {
+	address := model.CanonicalAddress(user.EthereumAddress)
+	if existing, ok := repo.users[address]; ok {
+		existing.Nonce = user.Nonce
+		return nil
+	}
+	created := *user
+	created.EthereumAddress = address
+	if created.Role == "" {
+		created.Role = model.RoleUser
+	}
+	repo.users[address] = &created
+	return nil
+}
+
+func (repo *memoryUserRepository) ReserveStorage(ctx context.Context, ethereumAddress string, bytes, files, defaultQuotaBytes, defaultQuotaFiles int64) (bool, error) {
+	user, ok := repo.users[model.CanonicalAddress(ethereumAddress)]
+	if !ok {
+		return false, nil
+	}
+	quotaBytes, quotaFiles := defaultQuotaBytes, defaultQuotaFiles
+	if user.QuotaBytes != nil {
+		quotaBytes = *user.QuotaBytes
+	}
+	if user.QuotaFiles != nil {
+		quotaFiles = *user.QuotaFiles
+	}
+	if (quotaBytes > 0 && user.BytesStored+bytes > quotaBytes) || (quotaFiles > 0 && user.FileCount+files > quotaFiles) {
+		return false, nil
+	}
+	user.BytesStored += bytes
+	user.FileCount += files
+	return true, nil
+}
+
+func (repo *memoryUserRepository) ReleaseStorage(ctx context.Context, ethereumAddress string, bytes, files int64) error {
+	if user, ok := repo.users[model.CanonicalAddress(ethereumAddress)]; ok {
+		user.BytesStored = max(user.BytesStored-bytes, 0)
+		user.FileCount = max(user.FileCount-files, 0)
+	}
+	return nil
+}
+
+func (repo *memoryUserRepository) UpdateRole(ctx context.Context, ethereumAddress, role string) error {
+	user, ok := repo.users[model.CanonicalAddress(ethereumAddress)]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.Role = role
+	return nil
+}
+
+func (repo *memoryUserRepository) UpdateDisabledAt(ctx context.Context, ethereumAddress string, disabledAt *time.Time) error {
+	user, ok := repo.users[model.CanonicalAddress(ethereumAddress)]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	user.DisabledAt = disabledAt
+	return nil
+}
+
+func (repo *memoryUserRepository) ConsumeNonce(ctx context.Context, ethereumAddress, nonce, nextNonce string) (bool, error) {
+	key := strings.ToLower(ethereumAddress)
+	if repo.nonces[key] != nonce {
+		return false, nil
+	}
+	repo.nonces[key] = nextNonce
+	return true, nil
+}
+
+func signTypedData(t *testing.T, key *ecdsa.PrivateKey, typedData apitypes.TypedData) string {
+	digest, _, err := apitypes.TypedDataAndHash(typedData)
+	require.NoError(t, err)
+	signature, err := crypto.Sign(digest, key)
+	require.NoError(t, err)
+	signature[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(signature)
+}
+
+func TestAuthorizeAction(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	grantee := "0x00000000000000000000000000000000000000aa"
+
+	userRepo := &memoryUserRepository{nonces: map[string]string{strings.ToLower(address): "nonce-1"}}
+	userService := service.NewUserService(userRepo, "secret", 1337, nil)
+
+	params := map[string]interface{}{"fileId": big.NewInt(7), "grantee": grantee}
+	deadline := time.Now().Add(10 * time.Minute).Unix()
+	auth := service.ActionAuthorization{
+		Nonce:     "nonce-1",
+		Deadline:  deadline,
+		Signature: signTypedData(t, key, userService.ActionTypedData(service.ActionShareGrant, params, "nonce-1", deadline)),
+	}
+
+	// Tampered parameters do not match the signature
+	tampered := map[string]interface{}{"fileId": big.NewInt(8), "grantee": grantee}
+	assert.ErrorIs(t, userService.AuthorizeAction(ctx, address, service.ActionShareGrant, tampered, auth), service.ErrInvalidTypedSignature)
+
+	// Nor does a different action over the same fields
+	assert.ErrorIs(t, userService.AuthorizeAction(ctx, address, service.ActionTransferOwnership, map[string]interface{}{"fileId": big.NewInt(7), "newOwner": grantee}, auth), service.ErrInvalidTypedSignature)
+
+	// Another wallet cannot use the signature
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	assert.ErrorIs(t, userService.AuthorizeAction(ctx, crypto.PubkeyToAddress(other.PublicKey).Hex(), service.ActionShareGrant, params, auth), service.ErrInvalidTypedSignature)
+
+	require.NoError(t, userService.AuthorizeAction(ctx, address, service.ActionShareGrant, params, auth))
+	assert.NotEqual(t, "nonce-1", userRepo.nonces[strings.ToLower(address)])
+
+	// The nonce was consumed, so the signature cannot be replayed
+	assert.ErrorIs(t, userService.AuthorizeAction(ctx, address, service.ActionShareGrant, params, auth), service.ErrStaleNonce)
+}
+
+func TestAuthorizeActionDeadline(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	userRepo := &memoryUserRepository{nonces: map[string]string{strings.ToLower(address): "nonce-1"}}
+	userService := service.NewUserService(userRepo, "secret", 1337, nil)
+	params := map[string]interface{}{"fileId": big.NewInt(7)}
+
+	authorization := func(deadline int64) service.ActionAuthorization {
+		typedData := userService.ActionTypedData(service.ActionDeleteFile, params, "nonce-1", deadline)
+		return service.ActionAuthorization{Nonce: "nonce-1", Deadline: deadline, Signature: signTypedData(t, key, typedData)}
+	}
+
+	expired := authorization(time.Now().Add(-time.Minute).Unix())
+	assert.ErrorIs(t, userService.AuthorizeAction(ctx, address, service.ActionDeleteFile, params, expired), service.ErrAuthorizationExpired)
+
+	tooLate := authorization(time.Now().Add(24 * time.Hour).Unix())
+	assert.ErrorIs(t, userService.AuthorizeAction(ctx, address, service.ActionDeleteFile, params, tooLate), service.ErrInvalidAuthorization)
+
+	// Neither rejection consumed the nonce
+	assert.Equal(t, "nonce-1", userRepo.nonces[strings.ToLower(address)])
+
+	malformed := authorization(time.Now().Add(time.Minute).Unix())
+	malformed.Signature = "0x1234"
+	assert.ErrorIs(t, userService.AuthorizeAction(ctx, address, service.ActionDeleteFile, params, malformed), service.ErrInvalidAuthorization)
+}
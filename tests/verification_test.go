@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"SafeTransfer/internal/api"
+	"SafeTransfer/internal/chain"
+	"SafeTransfer/internal/model"
+	"SafeTransfer/internal/repository"
+	"SafeTransfer/internal/service"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const (
+	verifiedHash = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	otherHash    = "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7"
+)
+
+// memoryFileRepository keeps files in memory for lookups. Saved files become the first version
+// of a new logical file, like in the database.
+type memoryFileRepository struct {
+	repository.FileRepository
+	files        []model.File
+	logicalFiles []model.LogicalFile
+}
+
+func (repo *memoryFileRepository) SaveFileMetadata(ctx context.Context, fileMetadata *model.File) error {
+	logicalFile := model.LogicalFile{EthereumAddress: fileMetadata.EthereumAddress, LatestVersion: 1}
+	logicalFile.ID = uint(len(repo.logicalFiles) + 1)
+	repo.logicalFiles = append(repo.logicalFiles, logicalFile)
+
+	fileMetadata.ID = uint(len(repo.files) + 1)
+	fileMetadata.LogicalFileID = &logicalFile.ID
+	fileMetadata.Version = 1
+	fileMetadata.IsLatest = true
+	repo.files = append(repo.files, *fileMetadata)
+	return nil
+}
+
+func (repo *memoryFileRepository) GetLogicalFile(ctx context.Context, id uint) (*model.LogicalFile, error) {
+	for i := range repo.logicalFiles {
+		if repo.logicalFiles[i].ID == id {
+			return &repo.logicalFiles[i], nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (repo *memoryFileRepository) GetFileMetadataByCID(ctx context.Context, cid string) (*model.File, error) {
+	for i := range repo.files {
+		if repo.files[i].CID == cid {
+			return &repo.files[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (repo *memoryFileRepository) FindFilesByHash(ctx context.Context, fileHash string, limit int) ([]model.File, error) {
+	var files []model.File
+	for _, file := range repo.files {
+		if file.FileHash == fileHash && len(files) < limit {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func TestVerifyAgainstStoredFiles(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryFileRepository{}
+	require.NoError(t, repo.SaveFileMetadata(ctx, &model.File{CID: "QmStoredCID", EthereumAddress: ownerAddress, FileHash: verifiedHash}))
+	shareRepo := &memoryShareRepository{shares: []model.Share{{LogicalFileID: 1, GranteeAddress: granteeAddress}}}
+	verifier := service.NewVerificationService(repo, shareRepo, nil)
+
+	for _, address := range []string{ownerAddress, granteeAddress} {
+		result, err := verifier.Verify(ctx, address, verifiedHash, "")
+		require.NoError(t, err)
+		assert.True(t, result.Verified(), address)
+		require.Len(t, result.Stored, 1, address)
+		assert.Equal(t, ownerAddress, result.Stored[0].Owner)
+		assert.Nil(t, result.Registry)
+	}
+
+	result, err := verifier.Verify(ctx, ownerAddress, otherHash, "QmStoredCID")
+	require.NoError(t, err)
+	assert.False(t, result.Verified())
+
+	// Stored files are hidden from anyone else, by hash or by CID
+	for _, address := range []string{strangerAddress, ""} {
+		result, err := verifier.Verify(ctx, address, verifiedHash, "")
+		require.NoError(t, err)
+		assert.False(t, result.Verified(), address)
+		assert.Empty(t, result.Stored, address)
+
+		result, err = verifier.Verify(ctx, address, verifiedHash, "QmStoredCID")
+		require.NoError(t, err)
+		assert.Empty(t, result.Stored, address)
+	}
+
+	_, err = verifier.Verify(ctx, ownerAddress, "not-a-hash", "")
+	assert.ErrorIs(t, err, service.ErrInvalidVerification)
+}
+
+func TestVerifyEndpointAccess(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryFileRepository{}
+	require.NoError(t, repo.SaveFileMetadata(ctx, &model.File{CID: "QmStoredCID", EthereumAddress: ownerAddress, FileHash: verifiedHash}))
+	userRepo := &memoryUserRepository{users: map[string]*model.User{
+		ownerAddress: {EthereumAddress: ownerAddress, Role: model.RoleUser},
+	}}
+	userService := service.NewUserService(userRepo, "secret", 1337, nil)
+	handler := &api.Handler{
+		VerifyService: service.NewVerificationService(repo, &memoryShareRepository{}, nil),
+		UserService:   userService,
+	}
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+	verify := func(header, value string) []map[string]any {
+		request := httptest.NewRequest(http.MethodPost, "/v1/verify", strings.NewReader(`{"sha256":"`+verifiedHash+`"}`))
+		request.Header.Set(header, value)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var response struct {
+			Stored []map[string]any `json:"stored"`
+		}
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+		return response.Stored
+	}
+
+	token, err := userService.GenerateJWT(ctx, ownerAddress)
+	require.NoError(t, err)
+	assert.Len(t, verify("Authorization", "Bearer "+token), 1)
+	assert.Empty(t, verify("Accept", "application/json"))
+	// Anonymous callers cannot claim an address
+	assert.Empty(t, verify("EthereumAddress", ownerAddress))
+}
+
+func TestVerifyAgainstRegistry(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	details := chain.FileRegistryFileDetails{Owner: owner, FileHash: verifiedHash, Timestamp: big.NewInt(1700000000)}
+	_, registry := newSimulatedContract(t, key, lookupStubRuntime(t, verifiedHash, details))
+	verifier := service.NewVerificationService(&memoryFileRepository{}, nil, registry)
+	ctx := context.Background()
+
+	result, err := verifier.Verify(ctx, "", verifiedHash, "QmRegisteredCID")
+	require.NoError(t, err)
+	assert.True(t, result.Verified())
+	assert.Empty(t, result.Stored)
+	assert.Equal(t, service.ChainVerified, result.Registry.Status)
+	assert.Equal(t, owner.Hex(), result.Registry.Owner)
+	assert.Equal(t, int64(1700000000), result.Registry.RegisteredAt.Unix())
+
+	result, err = verifier.Verify(ctx, "", otherHash, "QmRegisteredCID")
+	require.NoError(t, err)
+	assert.False(t, result.Verified())
+	assert.Equal(t, service.ChainMismatch, result.Registry.Status)
+}
@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionedRoutes(t *testing.T) {
+	router := newAPIRouter()
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+
+	current := serve(http.MethodGet, "/v1/openapi.json")
+	assert.Equal(t, http.StatusOK, current.Code)
+	assert.Empty(t, current.Header().Get("Deprecation"))
+	assert.Empty(t, current.Header().Get("Sunset"))
+
+	alias := serve(http.MethodGet, "/openapi.json")
+	assert.Equal(t, http.StatusOK, alias.Code)
+	assert.Equal(t, current.Body.String(), alias.Body.String())
+	assert.Regexp(t, `^@\d+$`, alias.Header().Get("Deprecation"))
+	sunset, err := http.ParseTime(alias.Header().Get("Sunset"))
+	require.NoError(t, err)
+	assert.True(t, sunset.After(time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, `</v1/openapi.json>; rel="successor-version"`, alias.Header().Get("Link"))
+
+	// Aliases go through the same middleware as the versioned routes
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "/v1/checkToken").Code)
+	unauthorized := serve(http.MethodGet, "/checkToken")
+	assert.Equal(t, http.StatusUnauthorized, unauthorized.Code)
+	assert.NotEmpty(t, unauthorized.Header().Get("Deprecation"))
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/v2/checkToken").Code)
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"SafeTransfer/internal/chain"
+	"SafeTransfer/internal/service"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyWalletSignatureContractWallet(t *testing.T) {
+	deployer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	_, registry := newSimulatedContract(t, deployer, walletStubRuntime(crypto.PubkeyToAddress(owner.PublicKey)))
+	wallet := registry.Address().Hex()
+	userService := service.NewUserService(nil, "secret", 1337, registry.Backend())
+	ctx := context.Background()
+
+	message := "Sign in to SafeTransfer: nonce-1"
+	require.NoError(t, userService.VerifyWalletSignature(ctx, wallet, message, signPersonalMessage(t, owner, message)))
+
+	// The wallet rejects signatures by anyone but its owner
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	assert.ErrorIs(t, userService.VerifyWalletSignature(ctx, wallet, message, signPersonalMessage(t, other, message)), service.ErrInvalidSignature)
+	assert.ErrorIs(t, userService.VerifyWalletSignature(ctx, wallet, "another message", signPersonalMessage(t, owner, message)), service.ErrInvalidSignature)
+
+	// Externally owned accounts are still checked by recovery
+	eoa := crypto.PubkeyToAddress(other.PublicKey).Hex()
+	require.NoError(t, userService.VerifyWalletSignature(ctx, eoa, message, signPersonalMessage(t, other, message)))
+
+	// Without a wallet backend, contract wallets cannot sign in
+	offline := service.NewUserService(nil, "secret", 1337, nil)
+	assert.ErrorIs(t, offline.VerifyWalletSignature(ctx, wallet, message, signPersonalMessage(t, owner, message)), service.ErrInvalidSignature)
+}
+
+// unreachableWallets fails every call, like a node that cannot be reached.
+type unreachableWallets struct{}
+
+func (unreachableWallets) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, errors.New("dial tcp 10.0.0.1:8545: i/o timeout")
+}
+
+func (unreachableWallets) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return nil, errors.New("dial tcp 10.0.0.1:8545: i/o timeout")
+}
+
+func TestVerifyWalletSignatureNodeUnreachable(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	userService := service.NewUserService(nil, "secret", 1337, unreachableWallets{})
+	ctx := context.Background()
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	message := "Sign in to SafeTransfer: nonce-1"
+
+	require.NoError(t, userService.VerifyWalletSignature(ctx, address, message, signPersonalMessage(t, key, message)))
+
+	// A wrong signature is rejected as invalid rather than blamed on the node
+	err = userService.VerifyWalletSignature(ctx, address, message, signPersonalMessage(t, other, message))
+	assert.ErrorIs(t, err, service.ErrInvalidSignature)
+	assert.NotErrorIs(t, err, service.ErrWalletUnavailable)
+}
+
+func TestAuthorizeActionContractWallet(t *testing.T) {
+	deployer, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	_, registry := newSimulatedContract(t, deployer, walletStubRuntime(crypto.PubkeyToAddress(owner.PublicKey)))
+	wallet := registry.Address().Hex()
+	userRepo := &memoryUserRepository{nonces: map[string]string{strings.ToLower(wallet): "nonce-1"}}
+	userService := service.NewUserService(userRepo, "secret", 1337, registry.Backend())
+
+	params := map[string]interface{}{"fileId": big.NewInt(7)}
+	deadline := time.Now().Add(10 * time.Minute).Unix()
+	auth := service.ActionAuthorization{
+		Nonce:     "nonce-1",
+		Deadline:  deadline,
+		Signature: signTypedData(t, owner, userService.ActionTypedData(service.ActionDeleteFile, params, "nonce-1", deadline)),
+	}
+	require.NoError(t, userService.AuthorizeAction(context.Background(), wallet, service.ActionDeleteFile, params, auth))
+	assert.ErrorIs(t, userService.AuthorizeAction(context.Background(), wallet, service.ActionDeleteFile, params, auth), service.ErrStaleNonce)
+}
+
+func TestIsValidSignatureReverted(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	_, registry := newSimulatedContract(t, key, revertStubRuntime("not a wallet"))
+	ctx := context.Background()
+
+	isContract, err := chain.IsContract(ctx, registry.Backend(), registry.Address())
+	require.NoError(t, err)
+	assert.True(t, isContract)
+
+	// A contract that reverts does not accept the signature
+	valid, err := chain.IsValidSignature(ctx, registry.Backend(), registry.Address(), common.Hash{1}, []byte{1, 2, 3})
+	require.NoError(t, err)
+	assert.False(t, valid)
+
+	isContract, err = chain.IsContract(ctx, registry.Backend(), crypto.PubkeyToAddress(key.PublicKey))
+	require.NoError(t, err)
+	assert.False(t, isContract)
+}
+
+func signPersonalMessage(t *testing.T, key *ecdsa.PrivateKey, message string) string {
+	t.Helper()
+	signature, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	require.NoError(t, err)
+	signature[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(signature)
+}
+
+// walletStubRuntime is a minimal EIP-1271 wallet: isValidSignature(hash, signature) returns the
+// magic value when ecrecover of the 65-byte [R || S || V] signature yields owner, and zero
+// otherwise.
+func walletStubRuntime(owner common.Address) []byte {
+	code := []byte{
+		0x60, 0x04, 0x35, 0x60, 0x00, 0x52, // mstore(0x00, hash)
+		0x60, 0xa4, 0x35, 0x60, 0xf8, 0x1c, 0x60, 0x20, 0x52, // mstore(0x20, v)
+		0x60, 0x64, 0x35, 0x60, 0x40, 0x52, // mstore(0x40, r)
+		0x60, 0x84, 0x35, 0x60, 0x60, 0x52, // mstore(0x60, s)
+		0x60, 0x20, 0x60, 0x80, 0x60, 0x80, 0x60, 0x00, 0x60, 0x01, 0x5a, 0xfa, 0x50, // staticcall(gas, ecrecover, 0, 0x80, 0x80, 0x20)
+		0x60, 0x80, 0x51, 0x73, // mload(0x80) == owner
+	}
+	code = append(code, owner.Bytes()...)
+	code = append(code,
+		0x14, 0x60, 0x49, 0x57, // jumpi(valid)
+		0x60, 0x20, 0x60, 0xa0, 0xf3, // return 32 zero bytes
+		0x5b, 0x63, 0x16, 0x26, 0xba, 0x7e, 0x60, 0xe0, 0x1b, 0x60, 0x00, 0x52, // valid: mstore(0, magic << 224)
+		0x60, 0x20, 0x60, 0x00, 0xf3, // return(0, 32)
+	)
+	return code
+}
@@ -1,11 +0,0 @@
-package utils
-
-import "os"
-
-func GetEnvOrDefault(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	return value
-}